@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"errors"
 	"flag"
 	"fmt"
@@ -10,8 +9,7 @@ import (
 	"os/exec"
 	"os/signal"
 	"path/filepath"
-	"regexp"
-	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -21,9 +19,15 @@ import (
 var (
 	processMutex     sync.Mutex  // Мьютекс для защиты доступа к runningProcesses
 	runningProcesses []*exec.Cmd // Список запущенных команд
+	interactiveShell bool        // Оболочка читает команды с терминала
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == builtinProcessArgument {
+		os.Exit(runBuiltinProcess())
+	}
+
+	noStartupFile := flag.Bool("norc", false, "do not read ~/.minishellrc at startup")
 	flag.Parse()
 
 	// Настройка обработки сигналов прерывания
@@ -35,25 +39,78 @@ func main() {
 		}
 	}()
 
-	scanner := bufio.NewScanner(os.Stdin)
-	// Увеличиваем буфер для чтения длинных строк
-	buffer := make([]byte, 0, 64*1024)
-	scanner.Buffer(buffer, 1024*1024)
+	// В интерактивном режиме используем редактор строки с историей,
+	// иначе читаем команды построчно без приглашения
+	var reader lineReader
+	interactiveShell = isTerminal(int(os.Stdin.Fd()))
+	if interactiveShell {
+		if err := shellHistory.Load(defaultHistoryFile()); err != nil {
+			fmt.Fprintln(os.Stderr, "history:", err)
+		}
+		reader = newLineEditor(os.Stdin, os.Stdout, shellHistory)
+	} else {
+		reader = newScannerLineReader(os.Stdin)
+	}
+
+	if !*noStartupFile {
+		loadStartupFile()
+	}
 
 	// Основной цикл чтения и выполнения команд
 	for {
-		if !scanner.Scan() {
-			if scanner.Err() != nil {
-				fmt.Fprintln(os.Stderr, scanner.Err())
+		if interactiveShell {
+			reportFinishedJobs(os.Stderr)
+		}
+		line, err := reader.ReadLine(shellPrompt())
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprintln(os.Stderr, err)
 			}
 			break
 		}
-		line := strings.TrimSpace(scanner.Text())
+		// Команда с ведущим пробелом не попадает в историю (как HISTCONTROL=ignorespace)
+		ignoreInHistory := strings.HasPrefix(line, " ")
+		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
 		}
+
+		if interactiveShell {
+			// Раскрываем ссылки на историю (!!, !N) и показываем итоговую команду
+			expanded, err := shellHistory.Expand(line)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				continue
+			}
+			if expanded != line {
+				fmt.Println(expanded)
+			}
+			line = expanded
+			if !ignoreInHistory {
+				shellHistory.Add(line)
+			}
+		}
 		_ = processCommandLine(line)
 	}
+
+	// Код завершения оболочки - код последней выполненной команды
+	os.Exit(lastExitStatus)
+}
+
+// Формирует приглашение с текущим каталогом (домашний каталог сокращается до ~)
+func shellPrompt() string {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return "$ "
+	}
+	if homeDir, err := os.UserHomeDir(); err == nil && homeDir != "" {
+		if currentDir == homeDir {
+			currentDir = "~"
+		} else if strings.HasPrefix(currentDir, homeDir+string(filepath.Separator)) {
+			currentDir = "~" + currentDir[len(homeDir):]
+		}
+	}
+	return currentDir + "$ "
 }
 
 // Устанавливает список запущенных процессов
@@ -82,41 +139,16 @@ func interruptRunningProcesses() {
 	}
 }
 
-// Заменяет переменные окружения в строке на их значения
-func expandEnvironmentVariables(input string) string {
-	envVarRegex := regexp.MustCompile(`\$[A-Za-z_][A-Za-z0-9_]*`)
-	return envVarRegex.ReplaceAllStringFunc(input, func(match string) string {
-		variableName := match[1:] // Убираем символ '$'
-		return os.Getenv(variableName)
-	})
-}
-
-// Разбивает строку на токены, обрабатывая операторы и переменные окружения
-func tokenizeCommandLine(line string) []string {
-	// Добавляем пробелы вокруг операторов для корректного разбиения
-	line = strings.ReplaceAll(line, "&&", " && ")
-	line = strings.ReplaceAll(line, "||", " || ")
-	line = strings.ReplaceAll(line, "|", " | ")
-	line = strings.ReplaceAll(line, ">", " > ")
-	line = strings.ReplaceAll(line, "<", " < ")
-
-	tokens := strings.Fields(line)
-
-	// Заменяем переменные окружения на их значения
-	for i := range tokens {
-		tokens[i] = expandEnvironmentVariables(tokens[i])
-	}
-
-	return tokens
+// Имена встроенных команд оболочки
+var builtinCommands = []string{
+	"cd", "pwd", "echo", "kill", "ps", "history",
+	"export", "unset", "env", "set", "alias", "unalias", "source", ".", "exit",
+	"jobs", "wait",
 }
 
 // Проверяет, является ли команда встроенной
 func isBuiltinCommand(commandName string) bool {
-	switch commandName {
-	case "cd", "pwd", "echo", "kill", "ps":
-		return true
-	}
-	return false
+	return slices.Contains(builtinCommands, commandName)
 }
 
 // Выполняет встроенную команду оболочки
@@ -127,27 +159,45 @@ func executeBuiltinCommand(args []string, stdin io.Reader, stdout io.Writer, std
 
 	switch args[0] {
 	case "cd":
-		if len(args) < 2 {
-			return 1, errors.New("cd: missing path")
-		}
-		path := args[1]
-
-		// Обработка домашней директории
-		if path == "~" {
-			if homeDir, ok := os.LookupEnv("HOME"); ok {
-				path = homeDir
+		// Без аргументов переходим в домашнюю директорию
+		// (тильда в аргументах раскрывается до вызова команды)
+		var path string
+		switch {
+		case len(args) < 2:
+			homeDir, ok := lookupVariable("HOME")
+			if !ok || homeDir == "" {
+				return 1, errors.New("cd: HOME not set")
 			}
+			path = homeDir
+		case args[1] == "-":
+			// cd - возвращается в предыдущий каталог и печатает его
+			previousDir, ok := lookupVariable("OLDPWD")
+			if !ok || previousDir == "" {
+				return 1, errors.New("cd: OLDPWD not set")
+			}
+			path = previousDir
+		default:
+			path = args[1]
 		}
 
 		// Преобразование относительного пути в абсолютный
+		currentDir, _ := os.Getwd()
 		if !filepath.IsAbs(path) {
-			currentDir, _ := os.Getwd()
 			path = filepath.Join(currentDir, path)
 		}
 
 		if err := os.Chdir(path); err != nil {
 			return 1, err
 		}
+
+		// PWD и OLDPWD экспортируются, как в bash; по OLDPWD раскрывается ~-
+		setVariable("OLDPWD", currentDir)
+		exportVariable("OLDPWD")
+		setVariable("PWD", path)
+		exportVariable("PWD")
+		if len(args) > 1 && args[1] == "-" {
+			fmt.Fprintln(stdout, path)
+		}
 		return 0, nil
 
 	case "pwd":
@@ -163,36 +213,58 @@ func executeBuiltinCommand(args []string, stdin io.Reader, stdout io.Writer, std
 		return 0, nil
 
 	case "kill":
-		if len(args) < 2 {
-			return 1, errors.New("kill: missing process ID")
-		}
-		processID, err := strconv.Atoi(args[1])
-		if err != nil {
-			return 1, err
-		}
-		process, err := os.FindProcess(processID)
-		if err != nil {
-			return 1, err
-		}
-		if err := process.Kill(); err != nil {
-			return 1, err
-		}
-		return 0, nil
+		return builtinKill(args[1:], stdout)
 
 	case "ps":
-		var command *exec.Cmd
-		if runtime.GOOS == "windows" {
-			command = exec.Command("tasklist")
-		} else {
-			command = exec.Command("ps", "-e", "-o", "pid,comm")
+		return builtinPs(args[1:], stdin, stdout, stderr)
+
+	case "jobs":
+		return builtinJobs(args[1:], stdout)
+
+	case "wait":
+		return builtinWait(args[1:])
+
+	case "history":
+		if len(args) > 1 && args[1] == "-c" {
+			if err := shellHistory.Clear(); err != nil {
+				return 1, err
+			}
+			return 0, nil
 		}
-		command.Stdin = stdin
-		command.Stdout = stdout
-		command.Stderr = stderr
-		if err := command.Run(); err != nil {
-			return 1, err
+		count := 0
+		if len(args) > 1 {
+			number, err := strconv.Atoi(args[1])
+			if err != nil || number < 0 {
+				return 1, fmt.Errorf("history: %s: numeric argument required", args[1])
+			}
+			count = number
 		}
+		shellHistory.Print(stdout, count)
 		return 0, nil
+
+	case "export":
+		return builtinExport(args[1:], stdout)
+
+	case "unset":
+		return builtinUnset(args[1:])
+
+	case "env":
+		return builtinEnv(args[1:], stdin, stdout, stderr)
+
+	case "set":
+		return builtinSet(args[1:], stdout)
+
+	case "alias":
+		return builtinAlias(args[1:], stdout)
+
+	case "unalias":
+		return builtinUnalias(args[1:])
+
+	case "source", ".":
+		return builtinSource(args[1:])
+
+	case "exit":
+		return builtinExit(args[1:])
 	}
 
 	return 127, fmt.Errorf("unknown builtin command: %s", args[0])
@@ -222,19 +294,44 @@ func parsePipelineStages(tokens []string) ([][]string, error) {
 	return stages, nil
 }
 
+// Отделяет присваивания NAME=value, стоящие перед именем команды.
+// Значения присваиваний возвращаются уже раскрытыми.
+func splitAssignments(stageTokens []string) (assignments []string, rest []string, err error) {
+	for i, token := range stageTokens {
+		if !isAssignmentWord(token) {
+			return assignments, stageTokens[i:], nil
+		}
+		name, rawValue, _ := strings.Cut(token, "=")
+		value, err := expandAssignmentValue(rawValue)
+		if err != nil {
+			return nil, nil, err
+		}
+		assignments = append(assignments, name+"="+value)
+	}
+	return assignments, nil, nil
+}
+
 // Обрабатывает перенаправления ввода/вывода в этапе пайплайна
+// и раскрывает аргументы команды
 func processRedirections(stageTokens []string) (commandArgs []string, input io.Reader, output io.Writer, err error) {
-	commandArgs = []string{}
+	var rawArgs []string
 	input = nil
 	output = nil
 
 	for i := 0; i < len(stageTokens); i++ {
 		token := stageTokens[i]
 
-		// Обработка перенаправления вывода
-		if token == ">" && i+1 < len(stageTokens) {
-			filename := stageTokens[i+1]
-			file, fileErr := os.Create(filename)
+		// Обработка перенаправления вывода (с перезаписью или дописыванием)
+		if (token == ">" || token == ">>") && i+1 < len(stageTokens) {
+			filename, expandErr := expandWord(stageTokens[i+1])
+			if expandErr != nil {
+				return nil, nil, nil, expandErr
+			}
+			flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+			if token == ">>" {
+				flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+			}
+			file, fileErr := os.OpenFile(filename, flags, 0644)
 			if fileErr != nil {
 				return nil, nil, nil, fileErr
 			}
@@ -245,7 +342,10 @@ func processRedirections(stageTokens []string) (commandArgs []string, input io.R
 
 		// Обработка перенаправления ввода
 		if token == "<" && i+1 < len(stageTokens) {
-			filename := stageTokens[i+1]
+			filename, expandErr := expandWord(stageTokens[i+1])
+			if expandErr != nil {
+				return nil, nil, nil, expandErr
+			}
 			file, fileErr := os.Open(filename)
 			if fileErr != nil {
 				return nil, nil, nil, fileErr
@@ -255,14 +355,36 @@ func processRedirections(stageTokens []string) (commandArgs []string, input io.R
 			continue
 		}
 
-		commandArgs = append(commandArgs, token)
+		rawArgs = append(rawArgs, token)
 	}
 
+	commandArgs, err = expandWords(rawArgs)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 	return commandArgs, input, output, nil
 }
 
+// Этап пайплайна: внешняя команда или встроенная команда, выполняемая в горутине.
+// closeAfterStart - копии каналов и файлов перенаправлений, которые оболочка
+// закрывает после запуска процесса (или после завершения встроенной команды).
+type pipelineStage struct {
+	args            []string
+	assignments     []string
+	command         *exec.Cmd
+	stdin           io.Reader
+	stdout          io.Writer
+	closeAfterStart []io.Closer
+}
+
 // Выполняет пайплайн команд
 func executePipeline(tokens []string) (int, error) {
+	return executePipelineMode(tokens, false)
+}
+
+// Выполняет пайплайн команд; фоновый пайплайн запускается в отдельной
+// группе процессов и регистрируется как задание без ожидания завершения
+func executePipelineMode(tokens []string, background bool) (int, error) {
 	stages, err := parsePipelineStages(tokens)
 	if err != nil {
 		return 1, err
@@ -271,119 +393,254 @@ func executePipeline(tokens []string) (int, error) {
 		return 0, nil
 	}
 
-	// Обработка одиночной команды (без пайплайна) с возможными перенаправлениями
-	if len(stages) == 1 {
-		args, inputReader, outputWriter, err := processRedirections(stages[0])
+	// Раскрываем слова и открываем перенаправления для каждого этапа
+	substitutionStatus = 0
+	var pipeline []*pipelineStage
+	closeAll := func() {
+		for _, stage := range pipeline {
+			for _, closer := range stage.closeAfterStart {
+				_ = closer.Close()
+			}
+		}
+	}
+	for _, stageTokens := range stages {
+		assignments, commandTokens, err := splitAssignments(stageTokens)
 		if err != nil {
+			closeAll()
 			return 1, err
 		}
-		if len(args) == 0 {
-			return 0, nil
+		args, inputRedirect, outputRedirect, err := processRedirections(commandTokens)
+		if err != nil {
+			closeAll()
+			return 1, err
+		}
+
+		stage := &pipelineStage{args: args, assignments: assignments, stdin: inputRedirect, stdout: outputRedirect}
+		if inputRedirect != nil {
+			stage.closeAfterStart = append(stage.closeAfterStart, inputRedirect.(io.Closer))
+		}
+		if outputRedirect != nil {
+			stage.closeAfterStart = append(stage.closeAfterStart, outputRedirect.(io.Closer))
 		}
+		pipeline = append(pipeline, stage)
 
-		// Выполнение встроенной команды
-		if isBuiltinCommand(args[0]) {
+		if len(args) == 0 && (len(stages) > 1 || background) {
+			closeAll()
+			return 1, errors.New("empty command")
+		}
+	}
+
+	// Одиночная команда из присваиваний или встроенная команда выполняется в текущей оболочке
+	if len(pipeline) == 1 && !background {
+		stage := pipeline[0]
+		if len(stage.args) == 0 {
+			// Строка из одних присваиваний задает переменные оболочки; код возврата -
+			// код последней подстановки команды (POSIX), без подстановок - 0
+			for _, assignment := range stage.assignments {
+				name, value, _ := strings.Cut(assignment, "=")
+				setVariable(name, value)
+			}
+			closeAll()
+			pipeStatus = []int{substitutionStatus}
+			return substitutionStatus, nil
+		}
+
+		if isBuiltinCommand(stage.args[0]) {
+			inputReader, outputWriter := stage.stdin, stage.stdout
 			if inputReader == nil {
 				inputReader = os.Stdin
 			}
 			if outputWriter == nil {
 				outputWriter = os.Stdout
 			}
-			exitCode, err := executeBuiltinCommand(args, inputReader, outputWriter, os.Stderr)
-			// Закрываем файл вывода, если он был открыт
-			if closer, ok := outputWriter.(io.Closer); ok && closer != os.Stdout {
-				_ = closer.Close()
-			}
+			// Присваивания перед встроенной командой действуют только на время ее выполнения
+			var exitCode int
+			withTemporaryEnvironment(stage.assignments, func() {
+				exitCode, err = executeBuiltinCommand(stage.args, inputReader, outputWriter, os.Stderr)
+			})
+			// Закрываем файлы перенаправлений, если они были открыты
+			closeAll()
+			pipeStatus = []int{exitCode}
 			return exitCode, err
 		}
 	}
 
-	var commands []*exec.Cmd
-	var previousOutput io.Reader = os.Stdin
-	var finalOutput io.Writer = os.Stdout
-	var filesToClose []io.Closer
+	// Соединяем этапы каналами ОС; перенаправления имеют приоритет над каналами
+	for stageIndex, stage := range pipeline {
+		if stageIndex < len(pipeline)-1 {
+			reader, writer, err := os.Pipe()
+			if err != nil {
+				closeAll()
+				return 1, err
+			}
+			next := pipeline[stageIndex+1]
+			stage.closeAfterStart = append(stage.closeAfterStart, writer)
+			next.closeAfterStart = append(next.closeAfterStart, reader)
+			if stage.stdout == nil {
+				stage.stdout = writer
+			}
+			if next.stdin == nil {
+				next.stdin = reader
+			}
+		}
+		if stage.stdin == nil {
+			stage.stdin = os.Stdin
+		}
+		if stage.stdout == nil {
+			stage.stdout = os.Stdout
+		}
 
-	// Создаем команды для каждого этапа пайплайна
-	for stageIndex, stageTokens := range stages {
-		args, inputRedirect, outputRedirect, err := processRedirections(stageTokens)
-		if err != nil {
-			return 1, err
+		var command *exec.Cmd
+		if !isBuiltinCommand(stage.args[0]) {
+			command = exec.Command(stage.args[0], stage.args[1:]...)
+			if len(stage.assignments) > 0 {
+				command.Env = append(os.Environ(), stage.assignments...)
+			}
+		} else if len(stage.assignments) > 0 || !isStatelessBuiltin(stage.args) {
+			// Этапы пайплайна выполняются в подоболочках: встроенная команда, меняющая
+			// состояние оболочки, запускается отдельным процессом
+			if command, err = newBuiltinProcess(stage.args, stage.assignments); err != nil {
+				closeAll()
+				return 1, err
+			}
+			for _, file := range command.ExtraFiles {
+				stage.closeAfterStart = append(stage.closeAfterStart, file)
+			}
 		}
-		if len(args) == 0 {
-			return 1, errors.New("empty command")
+		if command != nil {
+			command.Stdin = stage.stdin
+			command.Stdout = stage.stdout
+			command.Stderr = os.Stderr
+			stage.command = command
 		}
+	}
 
-		commandName := args[0]
-		commandArgs := args[1:]
-		command := exec.Command(commandName, commandArgs...)
+	statuses := make([]int, len(pipeline))
+	var commands []*exec.Cmd
+	var builtinsDone sync.WaitGroup
+	processGroup := 0
+
+	// Запускаем все этапы; ненайденная команда получает код 127, остальные этапы выполняются
+	for stageIndex, stage := range pipeline {
+		// Встроенная команда, не меняющая состояние оболочки, выполняется в горутине
+		if stage.command == nil {
+			builtinsDone.Add(1)
+			go func(stageIndex int, stage *pipelineStage) {
+				defer builtinsDone.Done()
+				exitCode, err := executeBuiltinCommand(stage.args, stage.stdin, stage.stdout, os.Stderr)
+				if err != nil && !errors.Is(err, errExitRequested) {
+					fmt.Fprintln(os.Stderr, err)
+				}
+				statuses[stageIndex] = exitCode
+				for _, closer := range stage.closeAfterStart {
+					_ = closer.Close()
+				}
+			}(stageIndex, stage)
+			continue
+		}
 
-		// Настройка ввода
-		if inputRedirect != nil {
-			command.Stdin = inputRedirect
-			if closer, ok := inputRedirect.(io.Closer); ok {
-				filesToClose = append(filesToClose, closer)
-			}
+		// Все процессы фонового задания помещаются в группу первого процесса
+		if background {
+			setProcessGroup(stage.command, processGroup)
+		}
+		if err := stage.command.Start(); err != nil {
+			fmt.Fprintln(os.Stderr, commandStartError(stage.args[0], err))
+			statuses[stageIndex] = exitStatusFromError(err)
 		} else {
-			command.Stdin = previousOutput
+			commands = append(commands, stage.command)
+			if background && processGroup == 0 {
+				processGroup = stage.command.Process.Pid
+			}
+		}
+		for _, closer := range stage.closeAfterStart {
+			_ = closer.Close()
 		}
+	}
 
-		// Настройка вывода
-		if stageIndex < len(stages)-1 {
-			// Промежуточный этап - перенаправляем вывод на следующий этап
-			outputPipe, err := command.StdoutPipe()
-			if err != nil {
-				return 1, err
-			}
-			previousOutput = outputPipe
-		} else {
-			// Последний этап - используем перенаправление или stdout
-			if outputRedirect != nil {
-				command.Stdout = outputRedirect
-				if closer, ok := outputRedirect.(io.Closer); ok {
-					filesToClose = append(filesToClose, closer)
-				}
-			} else {
-				command.Stdout = finalOutput
+	// Ожидает завершения всех команд и собирает их коды возврата
+	waitPipeline := func() []int {
+		for stageIndex, stage := range pipeline {
+			if stage.command != nil && stage.command.Process != nil {
+				statuses[stageIndex] = exitStatusFromError(stage.command.Wait())
 			}
 		}
+		builtinsDone.Wait()
+		return statuses
+	}
 
-		command.Stderr = os.Stderr
-		commands = append(commands, command)
+	if background {
+		job := addBackgroundJob(strings.Join(tokens, " "), commands, processGroup, func() int {
+			return pipelineExitStatus(waitPipeline())
+		})
+		announceBackgroundJob(job)
+		return 0, nil
 	}
 
 	// Сохраняем список запущенных процессов для возможного прерывания
 	setRunningProcesses(commands)
 	defer clearRunningProcesses()
 
-	// Запускаем все команды
-	for _, cmd := range commands {
-		if err := cmd.Start(); err != nil {
-			return 1, err
-		}
+	pipeStatus = waitPipeline()
+	return pipelineExitStatus(pipeStatus), nil
+}
+
+// Обрабатывает строку команд с операторами ;, && и ||
+func processCommandLine(line string) int {
+	tokens, err := tokenizeCommandLine(line)
+	if err == nil {
+		tokens, err = expandAliases(tokens)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		lastExitStatus = 2
+		return 2
 	}
 
-	// Ожидаем завершения всех команд
-	var waitError error
-	for i := len(commands) - 1; i >= 0; i-- {
-		if err := commands[i].Wait(); err != nil && waitError == nil {
-			waitError = err
+	// Списки команд, разделенные ';', выполняются последовательно,
+	// список, завершенный '&', запускается в фоне
+	exitCode := 0
+	var commandList []string
+	for _, token := range tokens {
+		if token == ";" || token == "&" {
+			if len(commandList) == 0 {
+				fmt.Fprintf(os.Stderr, "syntax error near unexpected token `%s'\n", token)
+				lastExitStatus = 2
+				return 2
+			}
+			if token == "&" {
+				exitCode = executeBackground(commandList)
+			} else {
+				exitCode = executeAndOrList(commandList)
+			}
+			commandList = nil
+			continue
 		}
+		commandList = append(commandList, token)
 	}
-
-	// Закрываем все открытые файлы
-	for _, closer := range filesToClose {
-		_ = closer.Close()
+	if len(commandList) > 0 {
+		exitCode = executeAndOrList(commandList)
 	}
+	return exitCode
+}
 
-	if waitError != nil {
-		return 1, waitError
+// Запускает пайплайн в фоне и регистрирует его как задание
+func executeBackground(tokens []string) int {
+	if slices.Contains(tokens, "&&") || slices.Contains(tokens, "||") {
+		fmt.Fprintln(os.Stderr, "background execution of && and || lists is not supported")
+		lastExitStatus = 2
+		return 2
 	}
-	return 0, nil
+	if _, err := executePipelineMode(tokens, true); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		lastExitStatus = 1
+		return 1
+	}
+	lastExitStatus = 0
+	return 0
 }
 
-// Обрабатывает строку команд с операторами && и ||
-func processCommandLine(line string) int {
-	tokens := tokenizeCommandLine(line)
+// Выполняет список пайплайнов, связанных операторами && и ||
+func executeAndOrList(tokens []string) int {
 	if len(tokens) == 0 {
 		return 0
 	}
@@ -396,8 +653,10 @@ func processCommandLine(line string) int {
 	for i := 0; i < len(tokens); i++ {
 		token := tokens[i]
 		if token == "&&" || token == "||" {
-			if len(currentSegment) == 0 {
-				return 1
+			if len(currentSegment) == 0 || i == len(tokens)-1 {
+				fmt.Fprintf(os.Stderr, "syntax error near unexpected token `%s'\n", token)
+				lastExitStatus = 2
+				return 2
 			}
 			commandSegments = append(commandSegments, currentSegment)
 			logicalOperators = append(logicalOperators, token)
@@ -415,6 +674,7 @@ func processCommandLine(line string) int {
 	}
 
 	previousExitCode := 0
+	lastExecuted, lastNegated := -1, false
 	// Выполняем сегменты с учетом логических операторов
 	for segmentIndex, segment := range commandSegments {
 		if segmentIndex > 0 {
@@ -427,8 +687,41 @@ func processCommandLine(line string) int {
 				continue
 			}
 		}
-		exitCode, _ := executePipeline(segment)
+
+		// Оператор '!' инвертирует код возврата пайплайна
+		negated := segment[0] == "!"
+		if negated {
+			segment = segment[1:]
+		}
+
+		exitCode := 0
+		if len(segment) > 0 {
+			var err error
+			exitCode, err = executePipeline(segment)
+			if errors.Is(err, errExitRequested) {
+				exitShell(exitCode)
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+		if negated {
+			if exitCode == 0 {
+				exitCode = 1
+			} else {
+				exitCode = 0
+			}
+		}
+
 		previousExitCode = exitCode
+		lastExitStatus = exitCode
+		lastExecuted, lastNegated = segmentIndex, negated
+	}
+
+	// set -e: ошибка последнего пайплайна списка завершает оболочку
+	// (кроме команд в условиях && и || и пайплайнов с '!')
+	if shellOptions["errexit"] && previousExitCode != 0 && lastExecuted == len(commandSegments)-1 && !lastNegated {
+		exitShell(previousExitCode)
 	}
 	return previousExitCode
 }
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Уровни приоритета бинарных операторов арифметики (от низшего к высшему)
+var arithmeticPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"|"},
+	{"^"},
+	{"&"},
+	{"==", "!="},
+	{"<=", ">=", "<", ">"},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// Операторы присваивания: составной оператор op= применяет op к текущему значению
+var arithmeticAssignments = []string{
+	"=", "+=", "-=", "*=", "/=", "%=", "<<=", ">>=", "&=", "^=", "|=",
+}
+
+// Операторы арифметики в порядке убывания длины для разбора
+var arithmeticOperators = []string{
+	"<<=", ">>=",
+	"**", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||", "++", "--",
+	"+=", "-=", "*=", "/=", "%=", "&=", "^=", "|=",
+	"+", "-", "*", "/", "%", "<", ">", "&", "|", "^", "!", "~", "(", ")", "?", ":", "=",
+}
+
+// Наибольшая глубина вложенного вычисления переменных: защищает от x=x
+const maxArithmeticDepth = 1024
+
+// Разборщик целочисленного арифметического выражения $((...))
+type arithmeticParser struct {
+	tokens   []string
+	position int
+	depth    int  // уровень вложенности вычисления значений переменных
+	skip     bool // вычисляется невыбранная ветвь &&, || или ?: без побочных эффектов
+}
+
+// Вычисляет целочисленное арифметическое выражение
+func evaluateArithmetic(expression string) (int64, error) {
+	return evaluateArithmeticDepth(expression, 0)
+}
+
+// Вычисляет выражение на заданном уровне вложенности
+func evaluateArithmeticDepth(expression string, depth int) (int64, error) {
+	if depth >= maxArithmeticDepth {
+		return 0, fmt.Errorf("%s: expression recursion level exceeded", expression)
+	}
+	tokens, err := tokenizeArithmetic(expression)
+	if err != nil {
+		return 0, err
+	}
+	if len(tokens) == 0 {
+		return 0, nil
+	}
+
+	parser := &arithmeticParser{tokens: tokens, depth: depth}
+	value, err := parser.parseAssignment()
+	if err != nil {
+		return 0, err
+	}
+	if parser.position < len(tokens) {
+		return 0, fmt.Errorf("%s: syntax error in expression (error token is %q)", expression, tokens[parser.position])
+	}
+	return value, nil
+}
+
+// Разбивает арифметическое выражение на числа, имена и операторы
+func tokenizeArithmetic(expression string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(expression); {
+		char := expression[i]
+		switch {
+		case char == ' ' || char == '\t' || char == '\n':
+			i++
+		case isNameChar(char, false):
+			end := i
+			for end < len(expression) && isNameChar(expression[end], false) {
+				end++
+			}
+			tokens = append(tokens, expression[i:end])
+			i = end
+		default:
+			operator := ""
+			for _, candidate := range arithmeticOperators {
+				if strings.HasPrefix(expression[i:], candidate) {
+					operator = candidate
+					break
+				}
+			}
+			if operator == "" {
+				return nil, fmt.Errorf("%s: syntax error: invalid arithmetic operator (error token is %q)", expression, expression[i:])
+			}
+			// ++ и -- относятся к соседнему имени; иначе это два унарных оператора, как в 5--3
+			if operator == "++" || operator == "--" {
+				afterName := len(tokens) > 0 && isNameChar(tokens[len(tokens)-1][0], true)
+				rest := strings.TrimLeft(expression[i+2:], " \t\n")
+				if !afterName && (rest == "" || !isNameChar(rest[0], true)) {
+					operator = operator[:1]
+				}
+			}
+			tokens = append(tokens, operator)
+			i += len(operator)
+		}
+	}
+	return tokens, nil
+}
+
+// Возвращает текущий токен или пустую строку в конце выражения
+func (p *arithmeticParser) peek() string {
+	if p.position < len(p.tokens) {
+		return p.tokens[p.position]
+	}
+	return ""
+}
+
+// Разбирает присваивание name = value и name op= value (правоассоциативное)
+func (p *arithmeticParser) parseAssignment() (int64, error) {
+	name := p.peek()
+	if name == "" || !isNameChar(name[0], true) || p.position+1 >= len(p.tokens) {
+		return p.parseConditional()
+	}
+	operator := p.tokens[p.position+1]
+	isAssignment := false
+	for _, candidate := range arithmeticAssignments {
+		if operator == candidate {
+			isAssignment = true
+			break
+		}
+	}
+	if !isAssignment {
+		return p.parseConditional()
+	}
+	p.position += 2
+
+	value, err := p.parseAssignment()
+	if err != nil {
+		return 0, err
+	}
+	if operator != "=" {
+		current, err := p.variableValue(name)
+		if err != nil {
+			return 0, err
+		}
+		if value, err = p.apply(strings.TrimSuffix(operator, "="), current, value); err != nil {
+			return 0, err
+		}
+	}
+	p.assign(name, value)
+	return value, nil
+}
+
+// Разбирает тернарный оператор cond ? a : b; вычисляется только выбранная ветвь
+func (p *arithmeticParser) parseConditional() (int64, error) {
+	condition, err := p.parseBinary(0)
+	if err != nil || p.peek() != "?" {
+		return condition, err
+	}
+	p.position++
+	whenTrue, err := p.parseSkipped(condition == 0, p.parseAssignment)
+	if err != nil {
+		return 0, err
+	}
+	if p.peek() != ":" {
+		return 0, errors.New("syntax error in expression: expected `:'")
+	}
+	p.position++
+	whenFalse, err := p.parseSkipped(condition != 0, p.parseConditional)
+	if err != nil {
+		return 0, err
+	}
+	if condition != 0 {
+		return whenTrue, nil
+	}
+	return whenFalse, nil
+}
+
+// Разбирает бинарные операторы заданного уровня приоритета (левоассоциативные)
+func (p *arithmeticParser) parseBinary(level int) (int64, error) {
+	if level >= len(arithmeticPrecedence) {
+		return p.parsePower()
+	}
+
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		operator := p.peek()
+		found := false
+		for _, candidate := range arithmeticPrecedence[level] {
+			if operator == candidate {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return left, nil
+		}
+		p.position++
+
+		// Правый операнд && и || не вычисляется, если результат уже известен
+		skip := (operator == "&&" && left == 0) || (operator == "||" && left != 0)
+		right, err := p.parseSkipped(skip, func() (int64, error) { return p.parseBinary(level + 1) })
+		if err != nil {
+			return 0, err
+		}
+		left, err = p.apply(operator, left, right)
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Разбирает возведение в степень (правоассоциативное)
+func (p *arithmeticParser) parsePower() (int64, error) {
+	base, err := p.parseUnary()
+	if err != nil || p.peek() != "**" {
+		return base, err
+	}
+	p.position++
+	exponent, err := p.parsePower()
+	if err != nil {
+		return 0, err
+	}
+	if exponent < 0 {
+		return 0, errors.New("exponent less than 0")
+	}
+	// Возведение в квадрат: число умножений - логарифм показателя
+	result := int64(1)
+	for ; exponent > 0; exponent >>= 1 {
+		if exponent&1 == 1 {
+			result *= base
+		}
+		base *= base
+	}
+	return result, nil
+}
+
+// Разбирает унарные операторы +, -, !, ~ и префиксные ++ и --
+func (p *arithmeticParser) parseUnary() (int64, error) {
+	operator := p.peek()
+	if operator == "++" || operator == "--" {
+		p.position++
+		name := p.peek()
+		if name == "" || !isNameChar(name[0], true) {
+			return 0, fmt.Errorf("syntax error: identifier expected after %s", operator)
+		}
+		p.position++
+		value, err := p.variableValue(name)
+		if err != nil {
+			return 0, err
+		}
+		value += incrementStep(operator)
+		p.assign(name, value)
+		return value, nil
+	}
+	if operator != "+" && operator != "-" && operator != "!" && operator != "~" {
+		return p.parsePrimary()
+	}
+	p.position++
+	value, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+	switch operator {
+	case "-":
+		return -value, nil
+	case "!":
+		return boolToInt(value == 0), nil
+	case "~":
+		return ^value, nil
+	}
+	return value, nil
+}
+
+// Разбирает число, имя переменной или выражение в скобках
+func (p *arithmeticParser) parsePrimary() (int64, error) {
+	token := p.peek()
+	if token == "" {
+		return 0, errors.New("syntax error: operand expected")
+	}
+	p.position++
+
+	if token == "(" {
+		value, err := p.parseAssignment()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ")" {
+			return 0, errors.New("syntax error: missing `)'")
+		}
+		p.position++
+		return value, nil
+	}
+
+	if isNameChar(token[0], true) {
+		value, err := p.variableValue(token)
+		if err != nil {
+			return 0, err
+		}
+		// Постфиксные ++ и -- возвращают значение до изменения
+		if operator := p.peek(); operator == "++" || operator == "--" {
+			p.position++
+			p.assign(token, value+incrementStep(operator))
+		}
+		return value, nil
+	}
+
+	if token[0] >= '0' && token[0] <= '9' {
+		value, err := strconv.ParseInt(token, 0, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s: value too great for base (error token is %q)", token, token)
+		}
+		return value, nil
+	}
+	return 0, fmt.Errorf("syntax error: operand expected (error token is %q)", token)
+}
+
+// Разбирает операнд; при skip его присваивания и ошибки деления не действуют
+func (p *arithmeticParser) parseSkipped(skip bool, parse func() (int64, error)) (int64, error) {
+	if !skip || p.skip {
+		return parse()
+	}
+	p.skip = true
+	defer func() { p.skip = false }()
+	return parse()
+}
+
+// Значение переменной вычисляется как выражение; пустое значение равно 0
+func (p *arithmeticParser) variableValue(name string) (int64, error) {
+	value, _ := lookupVariable(name)
+	if strings.TrimSpace(value) == "" {
+		return 0, nil
+	}
+	return evaluateArithmeticDepth(value, p.depth+1)
+}
+
+// Записывает результат присваивания в переменную оболочки
+func (p *arithmeticParser) assign(name string, value int64) {
+	if !p.skip {
+		setVariable(name, strconv.FormatInt(value, 10))
+	}
+}
+
+// Применяет бинарный оператор; в пропускаемой ветви деление на 0 не ошибка
+func (p *arithmeticParser) apply(operator string, left, right int64) (int64, error) {
+	if p.skip && right == 0 && (operator == "/" || operator == "%") {
+		return 0, nil
+	}
+	return applyArithmeticOperator(operator, left, right)
+}
+
+// Шаг инкремента для оператора ++ или --
+func incrementStep(operator string) int64 {
+	if operator == "--" {
+		return -1
+	}
+	return 1
+}
+
+// Применяет бинарный оператор к операндам
+func applyArithmeticOperator(operator string, left, right int64) (int64, error) {
+	switch operator {
+	case "+":
+		return left + right, nil
+	case "-":
+		return left - right, nil
+	case "*":
+		return left * right, nil
+	case "/", "%":
+		if right == 0 {
+			return 0, errors.New("division by 0")
+		}
+		if operator == "/" {
+			return left / right, nil
+		}
+		return left % right, nil
+	case "<<":
+		return left << uint64(right), nil
+	case ">>":
+		return left >> uint64(right), nil
+	case "<":
+		return boolToInt(left < right), nil
+	case "<=":
+		return boolToInt(left <= right), nil
+	case ">":
+		return boolToInt(left > right), nil
+	case ">=":
+		return boolToInt(left >= right), nil
+	case "==":
+		return boolToInt(left == right), nil
+	case "!=":
+		return boolToInt(left != right), nil
+	case "&":
+		return left & right, nil
+	case "^":
+		return left ^ right, nil
+	case "|":
+		return left | right, nil
+	case "&&":
+		return boolToInt(left != 0 && right != 0), nil
+	case "||":
+		return boolToInt(left != 0 || right != 0), nil
+	}
+	return 0, fmt.Errorf("unknown operator %s", operator)
+}
+
+// Преобразует логическое значение в 1 или 0
+func boolToInt(value bool) int64 {
+	if value {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEvaluateArithmetic(t *testing.T) {
+	setVariable("arithA", "6")
+	setVariable("arithB", "arithA * 2")
+	t.Cleanup(func() {
+		unsetVariable("arithA")
+		unsetVariable("arithB")
+	})
+
+	tests := []struct {
+		expression string
+		expected   int64
+	}{
+		{"", 0},
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"7 / 2 + 7 % 2", 4},
+		{"-3 + +5", 2},
+		{"2 ** 3 ** 2", 512},
+		{"2 ** 62", 1 << 62},
+		{"3 ** 0", 1},
+		{"2 ** 9999999999", 0}, // переполнение, но без миллиардов умножений
+		{"1 << 4 | 1", 17},
+		{"!0 + !5 + ~0", 0},
+		{"1 < 2 && 2 <= 2 && 3 > 2 && 3 >= 4 || 5 == 5", 1},
+		{"0x10 + 010", 24},
+		{"1 ? 2 : 3", 2},
+		{"0 ? 2 : 1 ? 4 : 5", 4},
+		{"arithA + 1", 7},
+		{"arithB", 12},
+		{"undefined + 1", 1},
+		{"5--3", 8},
+		{"1+-+-1", 2},
+	}
+
+	for _, test := range tests {
+		value, err := evaluateArithmetic(test.expression)
+		if err != nil {
+			t.Errorf("evaluateArithmetic(%q): %v", test.expression, err)
+			continue
+		}
+		if value != test.expected {
+			t.Errorf("evaluateArithmetic(%q) = %d, expected %d", test.expression, value, test.expected)
+		}
+	}
+}
+
+func TestEvaluateArithmeticAssignment(t *testing.T) {
+	t.Cleanup(func() {
+		for _, name := range []string{"arithX", "arithY", "arithZ"} {
+			unsetVariable(name)
+		}
+	})
+
+	// Выражения вычисляются по порядку и видят результаты предыдущих
+	tests := []struct {
+		expression string
+		expected   int64
+		variables  map[string]string
+	}{
+		{"arithX = 5", 5, map[string]string{"arithX": "5"}},
+		{"arithX += 3", 8, map[string]string{"arithX": "8"}},
+		{"arithX -= 1", 7, map[string]string{"arithX": "7"}},
+		{"arithX *= 6", 42, map[string]string{"arithX": "42"}},
+		{"arithX /= 5", 8, map[string]string{"arithX": "8"}},
+		{"arithX %= 5", 3, map[string]string{"arithX": "3"}},
+		{"arithX <<= 4", 48, map[string]string{"arithX": "48"}},
+		{"arithX >>= 2", 12, map[string]string{"arithX": "12"}},
+		{"arithX &= 10", 8, map[string]string{"arithX": "8"}},
+		{"arithX |= 3", 11, map[string]string{"arithX": "11"}},
+		{"arithX ^= 1", 10, map[string]string{"arithX": "10"}},
+		{"arithY = arithZ = arithX + 1", 11, map[string]string{"arithY": "11", "arithZ": "11"}},
+		{"arithX++", 10, map[string]string{"arithX": "11"}},
+		{"arithX--", 11, map[string]string{"arithX": "10"}},
+		{"++arithX", 11, map[string]string{"arithX": "11"}},
+		{"--arithX", 10, map[string]string{"arithX": "10"}},
+		{"arithX++ + ++arithX", 22, map[string]string{"arithX": "12"}},
+		{"(arithY = 2) * 3", 6, map[string]string{"arithY": "2"}},
+		// Невыбранные ветви &&, || и ?: не меняют переменные
+		{"0 && arithX++", 0, map[string]string{"arithX": "12"}},
+		{"1 || arithX++", 1, map[string]string{"arithX": "12"}},
+		{"0 && 1 / 0", 0, nil},
+		{"arithY ? arithZ = 1 : (arithX = 0)", 1, map[string]string{"arithX": "12", "arithZ": "1"}},
+	}
+
+	for _, test := range tests {
+		value, err := evaluateArithmetic(test.expression)
+		if err != nil {
+			t.Errorf("evaluateArithmetic(%q): %v", test.expression, err)
+			continue
+		}
+		if value != test.expected {
+			t.Errorf("evaluateArithmetic(%q) = %d, expected %d", test.expression, value, test.expected)
+		}
+		for name, expected := range test.variables {
+			if actual, _ := lookupVariable(name); actual != expected {
+				t.Errorf("evaluateArithmetic(%q): %s = %q, expected %q", test.expression, name, actual, expected)
+			}
+		}
+	}
+}
+
+func TestEvaluateArithmeticErrors(t *testing.T) {
+	// Переменная, ссылающаяся на себя, не должна переполнять стек
+	setVariable("arithSelf", "arithSelf")
+	setVariable("arithPing", "arithPong + 1")
+	setVariable("arithPong", "arithPing")
+	t.Cleanup(func() {
+		unsetVariable("arithSelf")
+		unsetVariable("arithPing")
+		unsetVariable("arithPong")
+	})
+
+	tests := []struct {
+		expression string
+		message    string
+	}{
+		{"1 / 0", "division by 0"},
+		{"1 % 0", "division by 0"},
+		{"2 ** -1", "exponent less than 0"},
+		{"(1 + 2", "missing `)'"},
+		{"1 +", "operand expected"},
+		{"1 ? 2", "expected `:'"},
+		{"1 2", "syntax error"},
+		{"1 @ 2", "invalid arithmetic operator"},
+		{"1 = 2", "syntax error"},
+		{"arithSelf += 1", "expression recursion level exceeded"},
+		{"99999999999999999999", "value too great"},
+		{"arithSelf", "expression recursion level exceeded"},
+		{"arithPing", "expression recursion level exceeded"},
+	}
+
+	for _, test := range tests {
+		value, err := evaluateArithmetic(test.expression)
+		if err == nil || !strings.Contains(err.Error(), test.message) {
+			t.Errorf("evaluateArithmetic(%q) = %d, %v, expected error containing %q", test.expression, value, err, test.message)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Встроенная команда export: экспорт переменных в окружение
+func builtinExport(args []string, stdout io.Writer) (int, error) {
+	unexport := false
+	if len(args) > 0 && (args[0] == "-n" || args[0] == "-p") {
+		unexport = args[0] == "-n"
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		environment := os.Environ()
+		sort.Strings(environment)
+		for _, entry := range environment {
+			name, value, _ := strings.Cut(entry, "=")
+			fmt.Fprintf(stdout, "export %s=%s\n", name, shellQuote(value))
+		}
+		return 0, nil
+	}
+
+	exitCode := 0
+	var errs []error
+	for _, arg := range args {
+		name, value, hasValue := strings.Cut(arg, "=")
+		if !isValidVariableName(name) {
+			errs = append(errs, fmt.Errorf("export: `%s': not a valid identifier", arg))
+			exitCode = 1
+			continue
+		}
+		if unexport {
+			unexportVariable(name)
+			continue
+		}
+		if hasValue {
+			setVariable(name, value)
+		}
+		exportVariable(name)
+	}
+	return exitCode, errors.Join(errs...)
+}
+
+// Встроенная команда unset: удаление переменных
+func builtinUnset(args []string) (int, error) {
+	if len(args) > 0 && args[0] == "-v" {
+		args = args[1:]
+	}
+	exitCode := 0
+	var errs []error
+	for _, name := range args {
+		if !isValidVariableName(name) {
+			errs = append(errs, fmt.Errorf("unset: `%s': not a valid identifier", name))
+			exitCode = 1
+			continue
+		}
+		unsetVariable(name)
+	}
+	return exitCode, errors.Join(errs...)
+}
+
+// Встроенная команда env: вывод окружения или запуск команды в измененном окружении
+func builtinEnv(args []string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	environment := os.Environ()
+
+	for len(args) > 0 {
+		arg := args[0]
+		if arg == "-i" || arg == "-" {
+			environment = nil
+		} else if arg == "-u" && len(args) > 1 {
+			environment = removeFromEnvironment(environment, args[1])
+			args = args[1:]
+		} else if isAssignmentWord(arg) {
+			name, _, _ := strings.Cut(arg, "=")
+			environment = append(removeFromEnvironment(environment, name), arg)
+		} else {
+			break
+		}
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		for _, entry := range environment {
+			fmt.Fprintln(stdout, entry)
+		}
+		return 0, nil
+	}
+
+	command := exec.Command(args[0], args[1:]...)
+	command.Env = environment
+	command.Stdin = stdin
+	command.Stdout = stdout
+	command.Stderr = stderr
+	if err := command.Start(); err != nil {
+		return exitStatusFromError(err), commandStartError(args[0], err)
+	}
+	// Код завершения команды становится кодом env без сообщения об ошибке
+	return exitStatusFromError(command.Wait()), nil
+}
+
+// Удаляет переменную из списка окружения в формате NAME=value
+func removeFromEnvironment(environment []string, name string) []string {
+	result := environment[:0:0]
+	for _, entry := range environment {
+		if !strings.HasPrefix(entry, name+"=") {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// Встроенная команда alias: определение и вывод псевдонимов
+func builtinAlias(args []string, stdout io.Writer) (int, error) {
+	if len(args) == 0 || (len(args) == 1 && args[0] == "-p") {
+		names := make([]string, 0, len(shellAliases))
+		for name := range shellAliases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(stdout, "alias %s=%s\n", name, shellQuote(shellAliases[name]))
+		}
+		return 0, nil
+	}
+
+	exitCode := 0
+	var errs []error
+	for _, arg := range args {
+		name, value, hasValue := strings.Cut(arg, "=")
+		if !hasValue {
+			aliasValue, ok := shellAliases[name]
+			if !ok {
+				errs = append(errs, fmt.Errorf("alias: %s: not found", name))
+				exitCode = 1
+				continue
+			}
+			fmt.Fprintf(stdout, "alias %s=%s\n", name, shellQuote(aliasValue))
+			continue
+		}
+		if name == "" || strings.ContainsAny(name, " \t/$`'\"\\|&;<>()=") {
+			errs = append(errs, fmt.Errorf("alias: `%s': invalid alias name", name))
+			exitCode = 1
+			continue
+		}
+		shellAliases[name] = value
+	}
+	return exitCode, errors.Join(errs...)
+}
+
+// Встроенная команда unalias: удаление псевдонимов
+func builtinUnalias(args []string) (int, error) {
+	if len(args) == 0 {
+		return 2, errors.New("unalias: usage: unalias [-a] name [name ...]")
+	}
+	if args[0] == "-a" {
+		clear(shellAliases)
+		return 0, nil
+	}
+
+	exitCode := 0
+	var errs []error
+	for _, name := range args {
+		if _, ok := shellAliases[name]; !ok {
+			errs = append(errs, fmt.Errorf("unalias: %s: not found", name))
+			exitCode = 1
+			continue
+		}
+		delete(shellAliases, name)
+	}
+	return exitCode, errors.Join(errs...)
+}
+
+// Встроенная команда source (.): выполнение команд из файла в текущей оболочке
+func builtinSource(args []string) (int, error) {
+	if len(args) == 0 {
+		return 2, errors.New("source: filename argument required")
+	}
+	return sourceFile(args[0])
+}
+
+// Выполняет команды из файла построчно в текущей оболочке
+func sourceFile(filename string) (int, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 1, fmt.Errorf("source: %w", err)
+	}
+	defer file.Close()
+
+	exitCode := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		exitCode = processCommandLine(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return 1, fmt.Errorf("source: %w", err)
+	}
+	return exitCode, nil
+}
+
+// Загружает ~/.minishellrc при запуске оболочки, если файл существует
+func loadStartupFile() {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
+	filename := filepath.Join(homeDir, ".minishellrc")
+	if _, err := os.Stat(filename); err != nil {
+		return
+	}
+	if _, err := sourceFile(filename); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinEnvStatus(t *testing.T) {
+	tests := []struct {
+		args    []string
+		status  int
+		message string
+	}{
+		{[]string{"true"}, 0, ""},
+		{[]string{"false"}, 1, ""},
+		{[]string{"sh", "-c", "exit 7"}, 7, ""},
+		{[]string{"ENV_TEST=1", "sh", "-c", `test "$ENV_TEST" = 1`}, 0, ""},
+		{[]string{"nosuchcommand-env-test"}, 127, "nosuchcommand-env-test: command not found"},
+	}
+
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		status, err := builtinEnv(test.args, strings.NewReader(""), &stdout, &stderr)
+		if status != test.status {
+			t.Errorf("env %v: status = %d, expected %d", test.args, status, test.status)
+		}
+		message := ""
+		if err != nil {
+			message = err.Error()
+		}
+		if message != test.message {
+			t.Errorf("env %v: error = %q, expected %q", test.args, message, test.message)
+		}
+	}
+}
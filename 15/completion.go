@@ -0,0 +1,146 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Символы, отделяющие слова при автодополнении
+const completionWordBreaks = " \t|&;<>()"
+
+// Находит начало дополняемого слова и варианты его замены
+func completeLine(line []rune, cursor int) (wordStart int, candidates []string) {
+	wordStart = cursor
+	for wordStart > 0 && !strings.ContainsRune(completionWordBreaks, line[wordStart-1]) {
+		wordStart--
+	}
+	word := string(line[wordStart:cursor])
+
+	// Первое слово команды дополняем именами команд или путями к исполняемым файлам
+	if isCommandPosition(line[:wordStart]) {
+		if strings.ContainsRune(word, '/') || strings.HasPrefix(word, "~") {
+			return wordStart, completeFilePath(word, true)
+		}
+		return wordStart, completeCommandName(word)
+	}
+	return wordStart, completeFilePath(word, false)
+}
+
+// Проверяет, стоит ли слово на месте имени команды
+func isCommandPosition(before []rune) bool {
+	text := strings.TrimRight(string(before), " \t")
+	if text == "" {
+		return true
+	}
+	last := text[len(text)-1]
+	return last == '|' || last == '&' || last == ';' || last == '('
+}
+
+// Дополняет имя команды встроенными командами и исполняемыми файлами из $PATH
+func completeCommandName(prefix string) []string {
+	seen := make(map[string]bool)
+	var candidates []string
+	add := func(name string) {
+		if strings.HasPrefix(name, prefix) && !seen[name] {
+			seen[name] = true
+			candidates = append(candidates, name)
+		}
+	}
+
+	for _, name := range builtinCommands {
+		add(name)
+	}
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			dir = "."
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !strings.HasPrefix(entry.Name(), prefix) || seen[entry.Name()] {
+				continue
+			}
+			info, err := os.Stat(filepath.Join(dir, entry.Name()))
+			if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+				continue
+			}
+			add(entry.Name())
+		}
+	}
+
+	sort.Strings(candidates)
+	return candidates
+}
+
+// Дополняет путь к файлу; каталоги получают завершающий '/'
+func completeFilePath(word string, executablesOnly bool) []string {
+	dirPart, filePart := "", word
+	if index := strings.LastIndex(word, "/"); index >= 0 {
+		dirPart, filePart = word[:index+1], word[index+1:]
+	}
+
+	// Каталог для чтения с учетом домашней директории
+	searchDir := dirPart
+	if strings.HasPrefix(searchDir, "~") {
+		if homeDir, err := os.UserHomeDir(); err == nil && (searchDir == "~" || strings.HasPrefix(searchDir, "~/")) {
+			searchDir = homeDir + searchDir[1:]
+		}
+	}
+	if searchDir == "" {
+		searchDir = "."
+	}
+
+	entries, err := os.ReadDir(searchDir)
+	if err != nil {
+		return nil
+	}
+
+	var candidates []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, filePart) {
+			continue
+		}
+		// Скрытые файлы показываем, только если префикс начинается с точки
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(filePart, ".") {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(searchDir, name))
+		if err != nil {
+			continue
+		}
+		if info.IsDir() {
+			candidates = append(candidates, dirPart+name+"/")
+			continue
+		}
+		if executablesOnly && info.Mode()&0111 == 0 {
+			continue
+		}
+		candidates = append(candidates, dirPart+name)
+	}
+
+	sort.Strings(candidates)
+	return candidates
+}
+
+// Возвращает общий префикс всех строк
+func commonPrefix(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	prefix := []rune(values[0])
+	for _, value := range values[1:] {
+		runes := []rune(value)
+		length := 0
+		for length < len(prefix) && length < len(runes) && prefix[length] == runes[length] {
+			length++
+		}
+		prefix = prefix[:length]
+	}
+	return string(prefix)
+}
@@ -0,0 +1,549 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// Фрагмент раскрытого слова.
+// quoted - фрагмент получен из кавычек или экранирования и не подлежит
+// разбиению на поля и раскрытию шаблонов; split - фрагмент является
+// результатом подстановки вне кавычек и разбивается по $IFS.
+type expandedPart struct {
+	text   string
+	quoted bool
+	split  bool
+}
+
+// Раскрывает слова команды в порядке POSIX: тильда, параметры, подстановка
+// команд и арифметика, затем разбиение на поля, раскрытие шаблонов имен
+// файлов и удаление кавычек.
+func expandWords(words []string) ([]string, error) {
+	var result []string
+	for _, word := range words {
+		parts, err := expandWordParts(word, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, field := range splitFields(parts) {
+			result = append(result, expandPathnames(field)...)
+		}
+	}
+	return result, nil
+}
+
+// Раскрывает одно слово в одну строку без разбиения на поля и шаблонов
+// (имена файлов перенаправлений)
+func expandWord(word string) (string, error) {
+	parts, err := expandWordParts(word, false)
+	if err != nil {
+		return "", err
+	}
+	return joinExpandedParts(parts), nil
+}
+
+// Раскрывает значение присваивания NAME=value: тильда раскрывается
+// также после каждого ':'
+func expandAssignmentValue(value string) (string, error) {
+	parts, err := expandWordParts(value, true)
+	if err != nil {
+		return "", err
+	}
+	return joinExpandedParts(parts), nil
+}
+
+// Склеивает фрагменты в строку
+func joinExpandedParts(parts []expandedPart) string {
+	var builder strings.Builder
+	for _, part := range parts {
+		builder.WriteString(part.text)
+	}
+	return builder.String()
+}
+
+// Раскрывает тильду, переменные, подстановки команд, арифметику,
+// экранирование и кавычки в слове
+func expandWordParts(word string, assignment bool) ([]expandedPart, error) {
+	var parts []expandedPart
+	var literal strings.Builder
+
+	flushLiteral := func() {
+		if literal.Len() > 0 {
+			parts = append(parts, expandedPart{text: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(word); {
+		char := word[i]
+
+		if char == '~' && (i == 0 || (assignment && word[i-1] == ':')) {
+			if home, next, ok := expandTilde(word, i, assignment); ok {
+				flushLiteral()
+				parts = append(parts, expandedPart{text: home, quoted: true})
+				i = next
+				continue
+			}
+		}
+
+		switch char {
+		case '\\':
+			if i+1 < len(word) {
+				flushLiteral()
+				parts = append(parts, expandedPart{text: word[i+1 : i+2], quoted: true})
+				i += 2
+			} else {
+				literal.WriteByte(char)
+				i++
+			}
+
+		case '\'':
+			end := strings.IndexByte(word[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("syntax error: unterminated quoted string")
+			}
+			flushLiteral()
+			parts = append(parts, expandedPart{text: word[i+1 : i+1+end], quoted: true})
+			i += end + 2
+
+		case '"':
+			flushLiteral()
+			text, next, err := expandDoubleQuoted(word, i+1)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, expandedPart{text: text, quoted: true})
+			i = next
+
+		case '$', '`':
+			value, next, expanded, err := expandSubstitution(word, i)
+			if err != nil {
+				return nil, err
+			}
+			if !expanded {
+				literal.WriteString(value)
+			} else {
+				flushLiteral()
+				parts = append(parts, expandedPart{text: value, split: true})
+			}
+			i = next
+
+		default:
+			literal.WriteByte(char)
+			i++
+		}
+	}
+
+	flushLiteral()
+	return parts, nil
+}
+
+// Раскрывает ~, ~user, ~+ и ~- в позиции start.
+// Возвращает домашний каталог, позицию после префикса и признак успеха.
+func expandTilde(word string, start int, assignment bool) (string, int, bool) {
+	end := start + 1
+	for end < len(word) && word[end] != '/' && !(assignment && word[end] == ':') {
+		// Префикс с кавычками или подстановками не раскрывается
+		if strings.IndexByte("\\'\"$`", word[end]) >= 0 {
+			return "", 0, false
+		}
+		end++
+	}
+
+	name := word[start+1 : end]
+	switch name {
+	case "":
+		if home, ok := lookupVariable("HOME"); ok {
+			return home, end, true
+		}
+		if home, err := os.UserHomeDir(); err == nil {
+			return home, end, true
+		}
+	case "+":
+		if currentDir, err := os.Getwd(); err == nil {
+			return currentDir, end, true
+		}
+	case "-":
+		if previousDir, ok := lookupVariable("OLDPWD"); ok {
+			return previousDir, end, true
+		}
+	default:
+		if account, err := user.Lookup(name); err == nil {
+			return account.HomeDir, end, true
+		}
+	}
+	return "", 0, false
+}
+
+// Раскрывает содержимое двойных кавычек, начиная с позиции start.
+// Возвращает текст и позицию после закрывающей кавычки.
+func expandDoubleQuoted(word string, start int) (string, int, error) {
+	var builder strings.Builder
+	for i := start; i < len(word); {
+		char := word[i]
+		switch {
+		case char == '"':
+			return builder.String(), i + 1, nil
+		case char == '\\' && i+1 < len(word) && strings.IndexByte("$`\"\\\n", word[i+1]) >= 0:
+			builder.WriteByte(word[i+1])
+			i += 2
+		case char == '$' || char == '`':
+			value, next, _, err := expandSubstitution(word, i)
+			if err != nil {
+				return "", 0, err
+			}
+			builder.WriteString(value)
+			i = next
+		default:
+			builder.WriteByte(char)
+			i++
+		}
+	}
+	return "", 0, errors.New("syntax error: unterminated quoted string")
+}
+
+// Раскрывает подстановку, начинающуюся с '$' или '`' в позиции start:
+// $NAME, ${...}, $(cmd), `cmd` и $((expr)).
+// Возвращает значение, позицию после подстановки и признак того,
+// что подстановка действительно произошла (одиночный '$' остается литералом).
+func expandSubstitution(word string, start int) (string, int, bool, error) {
+	if word[start] == '`' {
+		end, err := findClosingBacktick(word, start)
+		if err != nil {
+			return "", 0, false, err
+		}
+		command := unescapeBackquoted(word[start+1 : end])
+		output, err := commandSubstitution(command)
+		return output, end + 1, true, err
+	}
+
+	i := start + 1
+	if i >= len(word) {
+		return "$", i, false, nil
+	}
+
+	switch word[i] {
+	case '{':
+		end, err := findClosingBrace(word, i)
+		if err != nil {
+			return "", 0, false, err
+		}
+		value, err := expandBraceParameter(word[i+1 : end])
+		return value, end + 1, true, err
+
+	case '(':
+		end, err := findClosingParen(word, i)
+		if err != nil {
+			return "", 0, false, err
+		}
+		// $((expr)) - арифметика, если выражение целиком заключено во вторые скобки
+		if i+1 < end && word[i+1] == '(' && word[end-1] == ')' {
+			if inner, err := findClosingParen(word, i+1); err == nil && inner == end-1 {
+				value, err := expandArithmetic(word[i+2 : end-1])
+				return value, end + 1, true, err
+			}
+		}
+		output, err := commandSubstitution(word[i+1 : end])
+		return output, end + 1, true, err
+	}
+
+	// Специальные параметры: код возврата, PID оболочки и последнего фонового процесса
+	if value, ok := specialParameter(word[i]); ok {
+		return value, i + 1, true, nil
+	}
+
+	nameEnd := i
+	for nameEnd < len(word) && isNameChar(word[nameEnd], nameEnd == i) {
+		nameEnd++
+	}
+	if nameEnd == i {
+		// Одиночный '$' остается как есть
+		return "$", i, false, nil
+	}
+	value, _ := lookupParameter(word[i:nameEnd])
+	return value, nameEnd, true, nil
+}
+
+// Убирает экранирование \$, \` и \\ внутри обратных кавычек
+func unescapeBackquoted(command string) string {
+	var builder strings.Builder
+	for i := 0; i < len(command); i++ {
+		if command[i] == '\\' && i+1 < len(command) && strings.IndexByte("$`\\", command[i+1]) >= 0 {
+			i++
+		}
+		builder.WriteByte(command[i])
+	}
+	return builder.String()
+}
+
+// Раскрывает выражение $((...)): сначала подстановки, затем вычисление
+func expandArithmetic(expression string) (string, error) {
+	expanded, err := expandDoubleQuotedText(expression)
+	if err != nil {
+		return "", err
+	}
+	value, err := evaluateArithmetic(expanded)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(value, 10), nil
+}
+
+// Раскрывает текст так, как если бы он был заключен в двойные кавычки
+func expandDoubleQuotedText(text string) (string, error) {
+	value, _, err := expandDoubleQuoted(text+`"`, 0)
+	return value, err
+}
+
+// Выполняет подстановку команды: команда выполняется в подоболочке,
+// ее стандартный вывод возвращается без завершающих переводов строки
+func commandSubstitution(command string) (string, error) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return "", err
+	}
+
+	outputChannel := make(chan string, 1)
+	go func() {
+		output, _ := io.ReadAll(reader)
+		reader.Close()
+		outputChannel <- string(output)
+	}()
+
+	savedStdout := os.Stdout
+	os.Stdout = writer
+	runInSubshell(func() {
+		processCommandLine(command)
+	})
+	substitutionStatus = lastExitStatus
+	os.Stdout = savedStdout
+	writer.Close()
+
+	return strings.TrimRight(<-outputChannel, "\n"), nil
+}
+
+// Выполняет функцию в подоболочке: изменения каталога, переменных
+// и псевдонимов не влияют на текущую оболочку
+func runInSubshell(run func()) {
+	savedOptions := make(map[string]bool, len(shellOptions))
+	for name, value := range shellOptions {
+		savedOptions[name] = value
+	}
+	savedDir, dirErr := os.Getwd()
+	savedEnvironment := os.Environ()
+	savedVariables := make(map[string]string, len(shellVariables))
+	for name, value := range shellVariables {
+		savedVariables[name] = value
+	}
+	savedAliases := make(map[string]string, len(shellAliases))
+	for name, value := range shellAliases {
+		savedAliases[name] = value
+	}
+
+	defer func() {
+		if dirErr == nil {
+			_ = os.Chdir(savedDir)
+		}
+		os.Clearenv()
+		for _, entry := range savedEnvironment {
+			if name, value, ok := strings.Cut(entry, "="); ok {
+				os.Setenv(name, value)
+			}
+		}
+		shellVariables = savedVariables
+		shellAliases = savedAliases
+		shellOptions = savedOptions
+		subshellDepth--
+
+		// exit и set -e внутри подоболочки завершают только ее
+		if recovered := recover(); recovered != nil {
+			exit, ok := recovered.(subshellExit)
+			if !ok {
+				panic(recovered)
+			}
+			lastExitStatus = exit.code
+		}
+	}()
+	subshellDepth++
+	run()
+}
+
+// Возвращает значение специального параметра $?, $$ или $!
+func specialParameter(name byte) (string, bool) {
+	switch name {
+	case '?':
+		return strconv.Itoa(lastExitStatus), true
+	case '$':
+		return strconv.Itoa(os.Getpid()), true
+	case '!':
+		jobsMutex.Lock()
+		defer jobsMutex.Unlock()
+		if lastBackgroundPID == 0 {
+			return "", true
+		}
+		return strconv.Itoa(lastBackgroundPID), true
+	}
+	return "", false
+}
+
+// Возвращает значение параметра: переменной или массива PIPESTATUS.
+// Поддерживаются формы NAME, NAME[N], NAME[@] и NAME[*].
+func lookupParameter(parameter string) (string, bool) {
+	name, index, isElement := strings.Cut(parameter, "[")
+	if name != "PIPESTATUS" {
+		if isElement {
+			return "", false
+		}
+		return lookupVariable(name)
+	}
+
+	elements := make([]string, len(pipeStatus))
+	for i, status := range pipeStatus {
+		elements[i] = strconv.Itoa(status)
+	}
+	if !isElement {
+		index = "0]"
+	}
+	index = strings.TrimSuffix(index, "]")
+	if index == "@" || index == "*" {
+		return strings.Join(elements, " "), len(elements) > 0
+	}
+	position, err := strconv.Atoi(index)
+	if err != nil || position < 0 || position >= len(elements) {
+		return "", false
+	}
+	return elements[position], true
+}
+
+// Проверяет, может ли символ входить в имя переменной
+func isNameChar(char byte, first bool) bool {
+	if char == '_' || (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') {
+		return true
+	}
+	return !first && char >= '0' && char <= '9'
+}
+
+// Раскрывает ${NAME}, ${#NAME}, ${NAME[N]}, ${NAME:-word}, ${NAME:=word}, ${NAME:+word} и ${NAME:?word}
+func expandBraceParameter(expression string) (string, error) {
+	if len(expression) == 1 {
+		if value, ok := specialParameter(expression[0]); ok {
+			return value, nil
+		}
+	}
+	if expression == "#PIPESTATUS[@]" || expression == "#PIPESTATUS[*]" {
+		return strconv.Itoa(len(pipeStatus)), nil
+	}
+	if strings.HasPrefix(expression, "#") && isValidVariableName(expression[1:]) {
+		value, _ := lookupVariable(expression[1:])
+		return strconv.Itoa(len([]rune(value))), nil
+	}
+
+	nameEnd := 0
+	for nameEnd < len(expression) && isNameChar(expression[nameEnd], nameEnd == 0) {
+		nameEnd++
+	}
+	// Индекс элемента массива: NAME[N], NAME[@]
+	if nameEnd < len(expression) && expression[nameEnd] == '[' {
+		if closing := strings.IndexByte(expression[nameEnd:], ']'); closing > 0 {
+			nameEnd += closing + 1
+		}
+	}
+	name := expression[:nameEnd]
+	if name == "" {
+		return "", errors.New("${" + expression + "}: bad substitution")
+	}
+
+	value, isSet := lookupParameter(name)
+	operator := expression[nameEnd:]
+	if operator == "" {
+		return value, nil
+	}
+	if len(operator) < 2 || operator[0] != ':' {
+		return "", errors.New("${" + expression + "}: bad substitution")
+	}
+
+	argument, err := expandWord(operator[2:])
+	if err != nil {
+		return "", err
+	}
+	empty := !isSet || value == ""
+
+	switch operator[1] {
+	case '-':
+		if empty {
+			return argument, nil
+		}
+	case '=':
+		if empty {
+			setVariable(name, argument)
+			return argument, nil
+		}
+	case '+':
+		if empty {
+			return "", nil
+		}
+		return argument, nil
+	case '?':
+		if empty {
+			if argument == "" {
+				argument = "parameter null or not set"
+			}
+			return "", errors.New(name + ": " + argument)
+		}
+	default:
+		return "", errors.New("${" + expression + "}: bad substitution")
+	}
+	return value, nil
+}
+
+// Разбивает раскрытое слово на поля по символам $IFS.
+// Разбиваются только результаты подстановок вне кавычек; поле без кавычек,
+// оказавшееся пустым, отбрасывается.
+func splitFields(parts []expandedPart) [][]expandedPart {
+	separators, ok := lookupVariable("IFS")
+	if !ok {
+		separators = " \t\n"
+	}
+
+	var fields [][]expandedPart
+	var current []expandedPart
+	hasContent := false
+
+	finishField := func() {
+		if hasContent {
+			fields = append(fields, current)
+		}
+		current = nil
+		hasContent = false
+	}
+
+	for _, part := range parts {
+		if !part.split || separators == "" {
+			current = append(current, part)
+			hasContent = hasContent || part.quoted || part.text != ""
+			continue
+		}
+
+		text := part.text
+		for text != "" {
+			index := strings.IndexAny(text, separators)
+			if index < 0 {
+				current = append(current, expandedPart{text: text})
+				hasContent = true
+				break
+			}
+			if index > 0 {
+				current = append(current, expandedPart{text: text[:index]})
+				hasContent = true
+			}
+			finishField()
+			text = strings.TrimLeft(text[index:], separators)
+		}
+	}
+	finishField()
+	return fields
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandWords(t *testing.T) {
+	t.Setenv("HOME", "/home/tester")
+	setVariable("expandList", "a  b\tc")
+	setVariable("expandEmpty", "")
+	t.Cleanup(func() {
+		unsetVariable("expandList")
+		unsetVariable("expandEmpty")
+	})
+
+	tests := []struct {
+		words    []string
+		expected []string
+	}{
+		{[]string{"plain"}, []string{"plain"}},
+		{[]string{"$expandList"}, []string{"a", "b", "c"}},
+		{[]string{`"$expandList"`}, []string{"a  b\tc"}},
+		{[]string{"x${expandList}y"}, []string{"xa", "b", "cy"}},
+		{[]string{"$expandEmpty", "b"}, []string{"b"}},
+		{[]string{`"$expandEmpty"`}, []string{""}},
+		{[]string{"''"}, []string{""}},
+		{[]string{`'$expandList'`}, []string{"$expandList"}},
+		{[]string{`a\ b`}, []string{"a b"}},
+		{[]string{"~/bin", "~+x", "a~"}, []string{"/home/tester/bin", "~+x", "a~"}},
+		{[]string{"${expandUnset:-default value}"}, []string{"default", "value"}},
+		{[]string{"${#expandList}"}, []string{"6"}},
+		{[]string{"$((2 + 3))"}, []string{"5"}},
+		{[]string{"$(echo one two)"}, []string{"one", "two"}},
+		{[]string{`"$(echo one two)"`}, []string{"one two"}},
+		{[]string{"`echo x`y"}, []string{"xy"}},
+		{[]string{"$"}, []string{"$"}},
+	}
+
+	for _, test := range tests {
+		result, err := expandWords(test.words)
+		if err != nil {
+			t.Errorf("expandWords(%q): %v", test.words, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("expandWords(%q) = %q, expected %q", test.words, result, test.expected)
+		}
+	}
+}
+
+func TestSplitFields(t *testing.T) {
+	tests := []struct {
+		ifs      string
+		parts    []expandedPart
+		expected []string
+	}{
+		{" ", []expandedPart{{text: " a  b ", split: true}}, []string{"a", "b"}},
+		{" ", []expandedPart{{text: "x"}, {text: "a b", split: true}, {text: "y"}}, []string{"xa", "by"}},
+		{" ", []expandedPart{{text: "a b", quoted: true}}, []string{"a b"}},
+		{" ", []expandedPart{{text: "", quoted: true}}, []string{""}},
+		{" ", []expandedPart{{text: "  ", split: true}}, nil},
+		{":", []expandedPart{{text: "a:b c", split: true}}, []string{"a", "b c"}},
+		{"", []expandedPart{{text: "a b", split: true}}, []string{"a b"}},
+	}
+	t.Cleanup(func() { unsetVariable("IFS") })
+
+	for _, test := range tests {
+		setVariable("IFS", test.ifs)
+		var fields []string
+		for _, field := range splitFields(test.parts) {
+			fields = append(fields, joinExpandedParts(field))
+		}
+		if !reflect.DeepEqual(fields, test.expected) {
+			t.Errorf("IFS=%q splitFields(%v) = %q, expected %q", test.ifs, test.parts, fields, test.expected)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Раскрывает шаблон имен файлов (*, ?, [...], **) в поле.
+// Если совпадений нет, поле возвращается как есть без кавычек.
+func expandPathnames(field []expandedPart) []string {
+	var pattern, literal strings.Builder
+	hasMeta := false
+	for _, part := range field {
+		literal.WriteString(part.text)
+		if part.quoted {
+			// Символы в кавычках экранируются и сопоставляются буквально
+			for _, char := range part.text {
+				if strings.ContainsRune(`*?[\`, char) {
+					pattern.WriteByte('\\')
+				}
+				pattern.WriteRune(char)
+			}
+			continue
+		}
+		pattern.WriteString(part.text)
+		hasMeta = hasMeta || strings.ContainsAny(part.text, "*?[")
+	}
+
+	if !hasMeta {
+		return []string{literal.String()}
+	}
+	matches := globPattern(pattern.String())
+	if len(matches) == 0 {
+		return []string{literal.String()}
+	}
+	return matches
+}
+
+// Возвращает отсортированный список путей, соответствующих шаблону.
+// Компонент ** соответствует любому числу вложенных каталогов.
+func globPattern(pattern string) []string {
+	components := strings.Split(pattern, "/")
+	prefix := ""
+	if strings.HasPrefix(pattern, "/") {
+		prefix = "/"
+		components = components[1:]
+	}
+
+	seen := make(map[string]bool)
+	var matches []string
+	globComponents(prefix, components, func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			matches = append(matches, path)
+		}
+	})
+	sort.Strings(matches)
+	return matches
+}
+
+// Рекурсивно сопоставляет оставшиеся компоненты шаблона с содержимым каталога prefix
+func globComponents(prefix string, components []string, add func(string)) {
+	if len(components) == 0 {
+		add(prefix)
+		return
+	}
+
+	component, rest := components[0], components[1:]
+	directory := prefix
+	if directory == "" {
+		directory = "."
+	}
+
+	// Пустой компонент (двойной или завершающий '/') не меняет каталог
+	if component == "" {
+		if len(rest) == 0 {
+			add(prefix)
+			return
+		}
+		globComponents(prefix, rest, add)
+		return
+	}
+
+	if component == "**" {
+		// Ноль каталогов, затем рекурсивно каждый видимый подкаталог;
+		// завершающий ** перечисляет все файлы и каталоги дерева.
+		// Как globstar в bash, ** не заходит в символические ссылки на каталоги:
+		// иначе ссылка вроде d/up -> .. дает бесконечные совпадения
+		if len(rest) > 0 {
+			globComponents(prefix, rest, add)
+		}
+		entries, err := os.ReadDir(directory)
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			if len(rest) == 0 {
+				add(prefix + entry.Name())
+			}
+			if entry.IsDir() {
+				globComponents(prefix+entry.Name()+"/", components, add)
+			}
+		}
+		return
+	}
+
+	if !strings.ContainsAny(component, "*?[") {
+		name := unescapeGlob(component)
+		path := filepath.Join(directory, name)
+		if _, err := os.Lstat(path); err != nil {
+			return
+		}
+		if len(rest) == 0 {
+			add(prefix + name)
+		} else if isDirectory(path) {
+			globComponents(prefix+name+"/", rest, add)
+		}
+		return
+	}
+
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return
+	}
+	pattern := translateBracketNegation(component)
+	for _, entry := range entries {
+		name := entry.Name()
+		// Скрытые файлы сопоставляются, только если шаблон начинается с точки
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(component, ".") {
+			continue
+		}
+		if matched, err := filepath.Match(pattern, name); err != nil || !matched {
+			continue
+		}
+		if len(rest) == 0 {
+			add(prefix + name)
+		} else if isDirectory(filepath.Join(directory, name)) {
+			globComponents(prefix+name+"/", rest, add)
+		}
+	}
+}
+
+// Проверяет, является ли путь каталогом (с переходом по символическим ссылкам)
+func isDirectory(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// Убирает экранирование из компонента шаблона
+func unescapeGlob(component string) string {
+	var builder strings.Builder
+	for i := 0; i < len(component); i++ {
+		if component[i] == '\\' && i+1 < len(component) {
+			i++
+		}
+		builder.WriteByte(component[i])
+	}
+	return builder.String()
+}
+
+// Заменяет отрицание '!' в начале скобочного выражения на '^',
+// которое понимает filepath.Match
+func translateBracketNegation(component string) string {
+	var builder strings.Builder
+	inBracket := false
+	for i := 0; i < len(component); i++ {
+		switch c := component[i]; {
+		case c == '\\' && i+1 < len(component):
+			builder.WriteByte(c)
+			i++
+			builder.WriteByte(component[i])
+			continue
+		case c == '[' && !inBracket:
+			inBracket = true
+			builder.WriteByte(c)
+			if i+1 < len(component) && component[i+1] == '!' {
+				builder.WriteByte('^')
+				i++
+			}
+			continue
+		case c == ']':
+			inBracket = false
+		}
+		builder.WriteByte(component[i])
+	}
+	return builder.String()
+}
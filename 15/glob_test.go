@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGlobPattern(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.go", ".hidden.txt", "d/e.txt", "d/f/g.txt", "[x].txt", "abc", "bbc", "cbc"} {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Ссылка на родительский каталог: ** не должен заходить в нее и зацикливаться
+	if err := os.Symlink("..", filepath.Join(root, "d", "up")); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(root)
+
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"*.txt", []string{"[x].txt", "a.txt", "b.txt"}},
+		{"?.go", []string{"c.go"}},
+		{"[ab].txt", []string{"a.txt", "b.txt"}},
+		{`\[x\].txt`, []string{"[x].txt"}},
+		{"[!a]bc", []string{"bbc", "cbc"}},
+		{"[^a]bc", []string{"bbc", "cbc"}},
+		{`[\!a]bc`, []string{"abc"}},
+		{".*.txt", []string{".hidden.txt"}},
+		{"*/e.txt", []string{"d/e.txt"}},
+		{"d/*", []string{"d/e.txt", "d/f", "d/up"}},
+		{"**/*.txt", []string{"[x].txt", "a.txt", "b.txt", "d/e.txt", "d/f/g.txt"}},
+		{"d/**", []string{"d/e.txt", "d/f", "d/f/g.txt", "d/up"}},
+		{"d/up/*.go", []string{"d/up/c.go"}},
+		{"*.none", nil},
+		{root + "/d/f/*", []string{root + "/d/f/g.txt"}},
+	}
+
+	for _, test := range tests {
+		if matches := globPattern(test.pattern); !reflect.DeepEqual(matches, test.expected) {
+			t.Errorf("globPattern(%q) = %q, expected %q", test.pattern, matches, test.expected)
+		}
+	}
+}
+
+func TestExpandPathnamesQuoted(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "a.txt"), nil, 0644)
+	t.Chdir(root)
+
+	tests := []struct {
+		field    []expandedPart
+		expected []string
+	}{
+		{[]expandedPart{{text: "*.txt"}}, []string{"a.txt"}},
+		{[]expandedPart{{text: "*.txt", quoted: true}}, []string{"*.txt"}},
+		{[]expandedPart{{text: "*"}, {text: ".txt", quoted: true}}, []string{"a.txt"}},
+		{[]expandedPart{{text: "*.md"}}, []string{"*.md"}},
+	}
+
+	for _, test := range tests {
+		if result := expandPathnames(test.field); !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("expandPathnames(%v) = %q, expected %q", test.field, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Максимальное количество команд, хранимых в истории
+const maxHistorySize = 1000
+
+// История введенных команд с сохранением в файл
+type commandHistory struct {
+	mu       sync.Mutex
+	entries  []string
+	filename string
+}
+
+// Глобальная история команд (используется встроенной командой history)
+var shellHistory = &commandHistory{}
+
+// Возвращает путь к файлу истории ($HISTFILE или ~/.minishell_history)
+func defaultHistoryFile() string {
+	if filename := os.Getenv("HISTFILE"); filename != "" {
+		return filename
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".minishell_history")
+}
+
+// Загружает историю из файла и запоминает его для последующей записи
+func (h *commandHistory) Load(filename string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.filename = filename
+	if filename == "" {
+		return nil
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// Обрезаем слишком длинную историю и перезаписываем файл
+	if len(h.entries) > maxHistorySize {
+		h.entries = append([]string(nil), h.entries[len(h.entries)-maxHistorySize:]...)
+		return h.rewriteLocked()
+	}
+	return nil
+}
+
+// Добавляет команду в историю и дописывает ее в файл
+func (h *commandHistory) Add(line string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Пропускаем пустые строки и повторы подряд
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if len(h.entries) > 0 && h.entries[len(h.entries)-1] == line {
+		return
+	}
+
+	h.entries = append(h.entries, line)
+	if len(h.entries) > maxHistorySize {
+		h.entries = h.entries[len(h.entries)-maxHistorySize:]
+	}
+
+	if h.filename == "" {
+		return
+	}
+	file, err := os.OpenFile(h.filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	fmt.Fprintln(file, line)
+}
+
+// Очищает историю в памяти и в файле
+func (h *commandHistory) Clear() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries = nil
+	return h.rewriteLocked()
+}
+
+// Перезаписывает файл истории текущим содержимым (вызывается под мьютексом)
+func (h *commandHistory) rewriteLocked() error {
+	if h.filename == "" {
+		return nil
+	}
+	content := ""
+	if len(h.entries) > 0 {
+		content = strings.Join(h.entries, "\n") + "\n"
+	}
+	return os.WriteFile(h.filename, []byte(content), 0600)
+}
+
+// Возвращает копию всех записей истории
+func (h *commandHistory) Entries() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.entries...)
+}
+
+// Выводит последние count записей истории с номерами (count <= 0 - все записи)
+func (h *commandHistory) Print(output io.Writer, count int) {
+	entries := h.Entries()
+	start := 0
+	if count > 0 && count < len(entries) {
+		start = len(entries) - count
+	}
+	for i := start; i < len(entries); i++ {
+		fmt.Fprintf(output, "%5d  %s\n", i+1, entries[i])
+	}
+}
+
+// Раскрывает ссылки на историю: !!, !N, !-N и !prefix
+func (h *commandHistory) Expand(line string) (string, error) {
+	if !strings.Contains(line, "!") {
+		return line, nil
+	}
+	entries := h.Entries()
+
+	var result strings.Builder
+	inSingleQuotes := false
+	for i := 0; i < len(line); i++ {
+		char := line[i]
+		if char == '\'' {
+			inSingleQuotes = !inSingleQuotes
+		}
+		if char == '\\' && i+1 < len(line) && line[i+1] == '!' {
+			result.WriteByte('!')
+			i++
+			continue
+		}
+		if char != '!' || inSingleQuotes || i+1 >= len(line) {
+			result.WriteByte(char)
+			continue
+		}
+
+		next := line[i+1]
+		// '!' перед пробелом, '=', '(' или ';', а также $! и [!...] оставляем как есть
+		if strings.IndexByte(" \t=(;|&<>)", next) >= 0 || (i > 0 && (line[i-1] == '$' || line[i-1] == '[')) {
+			result.WriteByte(char)
+			continue
+		}
+
+		end := i + 1
+		var event string
+		switch {
+		case next == '!':
+			end = i + 2
+			event = "!!"
+		case next == '-' || (next >= '0' && next <= '9'):
+			end = i + 2
+			for end < len(line) && line[end] >= '0' && line[end] <= '9' {
+				end++
+			}
+			event = line[i:end]
+		default:
+			for end < len(line) && !strings.ContainsRune(" \t;|&<>()", rune(line[end])) {
+				end++
+			}
+			event = line[i:end]
+		}
+
+		replacement, err := lookupHistoryEvent(entries, event)
+		if err != nil {
+			return "", err
+		}
+		result.WriteString(replacement)
+		i = end - 1
+	}
+	return result.String(), nil
+}
+
+// Находит команду в истории по ссылке вида !!, !N, !-N или !prefix
+func lookupHistoryEvent(entries []string, event string) (string, error) {
+	notFound := fmt.Errorf("%s: event not found", event)
+	reference := event[1:]
+
+	if reference == "!" {
+		if len(entries) == 0 {
+			return "", notFound
+		}
+		return entries[len(entries)-1], nil
+	}
+
+	if number, err := strconv.Atoi(reference); err == nil {
+		index := number - 1
+		if number < 0 {
+			index = len(entries) + number
+		}
+		if number == 0 || index < 0 || index >= len(entries) {
+			return "", notFound
+		}
+		return entries[index], nil
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		if strings.HasPrefix(entries[i], reference) {
+			return entries[i], nil
+		}
+	}
+	return "", notFound
+}
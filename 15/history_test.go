@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHistoryExpand(t *testing.T) {
+	history := &commandHistory{entries: []string{"ls -l", "echo hello", "git status", "echo world"}}
+
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{"!!", "echo world"},
+		{"sudo !!", "sudo echo world"},
+		{"!1", "ls -l"},
+		{"!-2", "git status"},
+		{"!git; !ls", "git status; ls -l"},
+		{"!ec", "echo world"},
+		{"echo hi!", "echo hi!"},
+		{"echo ! x", "echo ! x"},
+		{`echo \!!`, "echo !!"},
+		{"echo '!!'", "echo '!!'"},
+		{"echo $! [!a]", "echo $! [!a]"},
+		{"no bang", "no bang"},
+	}
+
+	for _, test := range tests {
+		expanded, err := history.Expand(test.line)
+		if err != nil {
+			t.Errorf("Expand(%q): %v", test.line, err)
+			continue
+		}
+		if expanded != test.expected {
+			t.Errorf("Expand(%q) = %q, expected %q", test.line, expanded, test.expected)
+		}
+	}
+}
+
+func TestLookupHistoryEvent(t *testing.T) {
+	entries := []string{"ls -l", "echo hello"}
+
+	tests := []struct {
+		event    string
+		expected string
+		found    bool
+	}{
+		{"!!", "echo hello", true},
+		{"!1", "ls -l", true},
+		{"!2", "echo hello", true},
+		{"!-1", "echo hello", true},
+		{"!-2", "ls -l", true},
+		{"!ls", "ls -l", true},
+		{"!0", "", false},
+		{"!3", "", false},
+		{"!-3", "", false},
+		{"!cat", "", false},
+	}
+
+	for _, test := range tests {
+		command, err := lookupHistoryEvent(entries, test.event)
+		if (err == nil) != test.found || command != test.expected {
+			t.Errorf("lookupHistoryEvent(%q) = %q, %v, expected %q, found %v", test.event, command, err, test.expected, test.found)
+		}
+		if err != nil && !strings.Contains(err.Error(), "event not found") {
+			t.Errorf("lookupHistoryEvent(%q) error = %v", test.event, err)
+		}
+	}
+
+	if _, err := lookupHistoryEvent(nil, "!!"); err == nil {
+		t.Error("lookupHistoryEvent(!!) on empty history succeeded")
+	}
+}
+
+func TestHistoryAddAndLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "history")
+	history := &commandHistory{}
+	if err := history.Load(filename); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"ls", "ls", "  ", "pwd", "ls"} {
+		history.Add(line)
+	}
+
+	expected := []string{"ls", "pwd", "ls"}
+	if entries := history.Entries(); !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Entries() = %q, expected %q", entries, expected)
+	}
+
+	reloaded := &commandHistory{}
+	if err := reloaded.Load(filename); err != nil {
+		t.Fatal(err)
+	}
+	if entries := reloaded.Entries(); !reflect.DeepEqual(entries, expected) {
+		t.Errorf("reloaded Entries() = %q, expected %q", entries, expected)
+	}
+
+	if err := reloaded.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(filename); len(content) != 0 || len(reloaded.Entries()) != 0 {
+		t.Errorf("history not cleared: %q", content)
+	}
+}
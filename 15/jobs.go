@@ -0,0 +1,249 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Фоновое задание оболочки (пайплайн, запущенный с '&')
+type backgroundJob struct {
+	id       int
+	command  string
+	commands []*exec.Cmd
+	pgid     int
+	done     chan struct{}
+	status   int
+}
+
+// Таблица фоновых заданий
+var (
+	jobsMutex         sync.Mutex
+	backgroundJobs    []*backgroundJob
+	lastBackgroundPID int // PID последнего процесса последнего фонового задания ($!)
+)
+
+// Регистрирует фоновое задание и возвращает его номер
+func addBackgroundJob(command string, commands []*exec.Cmd, pgid int, wait func() int) *backgroundJob {
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+
+	id := 1
+	for _, existing := range backgroundJobs {
+		if existing.id >= id {
+			id = existing.id + 1
+		}
+	}
+	job := &backgroundJob{
+		id:       id,
+		command:  command,
+		commands: commands,
+		pgid:     pgid,
+		done:     make(chan struct{}),
+	}
+	backgroundJobs = append(backgroundJobs, job)
+	if len(commands) > 0 {
+		lastBackgroundPID = commands[len(commands)-1].Process.Pid
+	}
+
+	go func() {
+		status := wait()
+		jobsMutex.Lock()
+		job.status = status
+		jobsMutex.Unlock()
+		close(job.done)
+	}()
+	return job
+}
+
+// Проверяет, завершилось ли задание
+func (job *backgroundJob) finished() bool {
+	select {
+	case <-job.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Возвращает состояние задания для вывода командой jobs
+func (job *backgroundJob) state() string {
+	if !job.finished() {
+		return "Running"
+	}
+	jobsMutex.Lock()
+	status := job.status
+	jobsMutex.Unlock()
+	if status == 0 {
+		return "Done"
+	}
+	return fmt.Sprintf("Exit %d", status)
+}
+
+// Возвращает копию списка заданий
+func listBackgroundJobs() []*backgroundJob {
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+	return append([]*backgroundJob(nil), backgroundJobs...)
+}
+
+// Удаляет задание из таблицы
+func removeBackgroundJob(job *backgroundJob) {
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+	for i, existing := range backgroundJobs {
+		if existing == job {
+			backgroundJobs = append(backgroundJobs[:i], backgroundJobs[i+1:]...)
+			return
+		}
+	}
+}
+
+// Возвращает признак текущего (+) или предыдущего (-) задания
+func jobMarker(jobs []*backgroundJob, index int) string {
+	switch index {
+	case len(jobs) - 1:
+		return "+"
+	case len(jobs) - 2:
+		return "-"
+	}
+	return " "
+}
+
+// Сообщает о завершившихся фоновых заданиях и удаляет их из таблицы
+func reportFinishedJobs(output io.Writer) {
+	jobs := listBackgroundJobs()
+	for index, job := range jobs {
+		if job.finished() {
+			fmt.Fprintf(output, "[%d]%s  %-24s%s\n", job.id, jobMarker(jobs, index), job.state(), job.command)
+			removeBackgroundJob(job)
+		}
+	}
+}
+
+// Находит задание по спецификации %N, %%, %+, %-, %string или %?string
+func findJob(spec string) (*backgroundJob, error) {
+	jobs := listBackgroundJobs()
+	reference := strings.TrimPrefix(spec, "%")
+	notFound := fmt.Errorf("%s: no such job", spec)
+
+	switch {
+	case reference == "" || reference == "%" || reference == "+":
+		if len(jobs) == 0 {
+			return nil, notFound
+		}
+		return jobs[len(jobs)-1], nil
+
+	case reference == "-":
+		if len(jobs) < 2 {
+			return nil, notFound
+		}
+		return jobs[len(jobs)-2], nil
+	}
+
+	if number, err := strconv.Atoi(reference); err == nil {
+		for _, job := range jobs {
+			if job.id == number {
+				return job, nil
+			}
+		}
+		return nil, notFound
+	}
+
+	// %?string - задание, команда которого содержит строку; %string - начинается с нее
+	var matched []*backgroundJob
+	for _, job := range jobs {
+		if (strings.HasPrefix(reference, "?") && strings.Contains(job.command, reference[1:])) ||
+			strings.HasPrefix(job.command, reference) {
+			matched = append(matched, job)
+		}
+	}
+	switch len(matched) {
+	case 0:
+		return nil, notFound
+	case 1:
+		return matched[0], nil
+	}
+	return nil, fmt.Errorf("%s: ambiguous job spec", spec)
+}
+
+// Встроенная команда jobs: список фоновых заданий (-l - с PID процессов)
+func builtinJobs(args []string, stdout io.Writer) (int, error) {
+	showPIDs := len(args) > 0 && args[0] == "-l"
+	jobs := listBackgroundJobs()
+	for index, job := range jobs {
+		fmt.Fprintf(stdout, "[%d]%s  ", job.id, jobMarker(jobs, index))
+		if showPIDs && len(job.commands) > 0 {
+			fmt.Fprintf(stdout, "%d ", job.commands[0].Process.Pid)
+		}
+		fmt.Fprintf(stdout, "%-24s%s\n", job.state(), job.command)
+		if job.finished() {
+			removeBackgroundJob(job)
+		}
+	}
+	return 0, nil
+}
+
+// Встроенная команда wait: ожидание фоновых заданий (всех или указанных)
+func builtinWait(args []string) (int, error) {
+	if len(args) == 0 {
+		for _, job := range listBackgroundJobs() {
+			<-job.done
+			removeBackgroundJob(job)
+		}
+		return 0, nil
+	}
+
+	exitCode := 0
+	var errs []error
+	for _, arg := range args {
+		job, err := resolveWaitTarget(arg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("wait: %w", err))
+			exitCode = 127
+			continue
+		}
+		<-job.done
+		jobsMutex.Lock()
+		exitCode = job.status
+		jobsMutex.Unlock()
+		removeBackgroundJob(job)
+	}
+	return exitCode, errors.Join(errs...)
+}
+
+// Находит задание по спецификации %job или PID одного из его процессов
+func resolveWaitTarget(target string) (*backgroundJob, error) {
+	if strings.HasPrefix(target, "%") {
+		return findJob(target)
+	}
+	pid, err := strconv.Atoi(target)
+	if err != nil {
+		return nil, fmt.Errorf("`%s': not a pid or valid job spec", target)
+	}
+	for _, job := range listBackgroundJobs() {
+		for _, command := range job.commands {
+			if command.Process.Pid == pid {
+				return job, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("pid %d is not a child of this shell", pid)
+}
+
+// Сообщает номер и PID запущенного фонового задания (в интерактивном режиме)
+func announceBackgroundJob(job *backgroundJob) {
+	if !interactiveShell {
+		return
+	}
+	if len(job.commands) > 0 {
+		fmt.Fprintf(os.Stderr, "[%d] %d\n", job.id, job.commands[len(job.commands)-1].Process.Pid)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "[%d]\n", job.id)
+}
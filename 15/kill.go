@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Находит сигнал по имени (TERM, SIGTERM, sigterm) или номеру
+func parseSignal(name string) (syscall.Signal, error) {
+	if number, err := strconv.Atoi(name); err == nil {
+		for _, signal := range supportedSignals {
+			if int(signal) == number {
+				return signal, nil
+			}
+		}
+		if number == 0 {
+			return syscall.Signal(0), nil
+		}
+		return 0, fmt.Errorf("%s: invalid signal specification", name)
+	}
+
+	normalized := strings.TrimPrefix(strings.ToUpper(name), "SIG")
+	for _, signal := range supportedSignals {
+		if signalNames[signal] == normalized {
+			return signal, nil
+		}
+	}
+	return 0, fmt.Errorf("%s: invalid signal specification", name)
+}
+
+// Встроенная команда kill: посылает сигнал процессам, группам (-PGID) и заданиям (%job).
+// kill [-s SIGNAL | -n NUM | -SIGNAL] target...; kill -l [status...]
+func builtinKill(args []string, stdout io.Writer) (int, error) {
+	if len(args) == 0 {
+		return 2, errors.New("kill: usage: kill [-s sigspec | -n signum | -sigspec] pid | %job ... or kill -l [sigspec]")
+	}
+
+	if args[0] == "-l" || args[0] == "-L" {
+		return listSignals(args[1:], stdout)
+	}
+
+	signal := defaultKillSignal
+	switch {
+	case args[0] == "-s" || args[0] == "-n":
+		if len(args) < 2 {
+			return 2, fmt.Errorf("kill: %s: option requires an argument", args[0])
+		}
+		parsed, err := parseSignal(args[1])
+		if err != nil {
+			return 1, fmt.Errorf("kill: %w", err)
+		}
+		signal = parsed
+		args = args[2:]
+	case args[0] == "--":
+		args = args[1:]
+	case strings.HasPrefix(args[0], "-") && len(args[0]) > 1:
+		// -SIGNAL или -NUM; отрицательный PID указывается после --
+		parsed, err := parseSignal(args[0][1:])
+		if err != nil {
+			return 1, fmt.Errorf("kill: %w", err)
+		}
+		signal = parsed
+		args = args[1:]
+		if len(args) > 0 && args[0] == "--" {
+			args = args[1:]
+		}
+	}
+
+	if len(args) == 0 {
+		return 2, errors.New("kill: no process or job specified")
+	}
+
+	exitCode := 0
+	var errs []error
+	for _, target := range args {
+		if err := signalTarget(target, signal); err != nil {
+			errs = append(errs, fmt.Errorf("kill: %w", err))
+			exitCode = 1
+		}
+	}
+	return exitCode, errors.Join(errs...)
+}
+
+// Посылает сигнал одной цели: PID, -PGID или %job
+func signalTarget(target string, signal syscall.Signal) error {
+	if strings.HasPrefix(target, "%") {
+		job, err := findJob(target)
+		if err != nil {
+			return err
+		}
+		if job.finished() {
+			return fmt.Errorf("%s: job has terminated", target)
+		}
+		if job.pgid > 0 {
+			if err := sendSignal(-job.pgid, signal); err == nil {
+				return nil
+			}
+		}
+		// Без группы процессов сигнал посылается каждому процессу задания
+		for _, command := range job.commands {
+			if err := sendSignal(command.Process.Pid, signal); err != nil {
+				return fmt.Errorf("(%d) - %w", command.Process.Pid, err)
+			}
+		}
+		return nil
+	}
+
+	pid, err := strconv.Atoi(target)
+	if err != nil {
+		return fmt.Errorf("%s: arguments must be process or job IDs", target)
+	}
+	if err := sendSignal(pid, signal); err != nil {
+		return fmt.Errorf("(%d) - %w", pid, err)
+	}
+	return nil
+}
+
+// Выводит список сигналов или имена сигналов по номерам и кодам возврата (128+N)
+func listSignals(args []string, stdout io.Writer) (int, error) {
+	if len(args) == 0 {
+		for index, signal := range supportedSignals {
+			fmt.Fprintf(stdout, "%2d) SIG%-8s", int(signal), signalNames[signal])
+			if (index+1)%5 == 0 || index == len(supportedSignals)-1 {
+				fmt.Fprintln(stdout)
+			}
+		}
+		return 0, nil
+	}
+
+	exitCode := 0
+	var errs []error
+	for _, arg := range args {
+		if number, err := strconv.Atoi(arg); err == nil {
+			if number > 128 {
+				number -= 128
+			}
+			signal, err := parseSignal(strconv.Itoa(number))
+			if err != nil || number == 0 {
+				errs = append(errs, fmt.Errorf("kill: %s: invalid signal specification", arg))
+				exitCode = 1
+				continue
+			}
+			fmt.Fprintln(stdout, signalNames[signal])
+			continue
+		}
+		signal, err := parseSignal(arg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("kill: %w", err))
+			exitCode = 1
+			continue
+		}
+		fmt.Fprintln(stdout, int(signal))
+	}
+	return exitCode, errors.Join(errs...)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestParseSignal(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected syscall.Signal
+		valid    bool
+	}{
+		{"TERM", syscall.SIGTERM, true},
+		{"SIGTERM", syscall.SIGTERM, true},
+		{"sigterm", syscall.SIGTERM, true},
+		{"kill", syscall.SIGKILL, true},
+		{"Int", syscall.SIGINT, true},
+		{"9", syscall.SIGKILL, true},
+		{"15", syscall.SIGTERM, true},
+		{"0", 0, true},
+		{"SIG", 0, false},
+		{"FOO", 0, false},
+		{"999", 0, false},
+		{"-1", 0, false},
+		{"", 0, false},
+	}
+
+	for _, test := range tests {
+		signal, err := parseSignal(test.name)
+		if (err == nil) != test.valid || signal != test.expected {
+			t.Errorf("parseSignal(%q) = %v, %v, expected %v, valid %v", test.name, signal, err, test.expected, test.valid)
+		}
+	}
+}
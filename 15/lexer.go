@@ -0,0 +1,229 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// Операторы командной строки в порядке убывания длины
+var shellOperators = []string{"&&", "||", ">>", "|", ">", "<", ";", "&"}
+
+// Проверяет, является ли токен оператором
+func isOperatorToken(token string) bool {
+	for _, operator := range shellOperators {
+		if token == operator {
+			return true
+		}
+	}
+	return false
+}
+
+// Разбивает строку на слова и операторы с учетом кавычек и экранирования.
+// Слова возвращаются в исходном виде (с кавычками): раскрытие выполняется
+// непосредственно перед запуском команды.
+func tokenizeCommandLine(line string) ([]string, error) {
+	var tokens []string
+	var word strings.Builder
+	inWord := false
+
+	flushWord := func() {
+		if inWord {
+			tokens = append(tokens, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+
+	for i := 0; i < len(line); {
+		char := line[i]
+
+		switch {
+		case char == ' ' || char == '\t' || char == '\n' || char == '\r':
+			flushWord()
+			i++
+			continue
+
+		case char == '#' && !inWord:
+			// Комментарий до конца строки
+			flushWord()
+			return tokens, nil
+
+		case char == '\\':
+			inWord = true
+			if i+1 < len(line) {
+				word.WriteString(line[i : i+2])
+				i += 2
+			} else {
+				word.WriteByte(char)
+				i++
+			}
+			continue
+
+		case char == '\'' || char == '"':
+			end, err := findClosingQuote(line, i)
+			if err != nil {
+				return nil, err
+			}
+			inWord = true
+			word.WriteString(line[i : end+1])
+			i = end + 1
+			continue
+
+		case char == '$' && i+1 < len(line) && line[i+1] == '{':
+			end, err := findClosingBrace(line, i+1)
+			if err != nil {
+				return nil, err
+			}
+			inWord = true
+			word.WriteString(line[i : end+1])
+			i = end + 1
+			continue
+
+		case char == '$' && i+1 < len(line) && line[i+1] == '(':
+			// Подстановка команды $(...) или арифметическое выражение $((...))
+			end, err := findClosingParen(line, i+1)
+			if err != nil {
+				return nil, err
+			}
+			inWord = true
+			word.WriteString(line[i : end+1])
+			i = end + 1
+			continue
+
+		case char == '`':
+			end, err := findClosingBacktick(line, i)
+			if err != nil {
+				return nil, err
+			}
+			inWord = true
+			word.WriteString(line[i : end+1])
+			i = end + 1
+			continue
+		}
+
+		if operator := matchOperator(line[i:]); operator != "" {
+			flushWord()
+			tokens = append(tokens, operator)
+			i += len(operator)
+			continue
+		}
+
+		inWord = true
+		word.WriteByte(char)
+		i++
+	}
+
+	flushWord()
+	return tokens, nil
+}
+
+// Возвращает оператор, с которого начинается строка, или пустую строку
+func matchOperator(text string) string {
+	for _, operator := range shellOperators {
+		if strings.HasPrefix(text, operator) {
+			return operator
+		}
+	}
+	return ""
+}
+
+// Находит позицию закрывающей кавычки для кавычки в позиции start
+func findClosingQuote(line string, start int) (int, error) {
+	quote := line[start]
+	for i := start + 1; i < len(line); i++ {
+		if line[i] == quote {
+			return i, nil
+		}
+		if quote != '"' {
+			continue
+		}
+
+		// Внутри двойных кавычек пропускаем экранирование и вложенные подстановки
+		switch {
+		case line[i] == '\\':
+			i++
+		case line[i] == '$' && i+1 < len(line) && line[i+1] == '(':
+			end, err := findClosingParen(line, i+1)
+			if err != nil {
+				return 0, err
+			}
+			i = end
+		case line[i] == '`':
+			end, err := findClosingBacktick(line, i)
+			if err != nil {
+				return 0, err
+			}
+			i = end
+		}
+	}
+	return 0, errors.New("syntax error: unexpected EOF while looking for matching `" + string(quote) + "'")
+}
+
+// Находит закрывающую фигурную скобку для открывающей в позиции start
+func findClosingBrace(line string, start int) (int, error) {
+	depth := 0
+	for i := start; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '\'', '"':
+			end, err := findClosingQuote(line, i)
+			if err != nil {
+				return 0, err
+			}
+			i = end
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, errors.New("syntax error: missing `}'")
+}
+
+// Находит закрывающую круглую скобку для открывающей в позиции start
+func findClosingParen(line string, start int) (int, error) {
+	depth := 0
+	for i := start; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '\'', '"':
+			end, err := findClosingQuote(line, i)
+			if err != nil {
+				return 0, err
+			}
+			i = end
+		case '`':
+			end, err := findClosingBacktick(line, i)
+			if err != nil {
+				return 0, err
+			}
+			i = end
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, errors.New("syntax error: unexpected EOF while looking for matching `)'")
+}
+
+// Находит закрывающую обратную кавычку для кавычки в позиции start
+func findClosingBacktick(line string, start int) (int, error) {
+	for i := start + 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '`':
+			return i, nil
+		}
+	}
+	return 0, errors.New("syntax error: unexpected EOF while looking for matching ``'")
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenizeCommandLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{"ls -l /tmp", []string{"ls", "-l", "/tmp"}},
+		{"  echo   a\tb  ", []string{"echo", "a", "b"}},
+		{"echo 'a b' \"c d\"", []string{"echo", "'a b'", `"c d"`}},
+		{`echo a\ b`, []string{"echo", `a\ b`}},
+		{"a|b&&c||d;e&", []string{"a", "|", "b", "&&", "c", "||", "d", ";", "e", "&"}},
+		{"cat <in >out 2>>err", []string{"cat", "<", "in", ">", "out", "2", ">>", "err"}},
+		{"echo $(ls | wc -l) `date`", []string{"echo", "$(ls | wc -l)", "`date`"}},
+		{"echo $((1 + (2 * 3)))", []string{"echo", "$((1 + (2 * 3)))"}},
+		{"echo ${x:-a b}", []string{"echo", "${x:-a b}"}},
+		{"echo a # comment", []string{"echo", "a"}},
+		{"echo a#b", []string{"echo", "a#b"}},
+		{"", nil},
+	}
+
+	for _, test := range tests {
+		tokens, err := tokenizeCommandLine(test.line)
+		if err != nil {
+			t.Errorf("tokenizeCommandLine(%q): %v", test.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(tokens, test.expected) {
+			t.Errorf("tokenizeCommandLine(%q) = %q, expected %q", test.line, tokens, test.expected)
+		}
+	}
+}
+
+func TestTokenizeCommandLineErrors(t *testing.T) {
+	for _, line := range []string{`echo "abc`, "echo 'abc", "echo $(ls", "echo ${x", "echo `date"} {
+		if tokens, err := tokenizeCommandLine(line); err == nil {
+			t.Errorf("tokenizeCommandLine(%q) = %q, expected error", line, tokens)
+		}
+	}
+}
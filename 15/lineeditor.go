@@ -0,0 +1,540 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Коды управляющих клавиш
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyBackspace = 8
+	keyTab       = 9
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// Логические клавиши, полученные из escape-последовательностей
+const (
+	keyNone = iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDeleteForward
+)
+
+// Источник строк команд для основного цикла оболочки
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+}
+
+// Построчное чтение без редактирования (stdin не является терминалом)
+type scannerLineReader struct {
+	scanner *bufio.Scanner
+}
+
+// Создает построчный читатель с увеличенным буфером для длинных строк
+func newScannerLineReader(input io.Reader) *scannerLineReader {
+	scanner := bufio.NewScanner(input)
+	buffer := make([]byte, 0, 64*1024)
+	scanner.Buffer(buffer, 1024*1024)
+	return &scannerLineReader{scanner: scanner}
+}
+
+// Читает следующую строку; приглашение не выводится
+func (r *scannerLineReader) ReadLine(prompt string) (string, error) {
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+// Редактор строки в raw-режиме терминала с историей и автодополнением
+type lineEditor struct {
+	fd      int
+	input   *bufio.Reader
+	output  io.Writer
+	history *commandHistory
+
+	prompt       []rune
+	buffer       []rune
+	cursor       int
+	historyIndex int
+	savedLine    []rune
+	lastKeyTab   bool
+}
+
+// Создает редактор строки для терминала, связанного с stdin
+func newLineEditor(input *os.File, output io.Writer, history *commandHistory) *lineEditor {
+	return &lineEditor{
+		fd:      int(input.Fd()),
+		input:   bufio.NewReader(input),
+		output:  output,
+		history: history,
+	}
+}
+
+// Читает строку с редактированием; Ctrl+D на пустой строке возвращает io.EOF
+func (e *lineEditor) ReadLine(prompt string) (string, error) {
+	state, err := makeRaw(e.fd)
+	if err != nil {
+		// Терминал не поддерживает raw-режим - читаем строку как есть
+		fmt.Fprint(e.output, prompt)
+		line, readErr := e.input.ReadString('\n')
+		if readErr != nil && line == "" {
+			return "", readErr
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	defer restoreTerminal(e.fd, state)
+
+	e.prompt = []rune(prompt)
+	e.buffer = nil
+	e.cursor = 0
+	e.historyIndex = len(e.history.Entries())
+	e.savedLine = nil
+	e.lastKeyTab = false
+	e.refresh()
+
+	for {
+		char, _, err := e.input.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		isTab := char == keyTab
+		switch char {
+		case keyEnter, '\n':
+			e.cursor = len(e.buffer)
+			e.refresh()
+			fmt.Fprint(e.output, "\r\n")
+			return string(e.buffer), nil
+
+		case keyCtrlC:
+			// Отменяем текущую строку
+			fmt.Fprint(e.output, "^C\r\n")
+			e.buffer = nil
+			e.cursor = 0
+			e.historyIndex = len(e.history.Entries())
+			e.refresh()
+
+		case keyCtrlD:
+			if len(e.buffer) == 0 {
+				fmt.Fprint(e.output, "\r\n")
+				return "", io.EOF
+			}
+			e.deleteForward()
+
+		case keyTab:
+			e.complete()
+
+		case keyBackspace, keyDelete:
+			if e.cursor > 0 {
+				e.buffer = append(e.buffer[:e.cursor-1], e.buffer[e.cursor:]...)
+				e.cursor--
+				e.refresh()
+			}
+
+		case keyCtrlA:
+			e.moveTo(0)
+		case keyCtrlE:
+			e.moveTo(len(e.buffer))
+		case keyCtrlB:
+			e.moveTo(e.cursor - 1)
+		case keyCtrlF:
+			e.moveTo(e.cursor + 1)
+		case keyCtrlP:
+			e.historyMove(-1)
+		case keyCtrlN:
+			e.historyMove(1)
+
+		case keyCtrlK:
+			e.buffer = e.buffer[:e.cursor]
+			e.refresh()
+
+		case keyCtrlU:
+			e.buffer = append([]rune(nil), e.buffer[e.cursor:]...)
+			e.cursor = 0
+			e.refresh()
+
+		case keyCtrlW:
+			e.deleteWordBackward()
+
+		case keyCtrlL:
+			fmt.Fprint(e.output, "\x1b[H\x1b[2J")
+			e.refresh()
+
+		case keyCtrlR:
+			accepted, err := e.reverseSearch()
+			if err != nil {
+				return "", err
+			}
+			if accepted {
+				fmt.Fprint(e.output, "\r\n")
+				return string(e.buffer), nil
+			}
+
+		case keyEscape:
+			switch e.readEscapeSequence() {
+			case keyUp:
+				e.historyMove(-1)
+			case keyDown:
+				e.historyMove(1)
+			case keyLeft:
+				e.moveTo(e.cursor - 1)
+			case keyRight:
+				e.moveTo(e.cursor + 1)
+			case keyHome:
+				e.moveTo(0)
+			case keyEnd:
+				e.moveTo(len(e.buffer))
+			case keyDeleteForward:
+				e.deleteForward()
+			}
+
+		default:
+			if unicode.IsPrint(char) {
+				e.insert(char)
+			}
+		}
+		e.lastKeyTab = isTab
+	}
+}
+
+// Читает escape-последовательность после ESC и возвращает логическую клавишу
+func (e *lineEditor) readEscapeSequence() int {
+	introducer, err := e.input.ReadByte()
+	if err != nil {
+		return keyNone
+	}
+
+	if introducer == 'O' {
+		final, err := e.input.ReadByte()
+		if err != nil {
+			return keyNone
+		}
+		return escapeFinalKey(final, "")
+	}
+	if introducer != '[' {
+		return keyNone
+	}
+
+	var parameters strings.Builder
+	for {
+		b, err := e.input.ReadByte()
+		if err != nil {
+			return keyNone
+		}
+		if b >= 0x40 && b <= 0x7e {
+			return escapeFinalKey(b, parameters.String())
+		}
+		parameters.WriteByte(b)
+	}
+}
+
+// Определяет клавишу по завершающему символу CSI-последовательности
+func escapeFinalKey(final byte, parameters string) int {
+	switch final {
+	case 'A':
+		return keyUp
+	case 'B':
+		return keyDown
+	case 'C':
+		return keyRight
+	case 'D':
+		return keyLeft
+	case 'H':
+		return keyHome
+	case 'F':
+		return keyEnd
+	case '~':
+		switch parameters {
+		case "1", "7":
+			return keyHome
+		case "4", "8":
+			return keyEnd
+		case "3":
+			return keyDeleteForward
+		}
+	}
+	return keyNone
+}
+
+// Перерисовывает строку ввода с горизонтальной прокруткой
+func (e *lineEditor) refresh() {
+	width := terminalWidth(e.fd)
+	visible := e.buffer
+	cursor := e.cursor
+
+	for len(e.prompt)+cursor >= width && len(visible) > 0 && cursor > 0 {
+		visible = visible[1:]
+		cursor--
+	}
+	if limit := width - 1 - len(e.prompt); limit >= cursor && len(visible) > limit {
+		visible = visible[:limit]
+	}
+
+	var screen strings.Builder
+	screen.WriteString("\r")
+	screen.WriteString(string(e.prompt))
+	screen.WriteString(string(visible))
+	screen.WriteString("\x1b[K")
+	if back := len(visible) - cursor; back > 0 {
+		fmt.Fprintf(&screen, "\x1b[%dD", back)
+	}
+	fmt.Fprint(e.output, screen.String())
+}
+
+// Вставляет символ в позицию курсора
+func (e *lineEditor) insert(char rune) {
+	e.buffer = append(e.buffer, 0)
+	copy(e.buffer[e.cursor+1:], e.buffer[e.cursor:])
+	e.buffer[e.cursor] = char
+	e.cursor++
+	e.refresh()
+}
+
+// Вставляет строку в позицию курсора
+func (e *lineEditor) insertString(text string) {
+	runes := []rune(text)
+	tail := append([]rune(nil), e.buffer[e.cursor:]...)
+	e.buffer = append(append(e.buffer[:e.cursor], runes...), tail...)
+	e.cursor += len(runes)
+	e.refresh()
+}
+
+// Перемещает курсор в заданную позицию
+func (e *lineEditor) moveTo(position int) {
+	if position < 0 || position > len(e.buffer) {
+		return
+	}
+	e.cursor = position
+	e.refresh()
+}
+
+// Удаляет символ под курсором
+func (e *lineEditor) deleteForward() {
+	if e.cursor < len(e.buffer) {
+		e.buffer = append(e.buffer[:e.cursor], e.buffer[e.cursor+1:]...)
+		e.refresh()
+	}
+}
+
+// Удаляет слово перед курсором
+func (e *lineEditor) deleteWordBackward() {
+	start := e.cursor
+	for start > 0 && unicode.IsSpace(e.buffer[start-1]) {
+		start--
+	}
+	for start > 0 && !unicode.IsSpace(e.buffer[start-1]) {
+		start--
+	}
+	e.buffer = append(e.buffer[:start], e.buffer[e.cursor:]...)
+	e.cursor = start
+	e.refresh()
+}
+
+// Переходит к предыдущей (-1) или следующей (+1) команде истории
+func (e *lineEditor) historyMove(direction int) {
+	entries := e.history.Entries()
+	index := e.historyIndex + direction
+	if index < 0 || index > len(entries) {
+		return
+	}
+
+	// Запоминаем редактируемую строку перед уходом в историю
+	if e.historyIndex == len(entries) {
+		e.savedLine = append([]rune(nil), e.buffer...)
+	}
+
+	e.historyIndex = index
+	if index == len(entries) {
+		e.buffer = append([]rune(nil), e.savedLine...)
+	} else {
+		e.buffer = []rune(entries[index])
+	}
+	e.cursor = len(e.buffer)
+	e.refresh()
+}
+
+// Дополняет слово под курсором; повторный Tab выводит список вариантов
+func (e *lineEditor) complete() {
+	wordStart, candidates := completeLine(e.buffer, e.cursor)
+	if len(candidates) == 0 {
+		fmt.Fprint(e.output, "\a")
+		return
+	}
+
+	word := string(e.buffer[wordStart:e.cursor])
+	if len(candidates) == 1 {
+		completion := strings.TrimPrefix(candidates[0], word)
+		if !strings.HasSuffix(candidates[0], "/") {
+			completion += " "
+		}
+		e.insertString(completion)
+		return
+	}
+
+	if prefix := commonPrefix(candidates); len(prefix) > len(word) {
+		e.insertString(strings.TrimPrefix(prefix, word))
+		return
+	}
+
+	if !e.lastKeyTab {
+		fmt.Fprint(e.output, "\a")
+		return
+	}
+	e.printCandidates(candidates)
+	e.refresh()
+}
+
+// Выводит варианты дополнения в несколько колонок под строкой ввода
+func (e *lineEditor) printCandidates(candidates []string) {
+	names := make([]string, len(candidates))
+	columnWidth := 0
+	for i, candidate := range candidates {
+		name := candidate
+		if index := strings.LastIndex(strings.TrimSuffix(name, "/"), "/"); index >= 0 {
+			name = name[index+1:]
+		}
+		names[i] = name
+		if len([]rune(name)) > columnWidth {
+			columnWidth = len([]rune(name))
+		}
+	}
+	columnWidth += 2
+
+	columns := terminalWidth(e.fd) / columnWidth
+	if columns < 1 {
+		columns = 1
+	}
+	rows := (len(names) + columns - 1) / columns
+
+	var listing strings.Builder
+	listing.WriteString("\r\n")
+	for row := 0; row < rows; row++ {
+		for column := 0; column < columns; column++ {
+			index := column*rows + row
+			if index >= len(names) {
+				break
+			}
+			fmt.Fprintf(&listing, "%-*s", columnWidth, names[index])
+		}
+		listing.WriteString("\r\n")
+	}
+	fmt.Fprint(e.output, listing.String())
+}
+
+// Интерактивный обратный поиск по истории (Ctrl+R).
+// Возвращает true, если найденную команду нужно сразу выполнить.
+func (e *lineEditor) reverseSearch() (bool, error) {
+	entries := e.history.Entries()
+	originalBuffer := append([]rune(nil), e.buffer...)
+	originalCursor := e.cursor
+
+	var query []rune
+	matchIndex := len(entries)
+	match := ""
+	failed := false
+
+	// Ищет совпадение, начиная с позиции from и двигаясь к началу истории
+	search := func(from int) {
+		for i := from; i >= 0; i-- {
+			if i < len(entries) && strings.Contains(entries[i], string(query)) {
+				matchIndex = i
+				match = entries[i]
+				failed = false
+				return
+			}
+		}
+		failed = len(query) > 0
+	}
+
+	draw := func() {
+		label := "reverse-i-search"
+		if failed {
+			label = "failing reverse-i-search"
+		}
+		fmt.Fprintf(e.output, "\r(%s)`%s': %s\x1b[K", label, string(query), match)
+	}
+
+	// Переносит найденную команду в буфер редактора
+	accept := func() {
+		if match != "" {
+			e.buffer = []rune(match)
+			e.cursor = len(e.buffer)
+		}
+		e.historyIndex = len(entries)
+		e.refresh()
+	}
+
+	draw()
+	for {
+		char, _, err := e.input.ReadRune()
+		if err != nil {
+			return false, err
+		}
+
+		switch char {
+		case keyEnter, '\n':
+			accept()
+			return true, nil
+
+		case keyCtrlR:
+			if len(query) > 0 {
+				search(matchIndex - 1)
+			}
+
+		case keyBackspace, keyDelete:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				match = ""
+				search(len(entries) - 1)
+			}
+
+		case keyCtrlG, keyCtrlC:
+			e.buffer = originalBuffer
+			e.cursor = originalCursor
+			e.refresh()
+			return false, nil
+
+		case keyEscape:
+			e.readEscapeSequence()
+			accept()
+			return false, nil
+
+		default:
+			if !unicode.IsPrint(char) {
+				accept()
+				return false, nil
+			}
+			query = append(query, char)
+			search(matchIndex)
+		}
+		draw()
+	}
+}
@@ -0,0 +1,424 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Частота системного таймера в /proc (USER_HZ)
+const clockTicksPerSecond = 100
+
+// Сведения о процессе, прочитанные из /proc
+type processInfo struct {
+	pid        int
+	ppid       int
+	pgid       int
+	uid        int
+	state      string
+	comm       string
+	args       string
+	ttyNumber  int
+	threads    int
+	cpuTicks   uint64
+	startTicks uint64
+	vsizeBytes uint64
+	rssPages   int64
+}
+
+// Колонка вывода ps: заголовок, выравнивание и способ получения значения
+type psColumn struct {
+	header     string
+	rightAlign bool
+	value      func(process *processInfo, context *psContext) string
+}
+
+// Общие данные для вычисления колонок (время загрузки, кэш имен пользователей)
+type psContext struct {
+	bootTime  time.Time
+	userNames map[int]string
+}
+
+// Поддерживаемые колонки ps -o
+var psColumns = map[string]psColumn{
+	"pid":  {"PID", true, func(p *processInfo, _ *psContext) string { return strconv.Itoa(p.pid) }},
+	"ppid": {"PPID", true, func(p *processInfo, _ *psContext) string { return strconv.Itoa(p.ppid) }},
+	"pgid": {"PGID", true, func(p *processInfo, _ *psContext) string { return strconv.Itoa(p.pgid) }},
+	"uid":  {"UID", true, func(p *processInfo, _ *psContext) string { return strconv.Itoa(p.uid) }},
+	"user": {"USER", false, func(p *processInfo, c *psContext) string { return c.userName(p.uid) }},
+	"stat": {"STAT", false, func(p *processInfo, _ *psContext) string { return p.state }},
+	"tty":  {"TT", false, func(p *processInfo, _ *psContext) string { return ttyName(p.ttyNumber) }},
+	"nlwp": {"NLWP", true, func(p *processInfo, _ *psContext) string { return strconv.Itoa(p.threads) }},
+	"rss": {"RSS", true, func(p *processInfo, _ *psContext) string {
+		return strconv.FormatInt(p.rssPages*int64(os.Getpagesize())/1024, 10)
+	}},
+	"vsz": {"VSZ", true, func(p *processInfo, _ *psContext) string { return strconv.FormatUint(p.vsizeBytes/1024, 10) }},
+	"time": {"TIME", true, func(p *processInfo, _ *psContext) string {
+		return formatDuration(time.Duration(p.cpuTicks) * time.Second / clockTicksPerSecond)
+	}},
+	"etime": {"ELAPSED", true, func(p *processInfo, c *psContext) string { return formatDuration(c.elapsed(p)) }},
+	"pcpu": {"%CPU", true, func(p *processInfo, c *psContext) string {
+		elapsed := c.elapsed(p).Seconds()
+		if elapsed <= 0 {
+			return "0.0"
+		}
+		return strconv.FormatFloat(float64(p.cpuTicks)/clockTicksPerSecond/elapsed*100, 'f', 1, 64)
+	}},
+	"comm": {"COMMAND", false, func(p *processInfo, _ *psContext) string { return p.comm }},
+	"args": {"COMMAND", false, func(p *processInfo, _ *psContext) string {
+		if p.args == "" {
+			return "[" + p.comm + "]"
+		}
+		return p.args
+	}},
+}
+
+// Синонимы колонок в стиле procps
+var psColumnAliases = map[string]string{
+	"cmd": "args", "command": "args", "ucomm": "comm", "%cpu": "pcpu",
+	"state": "stat", "s": "stat", "tt": "tty", "thcount": "nlwp", "rssize": "rss",
+	"cputime": "time", "euser": "user", "uname": "user", "euid": "uid",
+}
+
+// Встроенная команда ps: список процессов из /proc.
+// ps [-e|-A] [-o col,...] [-u user,...] [-p pid,...] [-H|--forest]
+func builtinPs(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) (int, error) {
+	columns := []string{"pid", "comm"}
+	customColumns := false
+	forest := false
+	userFilter := map[int]bool{}
+	pidFilter := map[int]bool{}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-e", "-A", "ax", "-ax", "aux":
+			continue
+		case "-H", "--forest", "f":
+			forest = true
+			continue
+		case "-f":
+			columns = []string{"user", "pid", "ppid", "pcpu", "etime", "tty", "time", "args"}
+			customColumns = false
+			continue
+		}
+
+		if arg != "-o" && arg != "-u" && arg != "-p" {
+			return 1, fmt.Errorf("ps: unknown option %s", arg)
+		}
+		if i+1 >= len(args) {
+			return 1, fmt.Errorf("ps: option %s requires an argument", arg)
+		}
+		i++
+		values := strings.FieldsFunc(args[i], func(r rune) bool { return r == ',' || r == ' ' })
+
+		switch arg {
+		case "-o":
+			if !customColumns {
+				columns = nil
+				customColumns = true
+			}
+			for _, value := range values {
+				name := strings.ToLower(value)
+				if alias, ok := psColumnAliases[name]; ok {
+					name = alias
+				}
+				if _, ok := psColumns[name]; !ok {
+					return 1, fmt.Errorf("ps: unknown column %q", value)
+				}
+				columns = append(columns, name)
+			}
+		case "-u":
+			for _, value := range values {
+				uid, err := lookupUserID(value)
+				if err != nil {
+					return 1, fmt.Errorf("ps: %w", err)
+				}
+				userFilter[uid] = true
+			}
+		case "-p":
+			for _, value := range values {
+				pid, err := strconv.Atoi(value)
+				if err != nil {
+					return 1, fmt.Errorf("ps: invalid process id %q", value)
+				}
+				pidFilter[pid] = true
+			}
+		}
+	}
+
+	processes, err := readProcesses()
+	if err != nil {
+		return 1, fmt.Errorf("ps: %w", err)
+	}
+
+	// Фильтрация по пользователям и PID
+	filtered := processes[:0]
+	for _, process := range processes {
+		if len(userFilter) > 0 && !userFilter[process.uid] {
+			continue
+		}
+		if len(pidFilter) > 0 && !pidFilter[process.pid] {
+			continue
+		}
+		filtered = append(filtered, process)
+	}
+
+	depths := make([]int, len(filtered))
+	if forest {
+		filtered, depths = arrangeProcessTree(filtered)
+	}
+
+	context := &psContext{bootTime: readBootTime(), userNames: make(map[int]string)}
+	printProcessTable(stdout, filtered, depths, columns, context)
+	return 0, nil
+}
+
+// Читает сведения обо всех процессах из /proc, отсортированные по PID
+func readProcesses() ([]*processInfo, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	var processes []*processInfo
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		process, err := readProcess(pid)
+		if err != nil {
+			// Процесс мог завершиться во время чтения
+			continue
+		}
+		processes = append(processes, process)
+	}
+
+	sort.Slice(processes, func(i, j int) bool { return processes[i].pid < processes[j].pid })
+	return processes, nil
+}
+
+// Читает /proc/PID/stat, /proc/PID/cmdline и владельца процесса
+func readProcess(pid int) (*processInfo, error) {
+	directory := filepath.Join("/proc", strconv.Itoa(pid))
+	stat, err := os.ReadFile(filepath.Join(directory, "stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Имя команды в скобках может содержать пробелы и скобки
+	open := bytes.IndexByte(stat, '(')
+	closing := bytes.LastIndexByte(stat, ')')
+	if open < 0 || closing < open {
+		return nil, errors.New("malformed stat file")
+	}
+	fields := strings.Fields(string(stat[closing+1:]))
+	if len(fields) < 22 {
+		return nil, errors.New("malformed stat file")
+	}
+
+	// Поля после имени команды: state(0) ppid(1) pgrp(2) session(3) tty_nr(4) ...
+	// utime(11) stime(12) ... num_threads(17) ... starttime(19) vsize(20) rss(21)
+	number := func(index int) int64 {
+		value, _ := strconv.ParseInt(fields[index], 10, 64)
+		return value
+	}
+	process := &processInfo{
+		pid:        pid,
+		comm:       string(stat[open+1 : closing]),
+		state:      fields[0],
+		ppid:       int(number(1)),
+		pgid:       int(number(2)),
+		ttyNumber:  int(number(4)),
+		cpuTicks:   uint64(number(11) + number(12)),
+		threads:    int(number(17)),
+		startTicks: uint64(number(19)),
+		vsizeBytes: uint64(number(20)),
+		rssPages:   number(21),
+	}
+
+	if info, err := os.Stat(directory); err == nil {
+		if sys, ok := info.Sys().(*syscall.Stat_t); ok {
+			process.uid = int(sys.Uid)
+		}
+	}
+	if cmdline, err := os.ReadFile(filepath.Join(directory, "cmdline")); err == nil {
+		process.args = strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+	}
+	return process, nil
+}
+
+// Упорядочивает процессы в дерево (родитель перед потомками) и вычисляет глубину
+func arrangeProcessTree(processes []*processInfo) ([]*processInfo, []int) {
+	present := make(map[int]bool, len(processes))
+	children := make(map[int][]*processInfo)
+	for _, process := range processes {
+		present[process.pid] = true
+	}
+
+	var roots []*processInfo
+	for _, process := range processes {
+		if present[process.ppid] && process.ppid != process.pid {
+			children[process.ppid] = append(children[process.ppid], process)
+		} else {
+			roots = append(roots, process)
+		}
+	}
+
+	ordered := make([]*processInfo, 0, len(processes))
+	depths := make([]int, 0, len(processes))
+	var visit func(process *processInfo, depth int)
+	visit = func(process *processInfo, depth int) {
+		ordered = append(ordered, process)
+		depths = append(depths, depth)
+		for _, child := range children[process.pid] {
+			visit(child, depth+1)
+		}
+	}
+	for _, root := range roots {
+		visit(root, 0)
+	}
+	return ordered, depths
+}
+
+// Выводит таблицу процессов; в режиме дерева команда сдвигается по глубине
+func printProcessTable(output io.Writer, processes []*processInfo, depths []int, columns []string, context *psContext) {
+	rows := make([][]string, 0, len(processes)+1)
+	header := make([]string, len(columns))
+	for i, name := range columns {
+		header[i] = psColumns[name].header
+	}
+	rows = append(rows, header)
+
+	for index, process := range processes {
+		row := make([]string, len(columns))
+		for i, name := range columns {
+			value := psColumns[name].value(process, context)
+			if (name == "comm" || name == "args") && depths[index] > 0 {
+				value = strings.Repeat("    ", depths[index]-1) + " \\_ " + value
+			}
+			row[i] = value
+		}
+		rows = append(rows, row)
+	}
+
+	widths := make([]int, len(columns))
+	for _, row := range rows {
+		for i, value := range row {
+			if len(value) > widths[i] {
+				widths[i] = len(value)
+			}
+		}
+	}
+
+	for _, row := range rows {
+		var line strings.Builder
+		for i, value := range row {
+			if i > 0 {
+				line.WriteByte(' ')
+			}
+			switch {
+			case psColumns[columns[i]].rightAlign:
+				fmt.Fprintf(&line, "%*s", widths[i], value)
+			case i == len(row)-1:
+				line.WriteString(value)
+			default:
+				fmt.Fprintf(&line, "%-*s", widths[i], value)
+			}
+		}
+		fmt.Fprintln(output, line.String())
+	}
+}
+
+// Возвращает имя пользователя по UID (с кэшированием)
+func (c *psContext) userName(uid int) string {
+	if name, ok := c.userNames[uid]; ok {
+		return name
+	}
+	name := strconv.Itoa(uid)
+	if account, err := user.LookupId(name); err == nil {
+		name = account.Username
+	}
+	c.userNames[uid] = name
+	return name
+}
+
+// Возвращает время, прошедшее с запуска процесса
+func (c *psContext) elapsed(process *processInfo) time.Duration {
+	started := c.bootTime.Add(time.Duration(process.startTicks) * time.Second / clockTicksPerSecond)
+	return time.Since(started)
+}
+
+// Читает время загрузки системы (btime) из /proc/stat
+func readBootTime() time.Time {
+	content, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Now()
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if value, ok := strings.CutPrefix(line, "btime "); ok {
+			if seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil {
+				return time.Unix(seconds, 0)
+			}
+		}
+	}
+	return time.Now()
+}
+
+// Преобразует имя пользователя или числовой UID в UID
+func lookupUserID(name string) (int, error) {
+	if uid, err := strconv.Atoi(name); err == nil {
+		return uid, nil
+	}
+	account, err := user.Lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(account.Uid)
+}
+
+// Возвращает имя терминала по номеру устройства tty_nr
+func ttyName(number int) string {
+	if number == 0 {
+		return "?"
+	}
+	major := (number >> 8) & 0xfff
+	minor := (number & 0xff) | ((number >> 12) & 0xfff00)
+	switch {
+	case major >= 136 && major <= 143:
+		return fmt.Sprintf("pts/%d", (major-136)*256+minor)
+	case major == 4 && minor < 64:
+		return fmt.Sprintf("tty%d", minor)
+	case major == 4:
+		return fmt.Sprintf("ttyS%d", minor-64)
+	}
+	return fmt.Sprintf("%d,%d", major, minor)
+}
+
+// Форматирует длительность как [[DD-]HH:]MM:SS
+func formatDuration(duration time.Duration) string {
+	total := int64(duration.Seconds())
+	days := total / 86400
+	hours := total % 86400 / 3600
+	minutes := total % 3600 / 60
+	seconds := total % 60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%d-%02d:%02d:%02d", days, hours, minutes, seconds)
+	case hours > 0:
+		return fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds)
+	}
+	return fmt.Sprintf("%02d:%02d", minutes, seconds)
+}
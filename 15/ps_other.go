@@ -0,0 +1,26 @@
+//go:build !linux
+
+package main
+
+import (
+	"io"
+	"os/exec"
+	"runtime"
+)
+
+// Встроенная команда ps: без /proc используется системная утилита
+func builtinPs(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) (int, error) {
+	var command *exec.Cmd
+	if runtime.GOOS == "windows" {
+		command = exec.Command("tasklist")
+	} else {
+		command = exec.Command("ps", append([]string{"-e", "-o", "pid,comm"}, args...)...)
+	}
+	command.Stdin = stdin
+	command.Stdout = stdout
+	command.Stderr = stderr
+	if err := command.Run(); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
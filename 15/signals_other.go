@@ -0,0 +1,39 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Сигналы, поддерживаемые командой kill
+var supportedSignals = []syscall.Signal{syscall.SIGINT, syscall.SIGKILL, syscall.SIGTERM}
+
+// Имена сигналов без префикса SIG
+var signalNames = map[syscall.Signal]string{
+	syscall.SIGINT: "INT", syscall.SIGKILL: "KILL", syscall.SIGTERM: "TERM",
+}
+
+// Сигнал по умолчанию для команды kill (на этой платформе доступно только завершение)
+const defaultKillSignal = syscall.SIGKILL
+
+// Группы процессов на этой платформе не поддерживаются
+func setProcessGroup(command *exec.Cmd, pgid int) {}
+
+// Посылает сигнал процессу; поддерживается только принудительное завершение
+func sendSignal(pid int, signal syscall.Signal) error {
+	if pid <= 0 {
+		return errors.New("process groups are not supported on this platform")
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	if signal == syscall.SIGINT {
+		return process.Signal(os.Interrupt)
+	}
+	return process.Kill()
+}
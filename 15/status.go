@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"syscall"
+)
+
+// Состояние последних выполненных команд и опции оболочки
+var (
+	lastExitStatus     int                // Код возврата последнего пайплайна ($?)
+	pipeStatus         []int              // Коды возврата этапов последнего пайплайна (PIPESTATUS)
+	substitutionStatus int                // Код возврата последней подстановки команды $(...)
+	subshellDepth      int                // Глубина вложенности подоболочек
+	shellOptions       = map[string]bool{ // Опции, управляемые командой set
+		"errexit":  false,
+		"pipefail": false,
+	}
+)
+
+// Ошибка-признак вызова встроенной команды exit
+var errExitRequested = errors.New("exit requested")
+
+// Паника для выхода из подоболочки (exit или set -e внутри $(...))
+type subshellExit struct {
+	code int
+}
+
+// Вычисляет код возврата по ошибке запуска или ожидания процесса:
+// код завершения процесса, 128+N для завершения сигналом N,
+// 127 для ненайденной команды и 126 для неисполняемого файла
+func exitStatusFromError(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitError *exec.ExitError
+	if errors.As(err, &exitError) {
+		if status, ok := exitError.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		return exitError.ExitCode()
+	}
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+		return 127
+	}
+	if errors.Is(err, os.ErrPermission) {
+		return 126
+	}
+	return 1
+}
+
+// Формирует сообщение об ошибке запуска команды в стиле sh
+func commandStartError(name string, err error) error {
+	switch exitStatusFromError(err) {
+	case 127:
+		return fmt.Errorf("%s: command not found", name)
+	case 126:
+		return fmt.Errorf("%s: Permission denied", name)
+	}
+	return fmt.Errorf("%s: %w", name, err)
+}
+
+// Итоговый код пайплайна: код последнего этапа или, при pipefail,
+// код последнего завершившегося с ошибкой этапа
+func pipelineExitStatus(statuses []int) int {
+	if len(statuses) == 0 {
+		return 0
+	}
+	if shellOptions["pipefail"] {
+		for i := len(statuses) - 1; i >= 0; i-- {
+			if statuses[i] != 0 {
+				return statuses[i]
+			}
+		}
+		return 0
+	}
+	return statuses[len(statuses)-1]
+}
+
+// Завершает оболочку (или текущую подоболочку) с заданным кодом
+func exitShell(code int) {
+	if subshellDepth > 0 {
+		panic(subshellExit{code: code})
+	}
+	os.Exit(code)
+}
+
+// Встроенная команда exit: завершение оболочки с кодом n или $?
+func builtinExit(args []string) (int, error) {
+	if len(args) == 0 {
+		return lastExitStatus, errExitRequested
+	}
+	code, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 2, fmt.Errorf("exit: %s: numeric argument required", args[0])
+	}
+	return code & 0xff, errExitRequested
+}
+
+// Встроенная команда set: вывод переменных или управление опциями
+// (-e/+e, -o/+o errexit, -o/+o pipefail)
+func builtinSet(args []string, stdout io.Writer) (int, error) {
+	if len(args) == 0 {
+		for _, name := range allVariableNames() {
+			value, _ := lookupVariable(name)
+			fmt.Fprintf(stdout, "%s=%s\n", name, shellQuote(value))
+		}
+		return 0, nil
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || (arg[0] != '-' && arg[0] != '+') {
+			return 2, fmt.Errorf("set: %s: invalid option", arg)
+		}
+		enable := arg[0] == '-'
+
+		for _, letter := range arg[1:] {
+			switch letter {
+			case 'e':
+				shellOptions["errexit"] = enable
+			case 'o':
+				if i+1 >= len(args) {
+					printShellOptions(stdout, enable)
+					return 0, nil
+				}
+				i++
+				if _, known := shellOptions[args[i]]; !known {
+					return 2, fmt.Errorf("set: %s: invalid option name", args[i])
+				}
+				shellOptions[args[i]] = enable
+			default:
+				return 2, fmt.Errorf("set: %c%c: invalid option", arg[0], letter)
+			}
+		}
+	}
+	return 0, nil
+}
+
+// Выводит состояние опций (set -o) или команды для их восстановления (set +o)
+func printShellOptions(stdout io.Writer, humanReadable bool) {
+	names := make([]string, 0, len(shellOptions))
+	for name := range shellOptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if humanReadable {
+			state := "off"
+			if shellOptions[name] {
+				state = "on"
+			}
+			fmt.Fprintf(stdout, "%-15s\t%s\n", name, state)
+			continue
+		}
+		sign := "+"
+		if shellOptions[name] {
+			sign = "-"
+		}
+		fmt.Fprintf(stdout, "set %so %s\n", sign, name)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Аргумент, с которым оболочка запускает саму себя для выполнения встроенной команды
+// этапа пайплайна в отдельном процессе
+const builtinProcessArgument = "-run-builtin"
+
+// Состояние оболочки, передаваемое процессу встроенной команды
+type builtinProcessState struct {
+	Args       []string
+	Variables  map[string]string
+	Aliases    map[string]string
+	Options    map[string]bool
+	ExitStatus int
+}
+
+// Проверяет, может ли встроенная команда выполняться в пайплайне внутри процесса оболочки:
+// такие команды не меняют переменные, каталог, псевдонимы и опции
+// (jobs, wait и kill работают с заданиями текущей оболочки)
+func isStatelessBuiltin(args []string) bool {
+	switch args[0] {
+	case "echo", "pwd", "kill", "ps", "jobs", "wait", "env":
+		return true
+	case "history":
+		return len(args) < 2 || args[1] != "-c"
+	}
+	return false
+}
+
+// Готовит запуск встроенной команды в дочернем процессе оболочки: как в подоболочке,
+// cd, export, alias и прочие команды этапа пайплайна не меняют состояние текущей оболочки.
+// Переменные, псевдонимы и опции передаются через канал (fd 3), присваивания - через окружение.
+// Канал закрывается вместе с остальными файлами этапа после запуска процесса.
+func newBuiltinProcess(args, assignments []string) (*exec.Cmd, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	state, err := json.Marshal(builtinProcessState{
+		Args:       args,
+		Variables:  shellVariables,
+		Aliases:    shellAliases,
+		Options:    shellOptions,
+		ExitStatus: lastExitStatus,
+	})
+	if err != nil {
+		return nil, err
+	}
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		writer.Write(state)
+		writer.Close()
+	}()
+
+	command := exec.Command(executable, builtinProcessArgument)
+	command.Env = append(os.Environ(), assignments...)
+	command.ExtraFiles = []*os.File{reader}
+	return command, nil
+}
+
+// Выполняет встроенную команду в дочернем процессе (запуск с builtinProcessArgument)
+// и возвращает ее код возврата
+func runBuiltinProcess() int {
+	var state builtinProcessState
+	stateFile := os.NewFile(3, "state")
+	if err := json.NewDecoder(stateFile).Decode(&state); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	stateFile.Close()
+
+	if state.Variables != nil {
+		shellVariables = state.Variables
+	}
+	if state.Aliases != nil {
+		shellAliases = state.Aliases
+	}
+	if state.Options != nil {
+		shellOptions = state.Options
+	}
+	lastExitStatus = state.ExitStatus
+
+	exitCode, err := executeBuiltinCommand(state.Args, os.Stdin, os.Stdout, os.Stderr)
+	if err != nil && !errors.Is(err, errExitRequested) {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	return exitCode
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// Тестовый бинарный файл выполняет и встроенные команды этапов пайплайна
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 && os.Args[1] == builtinProcessArgument {
+		os.Exit(runBuiltinProcess())
+	}
+	os.Exit(m.Run())
+}
+
+func TestPipelineBuiltinsIsolated(t *testing.T) {
+	directory, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	setVariable("pipeLocal", "local")
+	t.Cleanup(func() { unsetVariable("pipeLocal") })
+
+	tests := []struct {
+		line   string
+		status int
+	}{
+		{"export PIPE_A=1 | export PIPE_B=2 | export PIPE_C=3", 0},
+		{"echo | cd /", 0},
+		{"cd / | true", 0},
+		{"true | cd /nonexistent-directory", 1},
+		{"unset pipeLocal | true", 0},
+		{"alias pipeAlias=ls | true", 0},
+		{"set -e | true", 0},
+		{"true | exit 3", 3},
+		// Присваивания перед встроенной командой действуют в ее процессе
+		{"PIPE_D=5 env | grep -q PIPE_D=5", 0},
+		// Процесс встроенной команды получает локальные переменные оболочки
+		{"set | grep -q pipeLocal=local", 0},
+	}
+
+	for _, test := range tests {
+		if status := processCommandLine(test.line); status != test.status {
+			t.Errorf("%q: status = %d, expected %d", test.line, status, test.status)
+		}
+	}
+
+	for _, name := range []string{"PIPE_A", "PIPE_B", "PIPE_C", "PIPE_D"} {
+		if _, ok := os.LookupEnv(name); ok {
+			t.Errorf("%s exported to the shell by a pipeline stage", name)
+		}
+	}
+	if current, _ := os.Getwd(); current != directory {
+		t.Errorf("pipeline stage changed directory to %s", current)
+	}
+	if value, ok := lookupVariable("pipeLocal"); !ok || value != "local" {
+		t.Errorf("pipeLocal = %q, %v after unset in a pipeline", value, ok)
+	}
+	if _, ok := shellAliases["pipeAlias"]; ok {
+		t.Error("alias defined by a pipeline stage")
+	}
+	if shellOptions["errexit"] {
+		t.Error("set -e in a pipeline stage changed shell options")
+	}
+}
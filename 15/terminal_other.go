@@ -0,0 +1,28 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// Сохраненное состояние терминала (raw-режим на этой платформе не поддерживается)
+type terminalState struct{}
+
+// Проверяет, связан ли дескриптор с терминалом
+func isTerminal(fd int) bool {
+	return false
+}
+
+// Переводит терминал в raw-режим и возвращает предыдущее состояние
+func makeRaw(fd int) (*terminalState, error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
+
+// Восстанавливает состояние терминала
+func restoreTerminal(fd int, state *terminalState) error {
+	return nil
+}
+
+// Возвращает ширину терминала в символах
+func terminalWidth(fd int) int {
+	return 80
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Локальные (неэкспортированные) переменные оболочки и псевдонимы команд.
+// Экспортированные переменные хранятся непосредственно в окружении процесса
+// и наследуются дочерними процессами.
+var (
+	shellVariables = make(map[string]string)
+	shellAliases   = make(map[string]string)
+)
+
+// Шаблоны имени переменной и слова-присваивания NAME=value
+var (
+	variableNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	assignmentRegex   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+)
+
+// Проверяет корректность имени переменной
+func isValidVariableName(name string) bool {
+	return variableNameRegex.MatchString(name)
+}
+
+// Проверяет, является ли слово присваиванием NAME=value
+func isAssignmentWord(word string) bool {
+	return assignmentRegex.MatchString(word)
+}
+
+// Возвращает значение переменной: сначала локальной, затем из окружения
+func lookupVariable(name string) (string, bool) {
+	if value, ok := shellVariables[name]; ok {
+		return value, true
+	}
+	return os.LookupEnv(name)
+}
+
+// Устанавливает переменную; уже экспортированная переменная обновляется в окружении
+func setVariable(name, value string) {
+	if _, exported := os.LookupEnv(name); exported {
+		os.Setenv(name, value)
+		return
+	}
+	shellVariables[name] = value
+}
+
+// Экспортирует переменную в окружение дочерних процессов
+func exportVariable(name string) {
+	if value, ok := shellVariables[name]; ok {
+		os.Setenv(name, value)
+		delete(shellVariables, name)
+		return
+	}
+	if _, ok := os.LookupEnv(name); !ok {
+		os.Setenv(name, "")
+	}
+}
+
+// Снимает экспорт, оставляя переменную локальной
+func unexportVariable(name string) {
+	if value, ok := os.LookupEnv(name); ok {
+		os.Unsetenv(name)
+		shellVariables[name] = value
+	}
+}
+
+// Удаляет переменную из оболочки и окружения
+func unsetVariable(name string) {
+	delete(shellVariables, name)
+	os.Unsetenv(name)
+}
+
+// Возвращает отсортированные имена всех переменных (локальных и окружения)
+func allVariableNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, entry := range os.Environ() {
+		if name, _, ok := strings.Cut(entry, "="); ok && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for name := range shellVariables {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Выполняет функцию с временно установленными переменными окружения
+func withTemporaryEnvironment(assignments []string, run func()) {
+	type savedValue struct {
+		value  string
+		exists bool
+	}
+	saved := make(map[string]savedValue)
+	for _, assignment := range assignments {
+		name, value, _ := strings.Cut(assignment, "=")
+		if _, done := saved[name]; !done {
+			previous, exists := os.LookupEnv(name)
+			saved[name] = savedValue{previous, exists}
+		}
+		os.Setenv(name, value)
+	}
+
+	defer func() {
+		for name, previous := range saved {
+			if previous.exists {
+				os.Setenv(name, previous.value)
+			} else {
+				os.Unsetenv(name)
+			}
+		}
+	}()
+	run()
+}
+
+// Заключает строку в одинарные кавычки, если она содержит специальные символы
+func shellQuote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n'\"\\$`|&;<>()*?[]#~{}!") {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// Подставляет псевдонимы на месте имен команд
+func expandAliases(tokens []string) ([]string, error) {
+	return expandAliasesExcept(tokens, map[string]bool{})
+}
+
+// Подставляет псевдонимы, пропуская уже раскрытые (защита от рекурсии)
+func expandAliasesExcept(tokens []string, expanding map[string]bool) ([]string, error) {
+	var result []string
+	commandPosition := true
+
+	for _, token := range tokens {
+		if isOperatorToken(token) {
+			result = append(result, token)
+			commandPosition = token == "|" || token == "&&" || token == "||" || token == ";" || token == "&"
+			continue
+		}
+
+		// Присваивания перед командой не меняют позицию имени команды
+		if commandPosition && isAssignmentWord(token) {
+			result = append(result, token)
+			continue
+		}
+
+		value, isAlias := shellAliases[token]
+		if !commandPosition || !isAlias || expanding[token] {
+			result = append(result, token)
+			commandPosition = false
+			continue
+		}
+
+		aliasTokens, err := tokenizeCommandLine(value)
+		if err != nil {
+			return nil, err
+		}
+		expanding[token] = true
+		aliasTokens, err = expandAliasesExcept(aliasTokens, expanding)
+		delete(expanding, token)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, aliasTokens...)
+
+		// Псевдоним, оканчивающийся пробелом, разрешает раскрытие следующего слова
+		commandPosition = strings.HasSuffix(value, " ") || strings.HasSuffix(value, "\t")
+	}
+	return result, nil
+}
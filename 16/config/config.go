@@ -1,10 +1,106 @@
 package config
 
+import (
+	"regexp"
+	"time"
+)
+
+// DefaultUserAgent - значение User-Agent по умолчанию
+const DefaultUserAgent = "Mozilla/5.0 (compatible; MyDownloader/1.0)"
+
 type Config struct {
 	URL      string
 	Output   string
 	MaxDepth int
 	Workers  int
+
+	// Переписывать ссылки в скачанных файлах на локальные после завершения обхода
+	ConvertLinks bool
+
+	// Пауза между запросами к одному хосту и ее случайное варьирование (0.5-1.5 от Wait)
+	Wait       time.Duration
+	RandomWait bool
+
+	// Не загружать и не соблюдать robots.txt
+	NoRobots bool
+
+	// Продолжить прерванный обход по журналу и докачать недокачанные файлы
+	Continue bool
+
+	// Не скачивать повторно файлы, не изменившиеся на сервере (условные запросы)
+	Timestamping bool
+
+	// Область обхода: дополнительные домены (с поддоменами), переход на любые хосты,
+	// разрешенные и запрещенные каталоги, запрет подъема выше каталога начального URL
+	Domains            []string
+	SpanHosts          bool
+	IncludeDirectories []string
+	ExcludeDirectories []string
+	NoParent           bool
+
+	// Фильтры файлов: списки суффиксов или шаблонов имени и регулярные выражения для URL
+	Accept      []string
+	Reject      []string
+	AcceptRegex *regexp.Regexp
+	RejectRegex *regexp.Regexp
+
+	// Скачивать все ресурсы, нужные для отображения страниц (изображения, стили, скрипты)
+	PageRequisites bool
+
+	// Максимальное количество ссылок на страницы, берущихся с одной страницы (0 - без ограничения)
+	MaxLinksPerPage int
+
+	// Количество повторов при сетевых ошибках, ответах 5xx и 429 и начальная пауза между ними
+	Retries      int
+	RetryBackoff time.Duration
+
+	// Записывать запросы и ответы в WARC-файл (с CDX-индексом); при WARCOnly файлы
+	// в выходной каталог не сохраняются
+	WARCFile string
+	WARCOnly bool
+
+	// Заголовки запросов: User-Agent и дополнительные заголовки "Name: value"
+	UserAgent string
+	Headers   []string
+
+	// Авторизация Basic или Bearer (учетные данные отправляются только хосту начального URL)
+	HTTPUser     string
+	HTTPPassword string
+	BearerToken  string
+
+	// Файлы cookies в формате Netscape (cookies.txt) для загрузки и сохранения
+	LoadCookies string
+	SaveCookies string
+
+	// Тело POST-запроса для начального URL (application/x-www-form-urlencoded)
+	PostData string
+
+	// HTTP(S)-прокси; если не задан, используются переменные окружения HTTP_PROXY/HTTPS_PROXY
+	Proxy string
+
+	// Дополнительные корневые сертификаты (PEM) и отключение проверки сертификатов сервера
+	CACertificate      string
+	NoCheckCertificate bool
+
+	// Подробность журнала: только ошибки или с отладочными сообщениями
+	Quiet   bool
+	Verbose bool
+
+	// Показывать индикатор прогресса (включается, когда вывод идет в терминал)
+	Progress bool
+
+	// Дополнительные начальные адреса (список -i); их хосты входят в область обхода
+	Seeds []string
+
+	// Искать карты сайта (robots.txt, /sitemap.xml) и брать из них адреса
+	Sitemaps bool
+
+	// Ограничения (0 - без ограничения): общая скорость загрузки в байтах в секунду,
+	// число соединений с одним хостом, общий объем обхода и размер одного файла
+	LimitRate          int64
+	MaxHostConnections int
+	Quota              int64
+	MaxFileSize        int64
 }
 
 func NewConfig(url, output string, depth, workers int) *Config {
@@ -13,5 +109,10 @@ func NewConfig(url, output string, depth, workers int) *Config {
 		Output:   output,
 		MaxDepth: depth,
 		Workers:  workers,
+
+		UserAgent:       DefaultUserAgent,
+		MaxLinksPerPage: 50,
+		Retries:         3,
+		RetryBackoff:    time.Second,
 	}
 }
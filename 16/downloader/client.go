@@ -0,0 +1,121 @@
+package downloader
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// newClient создает HTTP-клиент с прокси, настройками TLS и хранилищем cookies из конфигурации.
+func (d *Downloader) newClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxConnsPerHost = d.config.MaxHostConnections
+
+	if d.config.Proxy != "" {
+		proxy := d.config.Proxy
+		if !strings.Contains(proxy, "://") {
+			proxy = "http://" + proxy
+		}
+		proxyURL, err := url.Parse(proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("неверный адрес прокси %q", d.config.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if d.config.CACertificate != "" || d.config.NoCheckCertificate {
+		tlsConfig := &tls.Config{InsecureSkipVerify: d.config.NoCheckCertificate}
+		if d.config.CACertificate != "" {
+			pool, err := loadCertificates(d.config.CACertificate)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.RootCAs = pool
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	jar := newCookieJar()
+	if d.config.LoadCookies != "" {
+		if err := jar.Load(d.config.LoadCookies); err != nil {
+			return nil, fmt.Errorf("загрузка cookies: %w", err)
+		}
+	}
+	d.cookies = jar
+
+	return &http.Client{
+		Transport:     transport,
+		Jar:           jar,
+		Timeout:       30 * time.Second,
+		CheckRedirect: d.checkRedirect,
+	}, nil
+}
+
+// loadCertificates добавляет сертификаты из PEM-файла к системным корневым.
+func loadCertificates(path string) (*x509.CertPool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, fmt.Errorf("в %s нет сертификатов PEM", path)
+	}
+	return pool, nil
+}
+
+// newRequest создает запрос с User-Agent, заголовками -header и авторизацией.
+// Для начального URL с -post-data выполняется POST.
+func (d *Downloader) newRequest(ctx context.Context, rawURL string, task *Task) (*http.Request, error) {
+	method := http.MethodGet
+	var body *strings.Reader
+	if d.config.PostData != "" && task != nil && task.URL == d.config.URL && task.Depth == 0 {
+		method = http.MethodPost
+		body = strings.NewReader(d.config.PostData)
+	}
+
+	var req *http.Request
+	var err error
+	if body != nil {
+		req, err = http.NewRequestWithContext(ctx, method, rawURL, body)
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		req, err = http.NewRequestWithContext(ctx, method, rawURL, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", d.config.UserAgent)
+	for _, header := range d.config.Headers {
+		name, value, _ := strings.Cut(header, ":")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+
+	// Учетные данные отправляются только хосту начального URL
+	if d.seed != nil && strings.EqualFold(req.URL.Host, d.seed.Host) {
+		switch {
+		case d.config.BearerToken != "":
+			req.Header.Set("Authorization", "Bearer "+d.config.BearerToken)
+		case d.config.HTTPUser != "":
+			req.SetBasicAuth(d.config.HTTPUser, d.config.HTTPPassword)
+		}
+	}
+	return req, nil
+}
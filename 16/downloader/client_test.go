@@ -0,0 +1,194 @@
+package downloader
+
+import (
+	"context"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ds124wfegd/WB_L2/16/config"
+)
+
+func TestRequestHeadersAndAuth(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string]*http.Request)
+	bodies := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received[r.URL.Path] = r
+		bodies[r.URL.Path] = string(body)
+		mu.Unlock()
+
+		if r.URL.Path == "/" {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<a href="/next.html">next</a>`))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	cfg := config.NewConfig(server.URL+"/", t.TempDir(), 1, 1)
+	cfg.NoRobots = true
+	cfg.UserAgent = "TestAgent/2.0"
+	cfg.Headers = []string{"X-Test: yes", "Accept-Language: ru"}
+	cfg.HTTPUser = "user"
+	cfg.HTTPPassword = "secret"
+	cfg.PostData = "a=1&b=2"
+	if err := newDownloader(t, cfg).Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	seed, next := received["/"], received["/next.html"]
+	if seed == nil || next == nil {
+		t.Fatalf("requests not received: %v", received)
+	}
+	if seed.Method != http.MethodPost || bodies["/"] != "a=1&b=2" {
+		t.Errorf("seed request = %s %q, expected POST with post data", seed.Method, bodies["/"])
+	}
+	if next.Method != http.MethodGet {
+		t.Errorf("linked page requested with %s, expected GET", next.Method)
+	}
+	for _, req := range []*http.Request{seed, next} {
+		if req.UserAgent() != "TestAgent/2.0" || req.Header.Get("X-Test") != "yes" || req.Header.Get("Accept-Language") != "ru" {
+			t.Errorf("%s: unexpected headers %v", req.URL.Path, req.Header)
+		}
+		if user, password, ok := req.BasicAuth(); !ok || user != "user" || password != "secret" {
+			t.Errorf("%s: basic auth = %q/%q, %v", req.URL.Path, user, password, ok)
+		}
+	}
+}
+
+func TestAuthorizationOnlyForSeedHost(t *testing.T) {
+	cfg := config.NewConfig("http://example.com/", t.TempDir(), 0, 1)
+	cfg.BearerToken = "token"
+	d := newDownloader(t, cfg)
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"http://example.com/page", "Bearer token"},
+		{"http://other.com/page", ""},
+	}
+	for _, test := range tests {
+		req, err := d.newRequest(context.Background(), test.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := req.Header.Get("Authorization"); got != test.expected {
+			t.Errorf("%s: Authorization = %q, expected %q", test.url, got, test.expected)
+		}
+	}
+}
+
+func TestAuthorizationAfterSeedRedirect(t *testing.T) {
+	var mu sync.Mutex
+	authorization := make(map[string]string)
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		authorization[r.URL.Path] = r.Header.Get("Authorization")
+		mu.Unlock()
+		if r.URL.Path == "/" {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<a href="/next.html">next</a>`))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer other.Close()
+	seed := httptest.NewServer(http.RedirectHandler(other.URL+"/", http.StatusFound))
+	defer seed.Close()
+
+	cfg := config.NewConfig(seed.URL+"/", t.TempDir(), 1, 1)
+	cfg.NoRobots = true
+	cfg.BearerToken = "token"
+	cfg.Quiet = true
+	if err := newDownloader(t, cfg).Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Область обхода переносится на конечный хост, а учетные данные ему не отправляются
+	if _, ok := authorization["/next.html"]; !ok {
+		t.Fatalf("link on redirected seed not followed: %v", authorization)
+	}
+	for path, header := range authorization {
+		if header != "" {
+			t.Errorf("%s: Authorization = %q sent to redirect target host", path, header)
+		}
+	}
+}
+
+func TestProxy(t *testing.T) {
+	var requested string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.String()
+		w.Write([]byte("from proxy"))
+	}))
+	defer proxy.Close()
+
+	cfg := config.NewConfig("http://site.invalid/file.txt", t.TempDir(), 0, 1)
+	cfg.NoRobots = true
+	cfg.Proxy = strings.TrimPrefix(proxy.URL, "http://")
+	d := newDownloader(t, cfg)
+
+	if err := d.download(context.Background(), &Task{URL: cfg.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if requested != cfg.URL {
+		t.Errorf("proxy received %q, expected %q", requested, cfg.URL)
+	}
+	content, _ := os.ReadFile(filepath.Join(cfg.Output, "site.invalid", "file.txt"))
+	if string(content) != "from proxy" {
+		t.Errorf("file content = %q", content)
+	}
+}
+
+func TestCertificates(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secure"))
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certificate, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		configure func(cfg *config.Config)
+		success   bool
+	}{
+		{"untrusted", nil, false},
+		{"no check", func(cfg *config.Config) { cfg.NoCheckCertificate = true }, true},
+		{"custom CA", func(cfg *config.Config) { cfg.CACertificate = caFile }, true},
+	}
+
+	for _, test := range tests {
+		cfg := config.NewConfig(server.URL+"/", t.TempDir(), 0, 1)
+		cfg.NoRobots = true
+		cfg.Retries = 0
+		if test.configure != nil {
+			test.configure(cfg)
+		}
+		err := newDownloader(t, cfg).download(context.Background(), &Task{URL: cfg.URL})
+		if (err == nil) != test.success {
+			t.Errorf("%s: download error = %v, expected success %v", test.name, err, test.success)
+		}
+	}
+
+	cfg := config.NewConfig(server.URL+"/", t.TempDir(), 0, 1)
+	cfg.CACertificate = filepath.Join(t.TempDir(), "missing.pem")
+	if _, err := NewDownloader(cfg); err == nil {
+		t.Error("NewDownloader with missing CA file succeeded")
+	}
+}
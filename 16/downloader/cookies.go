@@ -0,0 +1,256 @@
+package downloader
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+const httpOnlyPrefix = "#HttpOnly_"
+
+// Cookie в хранилище
+type cookieEntry struct {
+	domain   string // без ведущей точки
+	hostOnly bool   // только для этого хоста, без поддоменов
+	path     string
+	secure   bool
+	httpOnly bool
+	expires  time.Time // нулевое значение - cookie сессии
+	name     string
+	value    string
+}
+
+func (c *cookieEntry) key() string {
+	return c.domain + ";" + c.path + ";" + c.name
+}
+
+func (c *cookieEntry) expired(now time.Time) bool {
+	return !c.expires.IsZero() && !c.expires.After(now)
+}
+
+// cookieJar - хранилище cookies (http.CookieJar), которое загружается из файла
+// cookies.txt в формате Netscape и сохраняется в него
+type cookieJar struct {
+	mu      sync.Mutex
+	entries map[string]*cookieEntry
+}
+
+func newCookieJar() *cookieJar {
+	return &cookieJar{entries: make(map[string]*cookieEntry)}
+}
+
+// SetCookies сохраняет cookies из ответа на запрос u. Cookies для чужих доменов отбрасываются.
+func (j *cookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	host := strings.ToLower(u.Hostname())
+	now := time.Now()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, cookie := range cookies {
+		entry := &cookieEntry{
+			domain:   strings.TrimPrefix(strings.ToLower(cookie.Domain), "."),
+			path:     cookie.Path,
+			secure:   cookie.Secure,
+			httpOnly: cookie.HttpOnly,
+			name:     cookie.Name,
+			value:    cookie.Value,
+		}
+		if entry.domain == "" {
+			entry.domain = host
+			entry.hostOnly = true
+		} else if !domainMatch(host, entry.domain) {
+			continue
+		} else if isPublicSuffix(entry.domain) {
+			// Cookie на публичный суффикс (com, co.uk) ушла бы всем его сайтам:
+			// допустима только как cookie самого хоста
+			if entry.domain != host {
+				continue
+			}
+			entry.hostOnly = true
+		}
+		if !strings.HasPrefix(entry.path, "/") {
+			entry.path = defaultCookiePath(u.Path)
+		}
+
+		switch {
+		case cookie.MaxAge < 0:
+			entry.expires = now
+		case cookie.MaxAge > 0:
+			entry.expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		case !cookie.Expires.IsZero():
+			entry.expires = cookie.Expires
+		}
+
+		if entry.expired(now) {
+			delete(j.entries, entry.key())
+			continue
+		}
+		j.entries[entry.key()] = entry
+	}
+}
+
+// Проверяет, является ли домен публичным суффиксом; одиночная метка без точки
+// (com, local) тоже считается суффиксом
+func isPublicSuffix(domain string) bool {
+	suffix, _ := publicsuffix.PublicSuffix(domain)
+	return suffix == domain
+}
+
+// Cookies возвращает cookies для запроса u: более длинные пути идут первыми.
+func (j *cookieJar) Cookies(u *url.URL) []*http.Cookie {
+	host := strings.ToLower(u.Hostname())
+	requestPath := u.EscapedPath()
+	if requestPath == "" {
+		requestPath = "/"
+	}
+	now := time.Now()
+
+	j.mu.Lock()
+	var matched []*cookieEntry
+	for key, entry := range j.entries {
+		if entry.expired(now) {
+			delete(j.entries, key)
+			continue
+		}
+		if entry.hostOnly && host != entry.domain || !entry.hostOnly && !domainMatch(host, entry.domain) {
+			continue
+		}
+		if !pathMatch(requestPath, entry.path) || entry.secure && u.Scheme != "https" {
+			continue
+		}
+		matched = append(matched, entry)
+	}
+	j.mu.Unlock()
+
+	sort.Slice(matched, func(a, b int) bool {
+		if len(matched[a].path) != len(matched[b].path) {
+			return len(matched[a].path) > len(matched[b].path)
+		}
+		return matched[a].name < matched[b].name
+	})
+
+	cookies := make([]*http.Cookie, 0, len(matched))
+	for _, entry := range matched {
+		cookies = append(cookies, &http.Cookie{Name: entry.name, Value: entry.value})
+	}
+	return cookies
+}
+
+// Load читает cookies.txt в формате Netscape:
+// domain, includeSubdomains, path, secure, expires, name, value через табуляцию.
+func (j *cookieJar) Load(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	now := time.Now()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	scanner := bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+		line = strings.TrimPrefix(line, httpOnlyPrefix)
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return fmt.Errorf("%s:%d: ожидалось 7 полей, получено %d", filename, number, len(fields))
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s:%d: неверное время истечения %q", filename, number, fields[4])
+		}
+
+		entry := &cookieEntry{
+			domain:   strings.TrimPrefix(strings.ToLower(fields[0]), "."),
+			hostOnly: !strings.EqualFold(fields[1], "TRUE"),
+			path:     fields[2],
+			secure:   strings.EqualFold(fields[3], "TRUE"),
+			httpOnly: httpOnly,
+			name:     fields[5],
+			value:    fields[6],
+		}
+		if expires > 0 {
+			entry.expires = time.Unix(expires, 0)
+		}
+		if !entry.expired(now) {
+			j.entries[entry.key()] = entry
+		}
+	}
+	return scanner.Err()
+}
+
+// Save записывает неистекшие cookies в формате Netscape; cookies сессии сохраняются с временем 0.
+func (j *cookieJar) Save(filename string) error {
+	now := time.Now()
+
+	j.mu.Lock()
+	entries := make([]*cookieEntry, 0, len(j.entries))
+	for _, entry := range j.entries {
+		if !entry.expired(now) {
+			entries = append(entries, entry)
+		}
+	}
+	j.mu.Unlock()
+
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].key() < entries[b].key()
+	})
+
+	var builder strings.Builder
+	builder.WriteString("# Netscape HTTP Cookie File\n")
+	for _, entry := range entries {
+		domain, subdomains := entry.domain, "FALSE"
+		if !entry.hostOnly {
+			domain, subdomains = "."+domain, "TRUE"
+		}
+		if entry.httpOnly {
+			domain = httpOnlyPrefix + domain
+		}
+		var expires int64
+		if !entry.expires.IsZero() {
+			expires = entry.expires.Unix()
+		}
+		fmt.Fprintf(&builder, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, subdomains, entry.path, strings.ToUpper(strconv.FormatBool(entry.secure)), expires, entry.name, entry.value)
+	}
+	return os.WriteFile(filename, []byte(builder.String()), 0600)
+}
+
+// domainMatch сообщает, совпадает ли хост с доменом cookie или является его поддоменом.
+func domainMatch(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// pathMatch проверяет соответствие пути запроса пути cookie (RFC 6265, 5.1.4).
+func pathMatch(requestPath, cookiePath string) bool {
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return len(requestPath) == len(cookiePath) || strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/'
+}
+
+// defaultCookiePath - каталог пути запроса (RFC 6265, 5.1.4).
+func defaultCookiePath(requestPath string) string {
+	if !strings.HasPrefix(requestPath, "/") || strings.Count(requestPath, "/") == 1 {
+		return "/"
+	}
+	return path.Dir(requestPath)
+}
@@ -0,0 +1,111 @@
+package downloader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ds124wfegd/WB_L2/16/config"
+)
+
+func TestCookieJarMatching(t *testing.T) {
+	jar := newCookieJar()
+	origin, _ := url.Parse("http://www.example.com/docs/page.html")
+	jar.SetCookies(origin, []*http.Cookie{
+		{Name: "host", Value: "1"},
+		{Name: "domain", Value: "2", Domain: ".example.com", Path: "/"},
+		{Name: "secure", Value: "3", Path: "/", Secure: true},
+		{Name: "foreign", Value: "4", Domain: "other.com"},
+		{Name: "expired", Value: "5", MaxAge: -1},
+		{Name: "suffix", Value: "6", Domain: ".com"},
+	})
+	// Хост на публичном суффиксе второго уровня не может ставить cookie на весь суффикс
+	shop, _ := url.Parse("http://shop.example.co.uk/")
+	jar.SetCookies(shop, []*http.Cookie{
+		{Name: "uk", Value: "7", Domain: "co.uk"},
+		{Name: "shop", Value: "8", Domain: "example.co.uk"},
+	})
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"http://www.example.com/docs/other.html", "host=1 domain=2"},
+		{"https://www.example.com/", "domain=2 secure=3"},
+		{"http://api.example.com/docs/", "domain=2"},
+		{"http://www.example.com/documents", "domain=2"},
+		{"http://other.com/", ""},
+		{"http://www.example.co.uk/", "shop=8"},
+		{"http://other.co.uk/", ""},
+	}
+	for _, test := range tests {
+		target, _ := url.Parse(test.url)
+		var names []string
+		for _, cookie := range jar.Cookies(target) {
+			names = append(names, cookie.Name+"="+cookie.Value)
+		}
+		if got := strings.Join(names, " "); got != test.expected {
+			t.Errorf("Cookies(%s) = %q, expected %q", test.url, got, test.expected)
+		}
+	}
+}
+
+func TestCookiesLoadAndSave(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "loaded" {
+			http.Error(w, "no session", http.StatusForbidden)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "visited", Value: "yes", Path: "/", HttpOnly: true, MaxAge: 3600})
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	host := strings.Split(strings.TrimPrefix(server.URL, "http://"), ":")[0]
+	directory := t.TempDir()
+	loadFile := filepath.Join(directory, "in.txt")
+	saveFile := filepath.Join(directory, "out.txt")
+	cookies := "# Netscape HTTP Cookie File\n" +
+		host + "\tFALSE\t/\tFALSE\t0\tsession\tloaded\n" +
+		host + "\tFALSE\t/\tFALSE\t1\told\texpired\n"
+	if err := os.WriteFile(loadFile, []byte(cookies), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.NewConfig(server.URL+"/", t.TempDir(), 0, 1)
+	cfg.NoRobots = true
+	cfg.LoadCookies = loadFile
+	cfg.SaveCookies = saveFile
+	d := newDownloader(t, cfg)
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !downloaded(d, server, "index.html") {
+		t.Fatal("page with loaded session cookie was not downloaded")
+	}
+
+	saved, err := os.ReadFile(saveFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(saved), "\n"), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], host+"\tFALSE\t/\tFALSE\t0\tsession\tloaded") {
+		t.Fatalf("unexpected cookies file:\n%s", saved)
+	}
+	fields := strings.Split(lines[2], "\t")
+	if fields[0] != httpOnlyPrefix+host || fields[5] != "visited" || expiresUnix(fields[4]) <= time.Now().Unix() {
+		t.Errorf("unexpected saved cookie %q", lines[2])
+	}
+}
+
+func expiresUnix(value string) int64 {
+	expires, _ := strconv.ParseInt(value, 10, 64)
+	return expires
+}
@@ -1,12 +1,14 @@
 package downloader
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -14,237 +16,718 @@ import (
 
 	"github.com/ds124wfegd/WB_L2/16/config"
 	"github.com/ds124wfegd/WB_L2/16/parser"
+	"github.com/ds124wfegd/WB_L2/16/robots"
 )
 
 type Downloader struct {
-	config  *config.Config
-	client  *http.Client
-	visited sync.Map
-	queue   chan *Task
-	wg      sync.WaitGroup
-	mu      sync.Mutex
-	closed  bool
+	config    *config.Config
+	client    *http.Client
+	visited   sync.Map
+	files     sync.Map          // URL -> downloadedFile
+	redirects sync.Map          // исходный URL -> URL, на который он перенаправлен
+	names     map[string]string // занятые имена файлов -> URL
+	namesMu   sync.Mutex
+	frontier  *frontier
+	seed      *url.URL        // начальный URL: учетные данные отправляются только его хосту
+	scopeSeed *url.URL        // начальный URL после перенаправлений: от него отсчитывается область обхода
+	scopeMu   sync.RWMutex    // scopeSeed меняется из рабочей горутины
+	seeds     map[string]bool // начальные адреса (URL и список -i)
+	seedHosts map[string]bool
+	wg        sync.WaitGroup
+	robots    map[string]*robotsEntry // scheme://host -> robots.txt
+	robotsMu  sync.Mutex
+	limiter   *hostLimiter
+	journal   *journal
+	previous  map[string]downloadedFile // файлы прошлого запуска (для -timestamping)
+	partial   map[string]downloadedFile // недокачанные файлы прерванного обхода (для -continue)
+	storage   Storage                   // скачанные файлы: выходной каталог или хранилище из WithStorage
+	hooks     hooks
+	warc      *warcTransport
+	cookies   *cookieJar
+	log       *logger
+	stats     *stats
+	bandwidth *tokenBucket // общее ограничение скорости (-limit-rate)
+	quotaOnce sync.Once
 }
 
-func NewDownloader(cfg *config.Config) *Downloader {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+// Сохраненный файл: путь относительно выходного каталога, тип содержимого
+// и валидаторы для условных запросов
+type downloadedFile struct {
+	filename     string
+	contentType  string
+	etag         string
+	lastModified string
+}
+
+// NewDownloader создает загрузчик и его HTTP-клиент (прокси, TLS, cookies).
+// Файлы сохраняются в каталог cfg.Output.
+func NewDownloader(cfg *config.Config) (*Downloader, error) {
+	return newFromOptions(&options{config: cfg})
+}
+
+func newFromOptions(o *options) (*Downloader, error) {
+	cfg := o.config
+	seed, _ := url.Parse(cfg.URL)
+
+	d := &Downloader{
+		config:    cfg,
+		seed:      seed,
+		scopeSeed: seed,
+		frontier:  newFrontier(),
+		robots:    make(map[string]*robotsEntry),
+		limiter:   newHostLimiter(),
+		storage:   o.storage,
+		hooks:     o.hooks,
+		stats:     newStats(),
+	}
+	if d.storage == nil {
+		d.storage = NewFileStorage(cfg.Output)
+	}
+
+	d.seeds = make(map[string]bool)
+	d.seedHosts = make(map[string]bool)
+	for _, seed := range d.seedURLs() {
+		d.seeds[seed] = true
+		if parsed, err := url.Parse(seed); err == nil {
+			d.seedHosts[parsed.Host] = true
+		}
+	}
+
+	level := levelInfo
+	switch {
+	case cfg.Quiet:
+		level = levelError
+	case cfg.Verbose:
+		level = levelDebug
+	}
+	logOutput := o.logOutput
+	if logOutput == nil {
+		logOutput = os.Stderr
+	}
+	d.log = newLogger(logOutput, level)
+
+	if cfg.LimitRate > 0 {
+		d.bandwidth = newTokenBucket(cfg.LimitRate)
 	}
 
-	return &Downloader{
-		config: cfg,
-		client: client,
-		queue:  make(chan *Task, 1000),
+	client, err := d.newClient()
+	if err != nil {
+		return nil, err
 	}
+	d.client = client
+	return d, nil
 }
 
-func (d *Downloader) Start() error {
-	if err := os.MkdirAll(d.config.Output, 0755); err != nil {
-		return err
+// Start выполняет обход и возвращается, когда все найденные ссылки обработаны.
+// При отмене ctx загрузки прерываются, недокачанные файлы удаляются, а Start возвращает ошибку ctx.
+func (d *Downloader) Start(ctx context.Context) error {
+	d.stats.started = time.Now()
+	if d.config.Progress {
+		progress := newProgress(os.Stderr, d.stats, d.frontier.Len, d.config.Workers)
+		logOutput := d.log.out.Writer()
+		d.log.out.SetOutput(progress)
+		go progress.Run()
+		defer func() {
+			progress.Stop()
+			d.log.out.SetOutput(logOutput)
+		}()
 	}
 
-	for i := 0; i < d.config.Workers; i++ {
-		d.wg.Add(1)
-		go d.worker(i)
+	// Без выходного каталога (хранилище из WithStorage) журнал не ведется
+	var journalPath string
+	if d.config.Output != "" {
+		if err := os.MkdirAll(d.config.Output, 0755); err != nil {
+			return err
+		}
+		journalPath = filepath.Join(d.config.Output, journalName)
 	}
 
-	d.addTask(&Task{URL: d.config.URL, Depth: 0})
+	if d.config.WARCFile != "" {
+		if err := d.openWARC(); err != nil {
+			return fmt.Errorf("создание WARC: %w", err)
+		}
+		defer d.closeWARC()
 
-	go d.monitorCompletion()
+		// Файлы нужны только для разбора ссылок и после обхода удаляются
+		if d.config.WARCOnly {
+			root, err := os.MkdirTemp("", "warc-files-*")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(root)
+			d.storage = NewFileStorage(root)
+		}
+	}
 
-	d.wg.Wait()
+	state := newCrawlState()
+	if journalPath != "" {
+		var err error
+		if state, err = loadJournal(journalPath); err != nil {
+			return fmt.Errorf("чтение журнала: %w", err)
+		}
+	}
+	d.previous = state.completed
 
-	return nil
-}
+	var tasks []*Task
+	for _, seed := range d.seedURLs() {
+		tasks = append(tasks, &Task{URL: seed, Depth: 0})
+	}
+	if d.config.Continue {
+		d.restore(state)
+		if _, done := state.completed[d.config.URL]; done || len(state.pending) > 0 {
+			tasks = state.pending
+		}
+	}
 
-func (d *Downloader) worker(id int) {
-	defer d.wg.Done()
+	if journalPath != "" {
+		var err error
+		if d.journal, err = openJournal(journalPath, d.config.Continue, d.log); err != nil {
+			return err
+		}
+		defer d.journal.Close()
+	}
+
+	// Начальные задачи ставятся до запуска воркеров: пустая очередь без задач в работе означает конец обхода
+	for _, task := range tasks {
+		d.addTask(task)
+	}
+	if d.config.Sitemaps {
+		d.discoverSitemaps(ctx)
+	}
 
-	for task := range d.queue {
-		log.Printf("Воркер %d: %s (глубина рекурсии %d)", id, task.URL, task.Depth)
+	stop := context.AfterFunc(ctx, d.frontier.Close)
+	defer stop()
 
-		if err := d.download(task); err != nil {
-			log.Printf("Ошибка скачивания %s: %v", task.URL, err)
+	for i := 0; i < d.config.Workers; i++ {
+		d.wg.Add(1)
+		go d.worker(ctx, i)
+	}
+
+	d.wg.Wait()
+
+	if d.config.SaveCookies != "" {
+		if err := d.cookies.Save(d.config.SaveCookies); err != nil {
+			d.log.errorf("Ошибка сохранения cookies: %v", err)
 		}
+	}
 
-		time.Sleep(100 * time.Millisecond)
+	if d.config.WARCOnly {
+		return ctx.Err()
 	}
-	log.Printf("Воркер %d закончил работу", id)
-}
 
-func (d *Downloader) addTask(task *Task) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
+	if err := d.writeManifest(); err != nil {
+		d.log.errorf("Ошибка записи %s: %v", manifestName, err)
+	}
 
-	if d.closed {
-		return
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	select {
-	case d.queue <- task:
-	default:
-		log.Printf("Очередь заполнена: %s", task.URL)
+	if d.config.ConvertLinks {
+		d.convertLinks()
 	}
-}
 
-func (d *Downloader) monitorCompletion() {
-	time.Sleep(2 * time.Second)
+	return nil
+}
 
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+// Summary возвращает итоги обхода: число и объем файлов, ошибки по кодам ответа,
+// самые долгие загрузки и объем по типам содержимого.
+func (d *Downloader) Summary() Summary {
+	return d.stats.summary()
+}
 
-	inactiveCount := 0
-	lastActivity := time.Now()
+// restore восстанавливает обработанные ссылки и недокачанные файлы прерванного обхода.
+func (d *Downloader) restore(state *crawlState) {
+	for rawURL, target := range state.redirects {
+		d.redirects.Store(rawURL, target)
+	}
+	for rawURL, file := range state.completed {
+		d.visited.Store(rawURL, true)
+		// Файл перенаправленной ссылки учтен под конечным адресом
+		if _, redirected := state.redirects[rawURL]; file.filename != "" && !redirected {
+			d.files.Store(rawURL, file)
+			d.reserveFilename(rawURL, file.filename)
+		}
+	}
+	d.partial = state.started
 
-	for range ticker.C {
-		queueLen := len(d.queue)
+	d.log.infof("Продолжение обхода: обработано %d, в очереди %d, недокачано %d",
+		len(state.completed), len(state.pending), len(state.started))
+}
 
-		if queueLen == 0 {
-			inactiveCount++
+func (d *Downloader) worker(ctx context.Context, id int) {
+	defer d.wg.Done()
 
-			if inactiveCount >= 5 || time.Since(lastActivity) > 30*time.Second {
-				d.mu.Lock()
-				if !d.closed {
-					d.closed = true
-					close(d.queue)
-					log.Println("Очередь закрыта")
-				}
-				d.mu.Unlock()
-				return
+	for {
+		task, ok := d.frontier.Pop()
+		if !ok {
+			break
+		}
+		d.log.debugf("Воркер %d: %s (глубина рекурсии %d)", id, task.URL, task.Depth)
+
+		d.stats.active.Add(1)
+		if err := d.download(ctx, task); err != nil && ctx.Err() == nil {
+			d.log.errorf("Ошибка скачивания %s: %v", task.URL, err)
+			d.stats.failed(err)
+			if d.hooks.onError != nil {
+				d.hooks.onError(task.URL, err)
 			}
-		} else {
-			inactiveCount = 0
-			lastActivity = time.Now()
 		}
+		d.stats.active.Add(-1)
+		d.checkQuota()
+		d.frontier.Done()
 	}
+	d.log.debugf("Воркер %d закончил работу", id)
 }
 
-func (d *Downloader) download(task *Task) error {
+func (d *Downloader) addTask(task *Task) {
+	// После остановки по квоте ссылки только записываются в журнал
+	if d.frontier.Push(task) || d.stats.quotaExceeded.Load() {
+		d.journal.Queued(task)
+	}
+}
+
+func (d *Downloader) download(ctx context.Context, task *Task) error {
 
 	if _, visited := d.visited.Load(task.URL); visited {
 		return nil
 	}
 	d.visited.Store(task.URL, true)
 
-	req, err := http.NewRequest("GET", task.URL, nil)
+	target, err := url.Parse(task.URL)
+	if err != nil {
+		return err
+	}
+
+	var rules *robots.Rules
+	if !d.config.NoRobots {
+		rules = d.robotsFor(ctx, target)
+		if !rules.Allowed(target.RequestURI()) {
+			d.log.infof("Запрещено robots.txt: %s", task.URL)
+			d.journal.Done(task.URL, downloadedFile{})
+			return nil
+		}
+	}
+	if err := d.limiter.Wait(ctx, target.Host, d.requestInterval(rules)); err != nil {
+		return err
+	}
+
+	req, err := d.newRequest(ctx, task.URL, task)
 	if err != nil {
 		return err
 	}
-	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; MyDownloader/1.0)")
+	if d.hooks.onRequest != nil {
+		if err := d.hooks.onRequest(req); err != nil {
+			return d.skipped(task, err)
+		}
+	}
 
-	resp, err := d.client.Do(req)
+	// Недокачанный файл запрашиваем с места остановки, уже скачанный - условным запросом
+	partial, offset := d.partialDownload(task.URL)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if validator := rangeValidator(partial); validator != "" {
+			req.Header.Set("If-Range", validator)
+		}
+	}
+	previous, conditional := d.previous[task.URL]
+	conditional = conditional && offset == 0 && d.config.Timestamping && d.setConditionalHeaders(req, previous)
+
+	started := time.Now()
+	resp, redirects, err := d.fetch(ctx, req, task)
+	if errors.Is(err, errRedirectOutOfScope) {
+		d.log.infof("Пропущено: %v", err)
+		d.journal.Done(task.URL, downloadedFile{})
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	// Файл сохраняется под конечным адресом цепочки перенаправлений
+	pageURL := task.URL
+	if len(redirects) > 0 {
+		pageURL = redirects[len(redirects)-1]
+		d.log.infof("Перенаправление: %s -> %s", task.URL, strings.Join(redirects, " -> "))
+		d.redirects.Store(task.URL, pageURL)
+		d.journal.Redirected(task.URL, pageURL)
+		if task.URL == d.config.URL {
+			if scopeSeed, err := url.Parse(pageURL); err == nil {
+				d.scopeMu.Lock()
+				d.scopeSeed = scopeSeed
+				d.scopeMu.Unlock()
+			}
+		}
+
+		if _, visited := d.visited.LoadOrStore(pageURL, true); visited {
+			d.log.debugf("Уже обработан: %s", pageURL)
+			d.journal.Done(task.URL, downloadedFile{})
+			return nil
+		}
 	}
 
-	filename := d.getFilename(task.URL, resp.Header.Get("Content-Type"))
-	fullPath := filepath.Join(d.config.Output, filename)
+	if d.hooks.onResponse != nil {
+		if err := d.hooks.onResponse(resp); err != nil {
+			d.discardBody(resp, "unspecified")
+			return d.skipped(task, err)
+		}
+	}
+
+	var record downloadedFile
+	switch {
+	case resp.StatusCode == http.StatusNotModified && conditional:
+		record = previous
+		d.stats.fileNotModified()
+		d.log.infof("Не изменился: %s", record.filename)
+
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		record = partial
+		d.log.infof("Уже скачан полностью: %s", record.filename)
+
+	case resp.StatusCode == http.StatusOK || (resp.StatusCode == http.StatusPartialContent && offset > 0):
+		var size int64
+		record, size, err = d.saveResponse(ctx, task.URL, pageURL, resp, partial, offset)
+		if errors.Is(err, errFileTooLarge) {
+			d.discardBody(resp, "length")
+			d.log.infof("Пропущен файл больше %s: %s", formatBytes(d.config.MaxFileSize), pageURL)
+			d.stats.fileSkipped()
+			d.journal.Done(task.URL, downloadedFile{})
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		d.stats.fileSaved(pageURL, record.contentType, size, time.Since(started))
+
+	default:
+		// Карта сайта по умолчанию (/sitemap.xml) есть не у всех сайтов
+		if task.Kind == parser.KindSitemap && resp.StatusCode == http.StatusNotFound {
+			d.log.infof("Карта сайта не найдена: %s", task.URL)
+			d.journal.Done(task.URL, downloadedFile{})
+			return nil
+		}
+		return &statusError{code: resp.StatusCode}
+	}
+
+	// Закрываем тело сразу, чтобы ответ попал в WARC раньше записи metadata
+	resp.Body.Close()
+
+	contentType := record.contentType
+	d.files.Store(pageURL, record)
 
-	dir := filepath.Dir(fullPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	// Относительные ссылки страницы разрешаются от ее конечного адреса
+	page := &Task{URL: pageURL, Depth: task.Depth, Requisite: task.Requisite, Kind: task.Kind}
+	var links []parser.Link
+	switch {
+	case task.Kind == parser.KindSitemap:
+		if links, err = d.parseSitemap(record.filename, page); err != nil {
+			d.log.errorf("Ошибка разбора карты сайта %s: %v", pageURL, err)
+		}
+	case task.Kind == parser.KindFeed || parser.IsFeedType(contentType):
+		if links, err = d.parseFeed(record.filename, page); err != nil {
+			d.log.errorf("Ошибка разбора ленты %s: %v", pageURL, err)
+		}
+	// Реквизиты не разбираются как страницы, чтобы не уходить по их ссылкам дальше
+	case strings.Contains(contentType, "text/html") && !task.Requisite:
+		if links, err = d.parseHTML(record.filename, page); err != nil {
+			d.log.errorf("Ошибка парсинга HTML: %v", err)
+		}
+	case isStylesheet(pageURL, contentType):
+		if links, err = d.parseCSS(record.filename, page); err != nil {
+			d.log.errorf("Ошибка парсинга CSS: %v", err)
+		}
+	}
+	d.archiveMetadata(task, pageURL, links)
+
+	// Отмечаем ссылку обработанной после постановки в очередь найденных в ней ссылок,
+	// чтобы при продолжении обхода они не потерялись
+	if pageURL != task.URL {
+		d.journal.Done(pageURL, record)
+	}
+	d.journal.Done(task.URL, record)
+	return nil
+}
+
+// skipped завершает обработку адреса, пропущенного обработчиком OnRequest или OnResponse (ErrSkip).
+func (d *Downloader) skipped(task *Task, err error) error {
+	if !errors.Is(err, ErrSkip) {
 		return err
 	}
+	d.log.debugf("Пропущено обработчиком: %s", task.URL)
+	d.journal.Done(task.URL, downloadedFile{})
+	return nil
+}
 
-	file, err := os.Create(fullPath)
+// saveResponse сохраняет тело ответа в файл, имя которого строится по конечному адресу pageURL;
+// ответ 206 дописывается к недокачанному файлу. Возвращает число записанных байтов.
+// При отмене ctx недокачанный файл удаляется.
+func (d *Downloader) saveResponse(ctx context.Context, rawURL, pageURL string, resp *http.Response, partial downloadedFile, offset int64) (downloadedFile, int64, error) {
+	contentType := resp.Header.Get("Content-Type")
+	record := downloadedFile{
+		filename:     d.getFilename(pageURL, contentType, resp.Header.Get("Content-Disposition")),
+		contentType:  contentType,
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}
+
+	// Размер известен заранее - большой файл не начинаем скачивать
+	if d.config.MaxFileSize > 0 && resp.ContentLength >= 0 && offset+resp.ContentLength > d.config.MaxFileSize {
+		return record, 0, errFileTooLarge
+	}
+
+	create := d.storage.Create
+	if resp.StatusCode == http.StatusPartialContent {
+		if start := contentRangeStart(resp.Header.Get("Content-Range")); start != offset {
+			return record, 0, fmt.Errorf("неожиданный Content-Range %q (ожидалось начало %d)", resp.Header.Get("Content-Range"), offset)
+		}
+		record.filename = partial.filename
+		create = d.storage.Append
+	}
+
+	file, err := create(record.filename)
 	if err != nil {
-		return err
+		return record, 0, err
 	}
 	defer file.Close()
 
-	_, err = io.Copy(file, resp.Body)
+	total := resp.ContentLength
+	if total >= 0 {
+		total += offset
+	}
+	transfer := d.stats.startTransfer(record.filename, total)
+	transfer.done.Store(offset)
+	defer d.stats.finishTransfer(transfer)
+
+	d.journal.Started(rawURL, record)
+	body := d.limitReader(ctx, resp.Body, offset)
+	written, err := io.Copy(file, &transferReader{reader: body, transfer: transfer, stats: d.stats})
 	if err != nil {
-		return err
+		if errors.Is(err, errFileTooLarge) {
+			file.Close()
+			d.storage.Remove(record.filename)
+		} else if ctx.Err() != nil {
+			file.Close()
+			d.storage.Remove(record.filename)
+			d.log.infof("Удален недокачанный файл: %s", record.filename)
+		}
+		return record, 0, err
 	}
 
-	log.Printf("Скачано: %s", filename)
+	if err := file.Close(); err != nil {
+		return record, 0, err
+	}
 
-	contentType := resp.Header.Get("Content-Type")
-	if strings.Contains(contentType, "text/html") && task.Depth < d.config.MaxDepth {
-		if err := d.parseHTML(fullPath, task.URL, task.Depth+1); err != nil {
-			log.Printf("Ошибка парсинга HTML: %v", err)
+	// Как wget -N: время изменения файла совпадает с Last-Modified сервера
+	if storage, ok := d.storage.(timestamper); ok && d.config.Timestamping {
+		if modified, err := http.ParseTime(record.lastModified); err == nil {
+			storage.Chtimes(record.filename, modified)
 		}
 	}
 
-	return nil
+	if resp.StatusCode == http.StatusPartialContent {
+		d.log.infof("Докачано: %s (с байта %d)", record.filename, offset)
+	} else {
+		d.log.infof("Скачано: %s", record.filename)
+	}
+	return record, written, nil
 }
 
-func (d *Downloader) getFilename(rawURL, contentType string) string {
-	parsed, err := url.Parse(rawURL)
+// partialDownload возвращает недокачанный файл прерванного обхода и его текущий размер.
+func (d *Downloader) partialDownload(rawURL string) (downloadedFile, int64) {
+	partial, ok := d.partial[rawURL]
+	if !ok || partial.filename == "" {
+		return partial, 0
+	}
+	info, err := d.storage.Stat(partial.filename)
 	if err != nil {
-		return fmt.Sprintf("file_%d.html", time.Now().UnixNano())
+		return partial, 0
 	}
+	return partial, info.Size()
+}
 
-	host := strings.ReplaceAll(parsed.Host, ":", "_")
-	urlPath := parsed.Path
+// setConditionalHeaders добавляет If-None-Match и If-Modified-Since для ранее скачанного файла.
+// Если локальной копии нет, запрос остается безусловным.
+func (d *Downloader) setConditionalHeaders(req *http.Request, previous downloadedFile) bool {
+	if previous.filename == "" {
+		return false
+	}
+	info, err := d.storage.Stat(previous.filename)
+	if err != nil {
+		return false
+	}
 
-	if urlPath == "" || urlPath == "/" {
-		urlPath = "/index.html"
+	if previous.etag != "" {
+		req.Header.Set("If-None-Match", previous.etag)
+	}
+	modified := previous.lastModified
+	if modified == "" {
+		modified = info.ModTime().UTC().Format(http.TimeFormat)
 	}
+	req.Header.Set("If-Modified-Since", modified)
+	return true
+}
 
-	urlPath = strings.TrimPrefix(urlPath, "/")
+// rangeValidator возвращает значение If-Range: сильный ETag или Last-Modified.
+func rangeValidator(file downloadedFile) string {
+	if file.etag != "" && !strings.HasPrefix(file.etag, "W/") {
+		return file.etag
+	}
+	return file.lastModified
+}
 
-	ext := ".html"
-	if strings.Contains(contentType, "css") {
-		ext = ".css"
-	} else if strings.Contains(contentType, "javascript") {
-		ext = ".js"
-	} else if strings.Contains(contentType, "image") {
-		ext = ".jpg"
+// contentRangeStart возвращает начальный байт из заголовка "bytes start-end/total".
+func contentRangeStart(header string) int64 {
+	var start, end, total int64
+	if _, err := fmt.Sscanf(header, "bytes %d-%d/%d", &start, &end, &total); err != nil {
+		if _, err := fmt.Sscanf(header, "bytes %d-%d/*", &start, &end); err != nil {
+			return -1
+		}
 	}
+	return start
+}
 
-	if strings.Contains(filepath.Base(urlPath), ".") {
-		ext = ""
+// parseHTML ставит в очередь ссылки страницы и возвращает все найденные ссылки. Ссылки на другие
+// страницы учитываются только до максимальной глубины и не более MaxLinksPerPage;
+// реквизиты (при -page-requisites) - всегда.
+func (d *Downloader) parseHTML(name string, task *Task) ([]parser.Link, error) {
+	content, err := d.storage.ReadFile(name)
+	if err != nil {
+		return nil, err
 	}
 
-	filename := filepath.Join(host, urlPath) + ext
+	html := string(content)
+	base, _ := url.Parse(task.URL)
+
+	links := parser.ExtractLinks(html, base)
+
+	count := 0
+	requisites := 0
+	for _, link := range links {
+		requisite := d.config.PageRequisites && link.Kind.IsRequisite()
+		if !requisite && (task.Depth >= d.config.MaxDepth || d.linkLimitReached(count)) {
+			continue
+		}
+		if !d.follow(task.URL, link, requisite) {
+			continue
+		}
+		if _, visited := d.visited.Load(link.URL); visited {
+			continue
+		}
 
-	filename = strings.ReplaceAll(filename, "?", "_")
-	filename = strings.ReplaceAll(filename, "&", "_")
-	filename = strings.ReplaceAll(filename, "=", "_")
+		d.addTask(&Task{URL: link.URL, Depth: task.Depth + 1, Requisite: requisite, Kind: link.Kind})
+		if requisite {
+			requisites++
+		} else {
+			count++
+		}
+	}
 
-	return filename
+	d.log.debugf("Найдена %d новая ссылка в %s", count, task.URL)
+	if requisites > 0 {
+		d.log.debugf("Найдено %d реквизитов страницы %s", requisites, task.URL)
+	}
+	return links, nil
 }
 
-func (d *Downloader) parseHTML(filepath, baseURL string, depth int) error {
-	content, err := os.ReadFile(filepath)
+// linkLimitReached сообщает, что со страницы уже взято MaxLinksPerPage ссылок (0 - без ограничения).
+func (d *Downloader) linkLimitReached(count int) bool {
+	return d.config.MaxLinksPerPage > 0 && count >= d.config.MaxLinksPerPage
+}
+
+// parseCSS ставит в очередь ресурсы, на которые ссылается файл стилей (шрифты, изображения,
+// импортированные стили) и возвращает найденные ссылки. Это реквизиты страницы,
+// поэтому глубина рекурсии не увеличивается.
+func (d *Downloader) parseCSS(name string, task *Task) ([]parser.Link, error) {
+	content, err := d.storage.ReadFile(name)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	html := string(content)
-	base, _ := url.Parse(baseURL)
-
-	links := parser.ExtractLinks(html, base)
+	base, _ := url.Parse(task.URL)
+	links := parser.ExtractCSSLinks(string(content), base)
 
 	count := 0
 	for _, link := range links {
-		if d.isSameDomain(link, baseURL) {
-			if _, visited := d.visited.Load(link); !visited {
-				d.addTask(&Task{URL: link, Depth: depth})
-				count++
-				if count >= 50 {
-					break
-				}
-			}
+		if !d.follow(task.URL, link, d.config.PageRequisites) {
+			continue
+		}
+		if _, visited := d.visited.Load(link.URL); !visited {
+			d.addTask(&Task{URL: link.URL, Depth: task.Depth, Requisite: d.config.PageRequisites, Kind: link.Kind})
+			count++
 		}
 	}
 
-	log.Printf("Найдена %d новая ссылка в %s", count, baseURL)
-	return nil
+	d.log.debugf("Найдено %d ресурсов в стилях %s", count, task.URL)
+	return links, nil
+}
+
+// isStylesheet определяет файл стилей по типу содержимого или расширению в URL.
+func isStylesheet(rawURL, contentType string) bool {
+	if strings.Contains(contentType, "text/css") {
+		return true
+	}
+	parsed, err := url.Parse(rawURL)
+	return err == nil && strings.EqualFold(path.Ext(parsed.Path), ".css")
 }
 
-func (d *Downloader) isSameDomain(link, baseURL string) bool {
-	linkURL, err1 := url.Parse(link)
-	base, err2 := url.Parse(baseURL)
+// convertLinks переписывает ссылки в скачанных HTML и CSS на относительные пути к локальным копиям.
+// Ссылки на нескачанные ресурсы заменяются абсолютными адресами.
+func (d *Downloader) convertLinks() {
+	converted := 0
+	d.files.Range(func(key, value any) bool {
+		file := value.(downloadedFile)
+		if !strings.Contains(file.contentType, "text/html") && !isStylesheet(key.(string), file.contentType) {
+			return true
+		}
 
-	if err1 != nil || err2 != nil {
-		return false
+		if err := d.convertFile(key.(string), file); err != nil {
+			d.log.errorf("Ошибка преобразования ссылок в %s: %v", file.filename, err)
+			return true
+		}
+		converted++
+		return true
+	})
+
+	d.log.infof("Ссылки преобразованы в %d файлах", converted)
+}
+
+func (d *Downloader) convertFile(pageURL string, file downloadedFile) error {
+	content, err := d.storage.ReadFile(file.filename)
+	if err != nil {
+		return err
+	}
+
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return err
+	}
+
+	rewrite := func(link parser.Link) (string, bool) {
+		target, ok := d.files.Load(d.resolveRedirects(link.URL))
+		if !ok {
+			return "", false
+		}
+		return relativeLink(file.filename, target.(downloadedFile).filename), true
+	}
+
+	var converted string
+	if strings.Contains(file.contentType, "text/html") {
+		converted = parser.RewriteLinks(string(content), base, rewrite)
+	} else {
+		converted = parser.RewriteCSSLinks(string(content), base, rewrite)
+	}
+
+	return writeFile(d.storage, file.filename, []byte(converted))
+}
+
+// relativeLink возвращает ссылку на файл to относительно каталога файла from.
+func relativeLink(from, to string) string {
+	relative, err := filepath.Rel(filepath.Dir(from), to)
+	if err != nil {
+		relative = to
 	}
 
-	return linkURL.Host == base.Host
+	link := url.URL{Path: filepath.ToSlash(relative)}
+	return link.String()
 }
@@ -0,0 +1,173 @@
+package downloader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ds124wfegd/WB_L2/16/config"
+)
+
+// newTestServer поднимает сайт с заданным robots.txt и считает запросы к нему
+func newTestServer(t *testing.T, robotsTxt string, robotsRequests *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			robotsRequests.Add(1)
+			if robotsTxt == "" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte(robotsTxt))
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("content of " + r.URL.Path))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newDownloader(t *testing.T, cfg *config.Config) *Downloader {
+	t.Helper()
+	d, err := NewDownloader(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func newTestDownloader(t *testing.T, server *httptest.Server) *Downloader {
+	t.Helper()
+	return newDownloader(t, config.NewConfig(server.URL, t.TempDir(), 0, 1))
+}
+
+func downloaded(d *Downloader, server *httptest.Server, path string) bool {
+	host := strings.ReplaceAll(strings.TrimPrefix(server.URL, "http://"), ":", "_")
+	_, err := os.Stat(filepath.Join(d.config.Output, host, path))
+	return err == nil
+}
+
+func TestRobotsDisallow(t *testing.T) {
+	var robotsRequests atomic.Int32
+	server := newTestServer(t, "User-agent: *\nDisallow: /private/\nDisallow: /*.txt$\n", &robotsRequests)
+	d := newTestDownloader(t, server)
+
+	paths := []struct {
+		path    string
+		allowed bool
+	}{
+		{"/public.dat", true},
+		{"/private/secret.dat", false},
+		{"/notes.txt", false},
+		{"/notes.txt.bak", true},
+	}
+
+	for _, test := range paths {
+		if err := d.download(context.Background(), &Task{URL: server.URL + test.path}); err != nil {
+			t.Fatalf("download(%s): %v", test.path, err)
+		}
+		if got := downloaded(d, server, test.path); got != test.allowed {
+			t.Errorf("%s downloaded = %v, expected %v", test.path, got, test.allowed)
+		}
+	}
+
+	if count := robotsRequests.Load(); count != 1 {
+		t.Errorf("robots.txt requested %d times, expected 1", count)
+	}
+}
+
+func TestNoRobots(t *testing.T) {
+	var robotsRequests atomic.Int32
+	server := newTestServer(t, "User-agent: *\nDisallow: /\n", &robotsRequests)
+	d := newTestDownloader(t, server)
+	d.config.NoRobots = true
+
+	if err := d.download(context.Background(), &Task{URL: server.URL + "/page.dat"}); err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	if !downloaded(d, server, "/page.dat") {
+		t.Errorf("page must be downloaded with NoRobots")
+	}
+	if count := robotsRequests.Load(); count != 0 {
+		t.Errorf("robots.txt requested %d times with NoRobots", count)
+	}
+}
+
+func TestMissingRobotsAllowsAll(t *testing.T) {
+	var robotsRequests atomic.Int32
+	server := newTestServer(t, "", &robotsRequests)
+	d := newTestDownloader(t, server)
+
+	if err := d.download(context.Background(), &Task{URL: server.URL + "/page.dat"}); err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	if !downloaded(d, server, "/page.dat") {
+		t.Errorf("page must be downloaded when robots.txt is missing")
+	}
+}
+
+func TestCrawlDelay(t *testing.T) {
+	var robotsRequests atomic.Int32
+	server := newTestServer(t, "User-agent: MyDownloader\nCrawl-delay: 0.2\n", &robotsRequests)
+	d := newTestDownloader(t, server)
+
+	start := time.Now()
+	for _, path := range []string{"/a.dat", "/b.dat", "/c.dat"} {
+		if err := d.download(context.Background(), &Task{URL: server.URL + path}); err != nil {
+			t.Fatalf("download(%s): %v", path, err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("three requests with Crawl-delay 0.2s took %v, expected at least 400ms", elapsed)
+	}
+}
+
+func TestHostLimiter(t *testing.T) {
+	limiter := newHostLimiter()
+	interval := 100 * time.Millisecond
+
+	start := time.Now()
+	done := make(chan time.Duration, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			limiter.Wait(context.Background(), "example.com", interval)
+			done <- time.Since(start)
+		}()
+	}
+
+	var latest time.Duration
+	for i := 0; i < 3; i++ {
+		latest = max(latest, <-done)
+	}
+	if latest < 2*interval {
+		t.Errorf("third request started after %v, expected at least %v", latest, 2*interval)
+	}
+
+	// Другие хосты не ждут
+	otherStart := time.Now()
+	limiter.Wait(context.Background(), "other.example.com", interval)
+	if elapsed := time.Since(otherStart); elapsed > interval/2 {
+		t.Errorf("first request to another host waited %v", elapsed)
+	}
+}
+
+func TestRequestInterval(t *testing.T) {
+	d := newDownloader(t, config.NewConfig("http://example.com/", t.TempDir(), 0, 1))
+	d.config.Wait = time.Second
+	d.config.RandomWait = true
+
+	for i := 0; i < 100; i++ {
+		interval := d.requestInterval(nil)
+		if interval < 500*time.Millisecond || interval > 1500*time.Millisecond {
+			t.Fatalf("random wait %v outside [0.5s, 1.5s]", interval)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package downloader
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"mime"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Имя файла для адресов, оканчивающихся на "/"
+	indexFilename = "index.html"
+
+	// Имя файла соответствия URL -> путь в выходном каталоге
+	manifestName = "manifest.json"
+
+	// Максимальная длина одного компонента пути в байтах (ограничение большинства файловых систем - 255)
+	maxSegmentLength = 200
+)
+
+// Расширения для распространенных типов содержимого (не зависят от системной базы mime)
+var mimeExtensions = map[string]string{
+	"text/html":                ".html",
+	"application/xhtml+xml":    ".html",
+	"text/css":                 ".css",
+	"text/javascript":          ".js",
+	"application/javascript":   ".js",
+	"application/x-javascript": ".js",
+	"application/json":         ".json",
+	"application/xml":          ".xml",
+	"text/xml":                 ".xml",
+	"text/plain":               ".txt",
+	"application/pdf":          ".pdf",
+	"application/zip":          ".zip",
+	"image/jpeg":               ".jpg",
+	"image/png":                ".png",
+	"image/gif":                ".gif",
+	"image/svg+xml":            ".svg",
+	"image/webp":               ".webp",
+	"image/avif":               ".avif",
+	"image/x-icon":             ".ico",
+	"image/vnd.microsoft.icon": ".ico",
+	"image/bmp":                ".bmp",
+	"font/woff":                ".woff",
+	"font/woff2":               ".woff2",
+	"font/ttf":                 ".ttf",
+	"font/otf":                 ".otf",
+	"audio/mpeg":               ".mp3",
+	"audio/ogg":                ".ogg",
+	"video/mp4":                ".mp4",
+	"video/webm":               ".webm",
+}
+
+// Имена устройств Windows, которые нельзя использовать как имена файлов
+var reservedNames = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "com5": true, "com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true, "lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+}
+
+// getFilename возвращает путь файла относительно выходного каталога: хост/путь URL с учетом
+// имени из Content-Disposition, расширения по типу содержимого и хеша строки запроса.
+// Разные URL никогда не получают одно и то же имя.
+func (d *Downloader) getFilename(rawURL, contentType, disposition string) string {
+	filename := buildFilename(rawURL, contentType, disposition)
+
+	d.namesMu.Lock()
+	defer d.namesMu.Unlock()
+	if d.names == nil {
+		d.names = make(map[string]string)
+	}
+
+	// Имя занято другим URL (например, после замены недопустимых символов) - добавляем хеш URL
+	if owner, taken := d.names[filename]; taken && owner != rawURL {
+		filename = withSuffix(filename, "_"+shortHash(rawURL))
+	}
+	d.names[filename] = rawURL
+	return filename
+}
+
+// reserveFilename отмечает имя файла занятым (для файлов, восстановленных из журнала).
+func (d *Downloader) reserveFilename(rawURL, filename string) {
+	d.namesMu.Lock()
+	defer d.namesMu.Unlock()
+	if d.names == nil {
+		d.names = make(map[string]string)
+	}
+	d.names[filename] = rawURL
+}
+
+func buildFilename(rawURL, contentType, disposition string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return filepath.Join("invalid", shortHash(rawURL))
+	}
+
+	host := sanitizeSegment(strings.ToLower(strings.ReplaceAll(parsed.Host, ":", "_")))
+	segments := strings.Split(strings.TrimPrefix(parsed.Path, "/"), "/")
+	name := segments[len(segments)-1]
+	directories := segments[:len(segments)-1]
+	if name == "" {
+		name = indexFilename
+	}
+
+	if attachment := dispositionFilename(disposition); attachment != "" {
+		name = attachment
+	}
+
+	extension := path.Ext(name)
+	base := strings.TrimSuffix(name, extension)
+	if parsed.RawQuery != "" {
+		base += "_" + shortHash(parsed.RawQuery)
+	}
+	name = base + extension + missingExtension(extension, contentType)
+
+	parts := []string{host}
+	for _, directory := range directories {
+		if directory != "" {
+			parts = append(parts, sanitizeSegment(directory))
+		}
+	}
+	parts = append(parts, sanitizeSegment(name))
+	return filepath.Join(parts...)
+}
+
+// missingExtension возвращает расширение, которое нужно добавить к имени файла: по типу
+// содержимого, если расширения нет, а для HTML - также вместо серверных (.php, .asp, ...),
+// чтобы страницы открывались в браузере без сервера.
+func missingExtension(extension, contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	// Произвольные двоичные данные не говорят ничего о формате файла
+	if err != nil || mediaType == "application/octet-stream" {
+		return ""
+	}
+
+	expected, known := mimeExtensions[mediaType]
+	if !known {
+		if extensions, err := mime.ExtensionsByType(mediaType); err == nil && len(extensions) > 0 {
+			expected = extensions[0]
+		}
+	}
+	if expected == "" {
+		return ""
+	}
+
+	switch {
+	case extension == "":
+		return expected
+	case expected == ".html" && !isHTMLExtension(extension):
+		return expected
+	}
+	return ""
+}
+
+func isHTMLExtension(extension string) bool {
+	switch strings.ToLower(extension) {
+	case ".html", ".htm", ".shtml", ".xhtml":
+		return true
+	}
+	return false
+}
+
+// dispositionFilename извлекает имя файла из Content-Disposition (включая filename*=UTF-8”).
+func dispositionFilename(disposition string) string {
+	if disposition == "" {
+		return ""
+	}
+	_, params, err := mime.ParseMediaType(disposition)
+	if err != nil {
+		return ""
+	}
+	// Только имя, без каталогов: сервер не должен управлять путем сохранения
+	name := path.Base(strings.ReplaceAll(params["filename"], `\`, "/"))
+	if name == "." || name == "/" || name == ".." {
+		return ""
+	}
+	return name
+}
+
+// sanitizeSegment делает компонент пути допустимым для файловых систем Linux, macOS и Windows.
+func sanitizeSegment(segment string) string {
+	var result strings.Builder
+	for _, char := range segment {
+		if char < 0x20 || char == 0x7f || strings.ContainsRune(`<>:"/\|?*`, char) {
+			result.WriteByte('_')
+			continue
+		}
+		result.WriteRune(char)
+	}
+
+	sanitized := strings.TrimRight(result.String(), ". ")
+	if sanitized == "" {
+		sanitized = "_"
+	}
+
+	stem, _, _ := strings.Cut(strings.ToLower(sanitized), ".")
+	if reservedNames[stem] {
+		sanitized = "_" + sanitized
+	}
+
+	if len(sanitized) > maxSegmentLength {
+		extension := path.Ext(sanitized)
+		if len(extension) > 16 {
+			extension = ""
+		}
+		cut := maxSegmentLength - len(extension) - 9
+		// Не разрезаем многобайтовый символ UTF-8
+		for cut > 0 && sanitized[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		sanitized = sanitized[:cut] + "_" + shortHash(sanitized) + extension
+	}
+	return sanitized
+}
+
+// withSuffix вставляет суффикс перед расширением имени файла.
+func withSuffix(filename, suffix string) string {
+	extension := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, extension) + suffix + extension
+}
+
+// shortHash возвращает первые 8 шестнадцатеричных символов SHA-1: стабильно между запусками.
+func shortHash(value string) string {
+	sum := sha1.Sum([]byte(value))
+	return hex.EncodeToString(sum[:4])
+}
+
+// writeManifest сохраняет соответствие URL -> путь файла (включая перенаправленные адреса) в JSON.
+func (d *Downloader) writeManifest() error {
+	manifest := make(map[string]string)
+	d.files.Range(func(key, value any) bool {
+		manifest[key.(string)] = filepath.ToSlash(value.(downloadedFile).filename)
+		return true
+	})
+	d.redirects.Range(func(key, value any) bool {
+		if file, ok := d.files.Load(d.resolveRedirects(key.(string))); ok {
+			manifest[key.(string)] = filepath.ToSlash(file.(downloadedFile).filename)
+		}
+		return true
+	})
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(d.storage, manifestName, append(content, '\n'))
+}
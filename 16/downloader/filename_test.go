@@ -0,0 +1,102 @@
+package downloader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ds124wfegd/WB_L2/16/config"
+)
+
+func TestBuildFilename(t *testing.T) {
+	tests := []struct {
+		url         string
+		contentType string
+		disposition string
+		expected    string
+	}{
+		{"http://example.com/", "text/html", "", "example.com/index.html"},
+		{"http://example.com", "text/html; charset=utf-8", "", "example.com/index.html"},
+		{"http://Example.com:8080/docs/", "text/html", "", "example.com_8080/docs/index.html"},
+		{"http://example.com/about", "text/html", "", "example.com/about.html"},
+		{"http://example.com/list.php", "text/html", "", "example.com/list.php.html"},
+		{"http://example.com/page.htm", "text/html", "", "example.com/page.htm"},
+		{"http://example.com/logo", "image/png", "", "example.com/logo.png"},
+		{"http://example.com/photo", "image/webp", "", "example.com/photo.webp"},
+		{"http://example.com/style.css", "text/css", "", "example.com/style.css"},
+		{"http://example.com/data", "application/octet-stream", "", "example.com/data"},
+		{"http://example.com/list?page=2", "text/html", "", "example.com/list_" + shortHash("page=2") + ".html"},
+		{"http://example.com/img.png?v=1", "image/png", "", "example.com/img_" + shortHash("v=1") + ".png"},
+		{"http://example.com/a%20b/c%3Ad.txt", "text/plain", "", "example.com/a b/c_d.txt"},
+		{"http://example.com/%D0%BF%D1%80%D0%B8%D0%B2%D0%B5%D1%82", "text/html", "", "example.com/привет.html"},
+		{"http://example.com/con", "text/plain", "", "example.com/_con.txt"},
+		{"http://example.com/download?id=7", "application/pdf", `attachment; filename="report 2024.pdf"`, "example.com/report 2024_" + shortHash("id=7") + ".pdf"},
+		{"http://example.com/get", "", `attachment; filename*=UTF-8''%D0%BE%D1%82%D1%87%D0%B5%D1%82.zip`, "example.com/отчет.zip"},
+		{"http://example.com/get", "", `attachment; filename="../../etc/passwd"`, "example.com/passwd"},
+	}
+
+	for _, test := range tests {
+		if result := filepath.ToSlash(buildFilename(test.url, test.contentType, test.disposition)); result != test.expected {
+			t.Errorf("buildFilename(%q, %q, %q) = %q, expected %q", test.url, test.contentType, test.disposition, result, test.expected)
+		}
+	}
+}
+
+func TestSanitizeLongSegment(t *testing.T) {
+	long := strings.Repeat("я", 300) + ".html"
+	result := sanitizeSegment(long)
+	if len(result) > maxSegmentLength || !strings.HasSuffix(result, ".html") {
+		t.Errorf("long segment sanitized to %d bytes: %q", len(result), result)
+	}
+	if !strings.HasPrefix(result, strings.Repeat("я", 10)) || sanitizeSegment(long) != result {
+		t.Errorf("long segment must keep its prefix and be stable")
+	}
+}
+
+func TestGetFilenameAvoidsCollisions(t *testing.T) {
+	d := newDownloader(t, config.NewConfig("http://example.com/", t.TempDir(), 0, 1))
+
+	first := d.getFilename("http://example.com/a:b", "text/plain", "")
+	second := d.getFilename("http://example.com/a_b", "text/plain", "")
+	again := d.getFilename("http://example.com/a:b", "text/plain", "")
+
+	if first == second {
+		t.Errorf("different URLs got the same file name %q", first)
+	}
+	if first != again {
+		t.Errorf("the same URL must keep its file name: %q and %q", first, again)
+	}
+}
+
+func TestWriteManifest(t *testing.T) {
+	d := newDownloader(t, config.NewConfig("http://example.com/", t.TempDir(), 0, 1))
+	d.files.Store("http://example.com/", downloadedFile{filename: filepath.Join("example.com", "index.html")})
+	d.files.Store("http://example.com/new.html", downloadedFile{filename: filepath.Join("example.com", "new.html")})
+	d.redirects.Store("http://example.com/old.html", "http://example.com/new.html")
+
+	if err := d.writeManifest(); err != nil {
+		t.Fatalf("writeManifest: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(d.config.Output, manifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest map[string]string
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		t.Fatalf("manifest is not valid JSON: %v", err)
+	}
+
+	expected := map[string]string{
+		"http://example.com/":         "example.com/index.html",
+		"http://example.com/new.html": "example.com/new.html",
+		"http://example.com/old.html": "example.com/new.html",
+	}
+	for rawURL, file := range expected {
+		if manifest[rawURL] != file {
+			t.Errorf("manifest[%s] = %q, expected %q", rawURL, manifest[rawURL], file)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package downloader
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// Очередь задач без ограничения размера с точным отслеживанием завершения:
+// обход закончен, когда очередь пуста и ни одна задача не выполняется.
+// Задачи с большим приоритетом выбираются раньше, с равным - в порядке добавления.
+type frontier struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	tasks   taskHeap
+	next    uint64 // порядковый номер следующей задачи
+	pending int    // задачи в очереди и в работе
+	closed  bool
+}
+
+// Задача в очереди с порядковым номером добавления
+type queuedTask struct {
+	task     *Task
+	sequence uint64
+}
+
+// taskHeap - куча задач для container/heap
+type taskHeap []queuedTask
+
+func (h taskHeap) Len() int { return len(h) }
+
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].task.Priority != h[j].task.Priority {
+		return h[i].task.Priority > h[j].task.Priority
+	}
+	return h[i].sequence < h[j].sequence
+}
+
+func (h taskHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *taskHeap) Push(x any) { *h = append(*h, x.(queuedTask)) }
+
+func (h *taskHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = queuedTask{}
+	*h = old[:len(old)-1]
+	return item
+}
+
+func newFrontier() *frontier {
+	f := &frontier{}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+// Push добавляет задачу; после закрытия очереди задачи не принимаются.
+func (f *frontier) Push(task *Task) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return false
+	}
+	heap.Push(&f.tasks, queuedTask{task: task, sequence: f.next})
+	f.next++
+	f.pending++
+	f.cond.Signal()
+	return true
+}
+
+// Pop ждет следующую задачу. Возвращает false, когда обход завершен или прерван.
+// Каждая полученная задача должна быть отмечена вызовом Done.
+func (f *frontier) Pop() (*Task, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for len(f.tasks) == 0 && !f.closed {
+		if f.pending == 0 {
+			f.closeLocked()
+			break
+		}
+		f.cond.Wait()
+	}
+	if f.closed {
+		return nil, false
+	}
+
+	return heap.Pop(&f.tasks).(queuedTask).task, true
+}
+
+// Done отмечает задачу выполненной (вместе с постановкой в очередь найденных в ней ссылок).
+func (f *frontier) Done() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.pending--
+	if f.pending == 0 && len(f.tasks) == 0 {
+		f.closeLocked()
+	}
+}
+
+// Len возвращает число задач, ожидающих в очереди.
+func (f *frontier) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.tasks)
+}
+
+// Close прерывает обход: ожидающие задачи отбрасываются, воркеры завершаются.
+func (f *frontier) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closeLocked()
+}
+
+func (f *frontier) closeLocked() {
+	f.closed = true
+	f.tasks = nil
+	f.cond.Broadcast()
+}
@@ -0,0 +1,127 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ds124wfegd/WB_L2/16/config"
+)
+
+func TestFrontierKeepsAllTasks(t *testing.T) {
+	f := newFrontier()
+	for i := 0; i < 5000; i++ {
+		f.Push(&Task{URL: fmt.Sprintf("http://example.com/%d", i)})
+	}
+
+	count := 0
+	for {
+		_, ok := f.Pop()
+		if !ok {
+			break
+		}
+		// Первая задача порождает еще одну, пока выполняется
+		if count == 0 {
+			f.Push(&Task{URL: "http://example.com/child"})
+		}
+		count++
+		f.Done()
+	}
+
+	if count != 5001 {
+		t.Errorf("processed %d tasks, expected 5001", count)
+	}
+	if f.Push(&Task{URL: "http://example.com/late"}) {
+		t.Errorf("finished frontier must not accept tasks")
+	}
+}
+
+func TestStartWaitsForSlowDownloads(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<a href="/slow.dat">slow</a>`))
+		case "/slow.dat":
+			time.Sleep(time.Second)
+			w.Write([]byte("slow content"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	d := newDownloader(t, config.NewConfig(server.URL+"/", t.TempDir(), 1, 2))
+	start := time.Now()
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	if !downloaded(d, server, "slow.dat") {
+		t.Errorf("slow file must be downloaded before Start returns")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Start took %v, expected to finish right after the last download", elapsed)
+	}
+}
+
+func TestStartCancelRemovesPartialFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/big.dat" {
+			http.NotFound(w, r)
+			return
+		}
+		// Тело отдается медленно, пока клиент не отключится
+		for i := 0; i < 100; i++ {
+			if _, err := w.Write([]byte(strings.Repeat("x", 1024))); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+	}))
+	defer server.Close()
+
+	d := newDownloader(t, config.NewConfig(server.URL+"/big.dat", t.TempDir(), 0, 1))
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	err := d.Start(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Start returned %v, expected context deadline error", err)
+	}
+
+	filename := d.getFilename(server.URL+"/big.dat", "", "")
+	if _, err := os.Stat(filepath.Join(d.config.Output, filename)); !os.IsNotExist(err) {
+		t.Errorf("partial file must be removed after cancellation, stat error: %v", err)
+	}
+}
+
+func TestFrontierPriority(t *testing.T) {
+	f := newFrontier()
+	f.Push(&Task{URL: "a"})
+	f.Push(&Task{URL: "old", Priority: 100})
+	f.Push(&Task{URL: "b"})
+	f.Push(&Task{URL: "new", Priority: 200})
+
+	var order []string
+	for range 4 {
+		task, _ := f.Pop()
+		order = append(order, task.URL)
+		f.Done()
+	}
+	if strings.Join(order, " ") != "new old a b" {
+		t.Errorf("pop order = %v, expected [new old a b]", order)
+	}
+}
@@ -0,0 +1,184 @@
+package downloader
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/ds124wfegd/WB_L2/16/parser"
+)
+
+// Имя журнала обхода в выходном каталоге
+const journalName = ".crawl-journal"
+
+// Операции журнала
+const (
+	journalQueued   = "queued"   // ссылка поставлена в очередь
+	journalStarted  = "started"  // начата запись файла
+	journalDone     = "done"     // ссылка обработана (файл сохранен или пропущен)
+	journalRedirect = "redirect" // ссылка перенаправлена на другой адрес
+)
+
+// Запись журнала (одна строка JSON)
+type journalEntry struct {
+	Op           string `json:"op"`
+	URL          string `json:"url"`
+	Depth        int    `json:"depth,omitempty"`
+	Requisite    bool   `json:"requisite,omitempty"`
+	Kind         int    `json:"kind,omitempty"`
+	Priority     int64  `json:"priority,omitempty"`
+	Location     string `json:"location,omitempty"`
+	File         string `json:"file,omitempty"`
+	ContentType  string `json:"type,omitempty"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"modified,omitempty"`
+}
+
+// Журнал обхода: очередь и обработанные ссылки дописываются в файл по мере работы,
+// что позволяет продолжить прерванный обход (-continue)
+type journal struct {
+	mu   sync.Mutex
+	file *os.File
+	log  *logger
+}
+
+// Состояние обхода, восстановленное из журнала
+type crawlState struct {
+	pending   []*Task                   // поставлены в очередь, но не обработаны
+	completed map[string]downloadedFile // обработаны
+	started   map[string]downloadedFile // запись файла начата, но не завершена
+	redirects map[string]string         // исходный URL -> конечный URL
+}
+
+// openJournal открывает журнал для дописывания (resume) или начинает его заново.
+func openJournal(path string, resume bool, log *logger) (*journal, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if !resume {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &journal{file: file, log: log}, nil
+}
+
+func newCrawlState() *crawlState {
+	return &crawlState{
+		completed: make(map[string]downloadedFile),
+		started:   make(map[string]downloadedFile),
+		redirects: make(map[string]string),
+	}
+}
+
+// loadJournal восстанавливает состояние обхода; отсутствующий журнал означает пустое состояние.
+func loadJournal(path string) (*crawlState, error) {
+	state := newCrawlState()
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return state, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var queued []*Task
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry journalEntry
+		// Последняя строка может быть оборвана при аварийном завершении
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+
+		record := downloadedFile{
+			filename:     entry.File,
+			contentType:  entry.ContentType,
+			etag:         entry.ETag,
+			lastModified: entry.LastModified,
+		}
+		switch entry.Op {
+		case journalQueued:
+			if !seen[entry.URL] {
+				seen[entry.URL] = true
+				queued = append(queued, &Task{URL: entry.URL, Depth: entry.Depth, Requisite: entry.Requisite, Kind: parser.LinkKind(entry.Kind), Priority: entry.Priority})
+			}
+		case journalStarted:
+			state.started[entry.URL] = record
+		case journalRedirect:
+			state.redirects[entry.URL] = entry.Location
+		case journalDone:
+			state.completed[entry.URL] = record
+			delete(state.started, entry.URL)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, task := range queued {
+		if _, done := state.completed[task.URL]; !done {
+			state.pending = append(state.pending, task)
+		}
+	}
+	return state, nil
+}
+
+func (j *journal) Queued(task *Task) {
+	j.write(journalEntry{Op: journalQueued, URL: task.URL, Depth: task.Depth, Requisite: task.Requisite, Kind: int(task.Kind), Priority: task.Priority})
+}
+
+func (j *journal) Redirected(rawURL, location string) {
+	j.write(journalEntry{Op: journalRedirect, URL: rawURL, Location: location})
+}
+
+func (j *journal) Started(rawURL string, file downloadedFile) {
+	j.write(fileEntry(journalStarted, rawURL, file))
+}
+
+func (j *journal) Done(rawURL string, file downloadedFile) {
+	j.write(fileEntry(journalDone, rawURL, file))
+}
+
+func (j *journal) Close() error {
+	if j == nil {
+		return nil
+	}
+	return j.file.Close()
+}
+
+func fileEntry(op, rawURL string, file downloadedFile) journalEntry {
+	return journalEntry{
+		Op:           op,
+		URL:          rawURL,
+		File:         file.filename,
+		ContentType:  file.contentType,
+		ETag:         file.etag,
+		LastModified: file.lastModified,
+	}
+}
+
+// write дописывает запись в журнал; без журнала (например, в тестах) ничего не делает.
+func (j *journal) write(entry journalEntry) {
+	if j == nil {
+		return
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		j.log.errorf("Ошибка записи журнала: %v", err)
+	}
+}
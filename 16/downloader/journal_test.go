@@ -0,0 +1,155 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ds124wfegd/WB_L2/16/config"
+)
+
+func TestLoadJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), journalName)
+	content := `{"op":"queued","url":"http://example.com/"}
+{"op":"queued","url":"http://example.com/a.html","depth":1}
+{"op":"queued","url":"http://example.com/b.html","depth":1}
+{"op":"started","url":"http://example.com/","file":"example.com/index.html","type":"text/html"}
+{"op":"done","url":"http://example.com/","file":"example.com/index.html","type":"text/html","etag":"\"v1\""}
+{"op":"started","url":"http://example.com/a.html","file":"example.com/a.html","type":"text/html"}
+{"op":"queued","url":"http://example.com/a.html","depth":1}
+{"op":"done","url":"http://exa`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := loadJournal(path)
+	if err != nil {
+		t.Fatalf("loadJournal: %v", err)
+	}
+
+	if len(state.pending) != 2 || state.pending[0].URL != "http://example.com/a.html" || state.pending[1].URL != "http://example.com/b.html" || state.pending[0].Depth != 1 {
+		t.Errorf("pending = %v", state.pending)
+	}
+	if file := state.completed["http://example.com/"]; file.filename != "example.com/index.html" || file.etag != `"v1"` {
+		t.Errorf("completed = %v", state.completed)
+	}
+	if _, ok := state.started["http://example.com/a.html"]; !ok || len(state.started) != 1 {
+		t.Errorf("started = %v", state.started)
+	}
+
+	missing, err := loadJournal(filepath.Join(t.TempDir(), journalName))
+	if err != nil || len(missing.pending) != 0 || len(missing.completed) != 0 {
+		t.Errorf("missing journal must give empty state, got %v, %v", missing, err)
+	}
+}
+
+// newContentServer отдает один файл через http.ServeContent (Range, If-Range, ETag, If-Modified-Since)
+func newContentServer(t *testing.T, content []byte, modified time.Time, requests *[]*http.Request) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		*requests = append(*requests, r.Clone(r.Context()))
+		w.Header().Set("ETag", `"file-v1"`)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		http.ServeContent(w, r, "large.bin", modified, bytes.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestContinuePartialDownload(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 100))
+	var requests []*http.Request
+	server := newContentServer(t, content, time.Now(), &requests)
+
+	output := t.TempDir()
+	d := newDownloader(t, config.NewConfig(server.URL, output, 0, 1))
+	d.config.Continue = true
+	fileURL := server.URL + "/large.bin"
+	filename := d.getFilename(fileURL, "application/octet-stream", "")
+
+	// Файл оборвался на 300 байтах: в журнале есть started, но нет done
+	fullPath := filepath.Join(output, filename)
+	os.MkdirAll(filepath.Dir(fullPath), 0755)
+	os.WriteFile(fullPath, content[:300], 0644)
+	journalContent := `{"op":"queued","url":"` + fileURL + `"}` + "\n" +
+		`{"op":"started","url":"` + fileURL + `","file":"` + filename + `","type":"application/octet-stream","etag":"\"file-v1\""}` + "\n"
+	os.WriteFile(filepath.Join(output, journalName), []byte(journalContent), 0644)
+
+	state, err := loadJournal(filepath.Join(output, journalName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.restore(state)
+
+	if err := d.download(context.Background(), state.pending[0]); err != nil {
+		t.Fatalf("download: %v", err)
+	}
+
+	if len(requests) != 1 || requests[0].Header.Get("Range") != "bytes=300-" || requests[0].Header.Get("If-Range") != `"file-v1"` {
+		t.Fatalf("expected one Range request, got %d (Range %q)", len(requests), requests[0].Header.Get("Range"))
+	}
+	saved, _ := os.ReadFile(fullPath)
+	if !bytes.Equal(saved, content) {
+		t.Errorf("resumed file has %d bytes, expected %d", len(saved), len(content))
+	}
+}
+
+func TestTimestamping(t *testing.T) {
+	content := []byte("unchanged content")
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var requests []*http.Request
+	server := newContentServer(t, content, modified, &requests)
+	output := t.TempDir()
+	fileURL := server.URL + "/large.bin"
+
+	// Первый запуск записывает журнал с валидаторами
+	first := newDownloader(t, config.NewConfig(server.URL, output, 0, 1))
+	first.config.Timestamping = true
+	journal, err := openJournal(filepath.Join(output, journalName), false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.journal = journal
+	if err := first.download(context.Background(), &Task{URL: fileURL}); err != nil {
+		t.Fatalf("first download: %v", err)
+	}
+	journal.Close()
+
+	second := newDownloader(t, config.NewConfig(server.URL, output, 0, 1))
+	second.config.Timestamping = true
+	state, err := loadJournal(filepath.Join(output, journalName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.previous = state.completed
+	if err := second.download(context.Background(), &Task{URL: fileURL}); err != nil {
+		t.Fatalf("second download: %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+	if requests[1].Header.Get("If-None-Match") != `"file-v1"` || requests[1].Header.Get("If-Modified-Since") != modified.Format(http.TimeFormat) {
+		t.Errorf("second request is not conditional: %v", requests[1].Header)
+	}
+
+	record, ok := second.files.Load(fileURL)
+	if !ok || record.(downloadedFile).filename != state.completed[fileURL].filename {
+		t.Errorf("unchanged file must still be registered, got %v", record)
+	}
+
+	info, err := os.Stat(filepath.Join(output, state.completed[fileURL].filename))
+	if err != nil || !info.ModTime().Equal(modified) {
+		t.Errorf("file mtime must match Last-Modified, got %v", info.ModTime())
+	}
+}
@@ -0,0 +1,110 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// Файл превышает -max-file-size
+var errFileTooLarge = errors.New("файл больше -max-file-size")
+
+// tokenBucket ограничивает суммарную скорость чтения всех загрузок (-limit-rate).
+// Байты резервируются заранее, поэтому одновременные загрузки делят полосу поровну.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // байт в секунду
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate int64) *tokenBucket {
+	burst := max(float64(rate)/10, 1024)
+	return &tokenBucket{rate: float64(rate), burst: burst, tokens: burst, last: time.Now()}
+}
+
+// Wait списывает n байтов и ждет, пока их не покроет накопленный запас.
+func (b *tokenBucket) Wait(ctx context.Context, n int) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens -= float64(n)
+	deficit := -b.tokens
+	b.mu.Unlock()
+
+	if deficit <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(time.Duration(deficit / b.rate * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Чтение с ограничением скорости общим ведром токенов
+type rateLimitedReader struct {
+	ctx    context.Context
+	reader io.Reader
+	bucket *tokenBucket
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	if len(p) > int(r.bucket.burst) {
+		p = p[:int(r.bucket.burst)]
+	}
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		if waitErr := r.bucket.Wait(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// Чтение, прерываемое ошибкой errFileTooLarge после remaining байтов
+type sizeLimitedReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (r *sizeLimitedReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return n, errFileTooLarge
+	}
+	return n, err
+}
+
+// limitReader добавляет к телу ответа ограничения -limit-rate и -max-file-size;
+// offset - размер уже скачанной части файла.
+func (d *Downloader) limitReader(ctx context.Context, body io.Reader, offset int64) io.Reader {
+	if d.config.MaxFileSize > 0 {
+		body = &sizeLimitedReader{reader: body, remaining: d.config.MaxFileSize - offset}
+	}
+	if d.bandwidth != nil {
+		body = &rateLimitedReader{ctx: ctx, reader: body, bucket: d.bandwidth}
+	}
+	return body
+}
+
+// checkQuota останавливает обход, когда скачано больше -quota: начатые загрузки завершаются,
+// а оставшиеся в очереди ссылки остаются в журнале для -continue.
+func (d *Downloader) checkQuota() {
+	if d.config.Quota <= 0 || d.stats.bytes.Load() < d.config.Quota {
+		return
+	}
+	d.quotaOnce.Do(func() {
+		d.log.infof("Превышена квота %s: новые загрузки не начинаются", formatBytes(d.config.Quota))
+		d.stats.quotaExceeded.Store(true)
+		d.frontier.Close()
+	})
+}
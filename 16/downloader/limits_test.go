@@ -0,0 +1,224 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ds124wfegd/WB_L2/16/config"
+	"github.com/ds124wfegd/WB_L2/16/warc"
+)
+
+func TestTokenBucketLimitsRate(t *testing.T) {
+	bucket := newTokenBucket(100 * 1024)
+	reader := &rateLimitedReader{ctx: context.Background(), reader: bytes.NewReader(make([]byte, 60*1024)), bucket: bucket}
+
+	started := time.Now()
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		t.Fatal(err)
+	}
+	// 60 КБ при 100 КБ/с с начальным запасом 10 КБ - не меньше 0.5 с
+	if elapsed := time.Since(started); elapsed < 450*time.Millisecond {
+		t.Errorf("60 KB read in %v at 100 KB/s", elapsed)
+	}
+}
+
+func TestTokenBucketCancel(t *testing.T) {
+	bucket := newTokenBucket(1024)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	bucket.Wait(ctx, 1024)
+	if err := bucket.Wait(ctx, 1024); err != context.Canceled {
+		t.Errorf("Wait() = %v, expected context.Canceled", err)
+	}
+}
+
+func TestMaxFileSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/small.bin":
+			w.Write(make([]byte, 100))
+		case "/big.bin":
+			w.Write(make([]byte, 2000))
+		case "/stream.bin":
+			// Размер заранее неизвестен: ответ передается частями
+			for range 4 {
+				w.Write(make([]byte, 500))
+				w.(http.Flusher).Flush()
+			}
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.NewConfig(server.URL+"/", t.TempDir(), 0, 1)
+	cfg.NoRobots = true
+	cfg.MaxFileSize = 1000
+	cfg.Quiet = true
+	d := newDownloader(t, cfg)
+
+	tests := []struct {
+		path       string
+		downloaded bool
+	}{
+		{"small.bin", true},
+		{"big.bin", false},
+		{"stream.bin", false},
+	}
+	for _, test := range tests {
+		if err := d.download(context.Background(), &Task{URL: server.URL + "/" + test.path}); err != nil {
+			t.Fatalf("download(%s): %v", test.path, err)
+		}
+		if got := downloaded(d, server, test.path); got != test.downloaded {
+			t.Errorf("%s downloaded = %v, expected %v", test.path, got, test.downloaded)
+		}
+	}
+	if summary := d.Summary(); summary.Skipped != 2 || summary.Errors != 0 {
+		t.Errorf("skipped = %d, errors = %d, expected 2 and 0", summary.Skipped, summary.Errors)
+	}
+}
+
+func TestMaxFileSizeWARC(t *testing.T) {
+	const size = 64 << 20
+	var sent atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<a href="/big.bin">big</a><a href="/stream.bin">stream</a>`))
+			return
+		case "/big.bin":
+			w.Header().Set("Content-Length", fmt.Sprint(size))
+		}
+		// Отправка прекращается, когда клиент закрывает соединение
+		chunk := make([]byte, 32*1024)
+		for written := 0; written < size; written += len(chunk) {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			sent.Add(int64(len(chunk)))
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.NewConfig(server.URL+"/", t.TempDir(), 1, 1)
+	cfg.NoRobots = true
+	cfg.MaxFileSize = 1000
+	cfg.WARCFile = filepath.Join(t.TempDir(), "crawl")
+	cfg.Quiet = true
+	if err := newDownloader(t, cfg).Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+	if sent.Load() >= size {
+		t.Errorf("%d bytes sent, expected skipped files not to be downloaded", sent.Load())
+	}
+
+	file, err := os.Open(cfg.WARCFile + ".warc.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	reader, err := warc.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncated := make(map[string]string)
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.Type() == warc.TypeResponse {
+			truncated[strings.TrimPrefix(record.Header.Get("WARC-Target-URI"), server.URL)] = record.Header.Get("WARC-Truncated")
+		}
+	}
+	expected := map[string]string{"/": "", "/big.bin": "length", "/stream.bin": "length"}
+	for path, reason := range expected {
+		if got, ok := truncated[path]; !ok || got != reason {
+			t.Errorf("%s: WARC-Truncated = %q (archived %v), expected %q", path, got, ok, reason)
+		}
+	}
+}
+
+func TestQuotaStopsCrawl(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		var links strings.Builder
+		for i := range 10 {
+			fmt.Fprintf(&links, `<a href="/page%d.html">%d</a>`, i, i)
+		}
+		w.Write([]byte(links.String()))
+	}))
+	defer server.Close()
+
+	output := t.TempDir()
+	cfg := config.NewConfig(server.URL+"/", output, 1, 1)
+	cfg.NoRobots = true
+	cfg.Quota = 1
+	cfg.Quiet = true
+	d := newDownloader(t, cfg)
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatalf("Start() = %v, expected clean stop", err)
+	}
+
+	summary := d.Summary()
+	if !summary.QuotaReached || summary.Files != 1 {
+		t.Errorf("quota reached = %v, files = %d, expected true and 1", summary.QuotaReached, summary.Files)
+	}
+
+	// Оставшиеся ссылки продолжаются с -continue
+	state, err := loadJournal(output + "/" + journalName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.pending) != 10 {
+		t.Errorf("%d pending tasks in journal, expected 10", len(state.pending))
+	}
+}
+
+func TestMaxHostConnections(t *testing.T) {
+	var active, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := active.Add(1)
+		defer active.Add(-1)
+		for {
+			previous := peak.Load()
+			if current <= previous || peak.CompareAndSwap(previous, current) {
+				break
+			}
+		}
+
+		if r.URL.Path == "/" {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<a href="/a">a</a><a href="/b">b</a><a href="/c">c</a><a href="/d">d</a>`))
+			return
+		}
+		time.Sleep(30 * time.Millisecond)
+		w.Write([]byte("data"))
+	}))
+	defer server.Close()
+
+	cfg := config.NewConfig(server.URL+"/", t.TempDir(), 1, 4)
+	cfg.NoRobots = true
+	cfg.MaxHostConnections = 1
+	cfg.Quiet = true
+	if err := newDownloader(t, cfg).Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if peak.Load() != 1 {
+		t.Errorf("peak concurrent requests = %d, expected 1", peak.Load())
+	}
+}
@@ -0,0 +1,44 @@
+package downloader
+
+import (
+	"io"
+	"log"
+)
+
+// Уровень подробности сообщений
+type logLevel int
+
+const (
+	levelError logLevel = iota // только ошибки (-quiet)
+	levelInfo                  // сохраненные файлы, перенаправления, пропуски
+	levelDebug                 // работа воркеров и найденные ссылки (-verbose)
+)
+
+// logger выводит сообщения обхода не подробнее заданного уровня
+type logger struct {
+	out   *log.Logger
+	level logLevel
+}
+
+func newLogger(output io.Writer, level logLevel) *logger {
+	return &logger{out: log.New(output, "", log.LstdFlags), level: level}
+}
+
+func (l *logger) errorf(format string, args ...any) {
+	l.printf(levelError, format, args...)
+}
+
+func (l *logger) infof(format string, args ...any) {
+	l.printf(levelInfo, format, args...)
+}
+
+func (l *logger) debugf(format string, args ...any) {
+	l.printf(levelDebug, format, args...)
+}
+
+func (l *logger) printf(level logLevel, format string, args ...any) {
+	if l == nil || level > l.level {
+		return
+	}
+	l.out.Printf(format, args...)
+}
@@ -0,0 +1,116 @@
+package downloader
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ds124wfegd/WB_L2/16/config"
+	"github.com/ds124wfegd/WB_L2/16/parser"
+)
+
+// ErrSkip, возвращенная из OnRequest или OnResponse, пропускает адрес без ошибки.
+var ErrSkip = errors.New("адрес пропущен обработчиком")
+
+// Option настраивает загрузчик, создаваемый New.
+type Option func(*options)
+
+type options struct {
+	config    *config.Config
+	storage   Storage
+	hooks     hooks
+	logOutput io.Writer
+}
+
+// Обработчики событий обхода. Вызываются из воркеров параллельно
+type hooks struct {
+	onRequest  func(*http.Request) error
+	onResponse func(*http.Response) error
+	onError    func(rawURL string, err error)
+	onLink     func(page string, link parser.Link) bool
+}
+
+// New создает загрузчик для начального адреса rawURL. Без опций скачивается только
+// сам адрес в текущий каталог одним воркером.
+func New(rawURL string, opts ...Option) (*Downloader, error) {
+	o := &options{config: config.NewConfig(rawURL, "", 0, 1)}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return newFromOptions(o)
+}
+
+// WithConfig изменяет настройки обхода: область, фильтры, ограничения, заголовки и т.д.
+func WithConfig(configure func(*config.Config)) Option {
+	return func(o *options) { configure(o.config) }
+}
+
+// WithOutput задает каталог для файлов и журнала обхода (нужен для Continue).
+func WithOutput(dir string) Option {
+	return func(o *options) { o.config.Output = dir }
+}
+
+// WithDepth задает глубину рекурсии.
+func WithDepth(depth int) Option {
+	return func(o *options) { o.config.MaxDepth = depth }
+}
+
+// WithWorkers задает число параллельных загрузок.
+func WithWorkers(workers int) Option {
+	return func(o *options) { o.config.Workers = workers }
+}
+
+// WithStorage сохраняет файлы в storage вместо каталога Output.
+func WithStorage(storage Storage) Option {
+	return func(o *options) { o.storage = storage }
+}
+
+// WithLogOutput направляет журнал загрузчика в w (io.Discard - отключить).
+func WithLogOutput(w io.Writer) Option {
+	return func(o *options) { o.logOutput = w }
+}
+
+// OnRequest вызывается перед отправкой запроса и может изменить его
+// (но не перенаправления и повторы). Ошибка прерывает обработку адреса, ErrSkip - пропускает его.
+func OnRequest(hook func(*http.Request) error) Option {
+	return func(o *options) { o.hooks.onRequest = hook }
+}
+
+// OnResponse вызывается для конечного ответа до сохранения файла; тело читать нельзя.
+// Ошибка прерывает обработку адреса, ErrSkip - пропускает его.
+func OnResponse(hook func(*http.Response) error) Option {
+	return func(o *options) { o.hooks.onResponse = hook }
+}
+
+// OnError вызывается для каждого адреса, обработка которого завершилась ошибкой.
+func OnError(hook func(rawURL string, err error)) Option {
+	return func(o *options) { o.hooks.onError = hook }
+}
+
+// OnLink фильтрует ссылки, прошедшие правила области обхода: при false ссылка
+// со страницы page не ставится в очередь.
+func OnLink(hook func(page string, link parser.Link) bool) Option {
+	return func(o *options) { o.hooks.onLink = hook }
+}
+
+// File - скачанный файл: адрес, имя в хранилище и тип содержимого.
+type File struct {
+	URL         string
+	Name        string
+	ContentType string
+}
+
+// Files возвращает скачанные файлы (без перенаправленных адресов), упорядоченные по URL.
+func (d *Downloader) Files() []File {
+	var files []File
+	d.files.Range(func(key, value any) bool {
+		file := value.(downloadedFile)
+		files = append(files, File{URL: key.(string), Name: filepath.ToSlash(file.filename), ContentType: file.contentType})
+		return true
+	})
+	slices.SortFunc(files, func(a, b File) int { return strings.Compare(a.URL, b.URL) })
+	return files
+}
@@ -0,0 +1,89 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ds124wfegd/WB_L2/16/config"
+	"github.com/ds124wfegd/WB_L2/16/parser"
+)
+
+func TestNewWithHooks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<a href="/a.html">a</a><a href="/b.html">b</a><a href="/private/c.html">c</a>` +
+				`<a href="/skip.html">skip</a><a href="/huge.html">huge</a><a href="/missing.html">missing</a>`))
+		case "/a.html", "/b.html", "/private/c.html", "/skip.html":
+			w.Write([]byte("token " + r.Header.Get("X-Token")))
+		case "/huge.html":
+			w.Header().Set("X-Huge", "1")
+			w.Write([]byte("huge"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var failed []string
+	storage := NewMemoryStorage()
+	d, err := New(server.URL+"/",
+		WithDepth(1),
+		WithWorkers(2),
+		WithStorage(storage),
+		WithLogOutput(io.Discard),
+		WithConfig(func(cfg *config.Config) { cfg.NoRobots = true }),
+		OnRequest(func(req *http.Request) error {
+			if strings.HasSuffix(req.URL.Path, "/skip.html") {
+				return ErrSkip
+			}
+			req.Header.Set("X-Token", "secret")
+			return nil
+		}),
+		OnResponse(func(resp *http.Response) error {
+			if resp.Header.Get("X-Huge") != "" {
+				return ErrSkip
+			}
+			return nil
+		}),
+		OnError(func(rawURL string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			failed = append(failed, rawURL+": "+err.Error())
+		}),
+		OnLink(func(page string, link parser.Link) bool {
+			return !strings.Contains(link.URL, "/private/")
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	host := strings.ReplaceAll(strings.TrimPrefix(server.URL, "http://"), ":", "_")
+	expected := []string{host + "/a.html", host + "/b.html", host + "/index.html", manifestName}
+	if got := storage.Files(); strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Errorf("stored files = %v, expected %v", got, expected)
+	}
+	if content, _ := storage.ReadFile(host + "/a.html"); string(content) != "token secret" {
+		t.Errorf("a.html = %q, OnRequest header not sent", content)
+	}
+
+	if len(failed) != 1 || !strings.HasPrefix(failed[0], server.URL+"/missing.html: HTTP 404") {
+		t.Errorf("OnError calls = %v, expected missing.html 404", failed)
+	}
+
+	files := d.Files()
+	if len(files) != 3 || files[0].URL != server.URL+"/" || files[0].Name != host+"/index.html" || files[0].ContentType != "text/html" {
+		t.Errorf("Files() = %+v", files)
+	}
+}
@@ -0,0 +1,166 @@
+package downloader
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	progressInterval = 200 * time.Millisecond
+	progressBarWidth = 20
+	maxProgressBars  = 8
+)
+
+// progress рисует в терминале строку состояния и полосы активных загрузок.
+// Сообщения журнала выводятся через Write над индикатором, после чего он перерисовывается.
+type progress struct {
+	mu       sync.Mutex
+	out      io.Writer
+	lines    int // строк, занятых индикатором
+	width    int
+	stats    *stats
+	queue    func() int
+	workers  int
+	rate     float64 // байт/с, сглаженное значение
+	lastTime time.Time
+	last     int64
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func newProgress(out io.Writer, stats *stats, queue func() int, workers int) *progress {
+	width := 80
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 20 {
+		width = columns
+	}
+	return &progress{
+		out:      out,
+		width:    width,
+		stats:    stats,
+		queue:    queue,
+		workers:  workers,
+		lastTime: time.Now(),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Write выводит строку журнала над индикатором.
+func (p *progress) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clear()
+	n, err := p.out.Write(b)
+	p.draw()
+	return n, err
+}
+
+// Run перерисовывает индикатор до вызова Stop.
+func (p *progress) Run() {
+	defer close(p.done)
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.mu.Lock()
+			p.clear()
+			p.draw()
+			p.mu.Unlock()
+		case <-p.stop:
+			p.mu.Lock()
+			p.clear()
+			p.mu.Unlock()
+			return
+		}
+	}
+}
+
+// Stop убирает индикатор с экрана.
+func (p *progress) Stop() {
+	close(p.stop)
+	<-p.done
+}
+
+// clear стирает строки индикатора, возвращая курсор в их начало.
+func (p *progress) clear() {
+	if p.lines == 0 {
+		return
+	}
+	fmt.Fprintf(p.out, "\r\033[%dA\033[J", p.lines)
+	p.lines = 0
+}
+
+func (p *progress) draw() {
+	now := time.Now()
+	bytes := p.stats.bytes.Load()
+	if elapsed := now.Sub(p.lastTime).Seconds(); elapsed >= progressInterval.Seconds()/2 {
+		current := float64(bytes-p.last) / elapsed
+		p.rate = 0.7*p.rate + 0.3*current
+		p.last, p.lastTime = bytes, now
+	}
+
+	var builder strings.Builder
+	transfers := p.stats.activeTransfers()
+	for i, t := range transfers {
+		if i == maxProgressBars {
+			fmt.Fprintf(&builder, "  ... еще %d\n", len(transfers)-maxProgressBars)
+			break
+		}
+		builder.WriteString(p.bar(t))
+		builder.WriteByte('\n')
+	}
+
+	summary := p.stats.summary()
+	status := fmt.Sprintf("Файлов: %d  Данные: %s  Скорость: %s/s  Очередь: %d  Активно: %d/%d",
+		summary.Files, formatBytes(bytes), formatBytes(int64(p.rate)), p.queue(), p.stats.active.Load(), p.workers)
+	if summary.Errors > 0 {
+		status += fmt.Sprintf("  Ошибок: %d", summary.Errors)
+	}
+	if runes := []rune(status); len(runes) >= p.width {
+		status = string(runes[:p.width-1])
+	}
+	builder.WriteString(status)
+	builder.WriteByte('\n')
+
+	io.WriteString(p.out, builder.String())
+	p.lines = strings.Count(builder.String(), "\n")
+}
+
+// bar возвращает строку полосы прогресса загрузки.
+func (p *progress) bar(t *transfer) string {
+	done := t.done.Load()
+	var line string
+	if t.total > 0 {
+		fraction := min(float64(done)/float64(t.total), 1)
+		filled := int(fraction * progressBarWidth)
+		arrow := ""
+		if filled < progressBarWidth {
+			arrow = ">"
+		}
+		line = fmt.Sprintf("%3.0f%% [%s%s%s] %s/%s  ", fraction*100, strings.Repeat("=", filled), arrow,
+			strings.Repeat(" ", max(progressBarWidth-filled-len(arrow), 0)), formatBytes(done), formatBytes(t.total))
+	} else {
+		// Размер неизвестен: бегущий маркер
+		position := int(done/16384) % (progressBarWidth - 2)
+		line = fmt.Sprintf("     [%s<=>%s] %s  ", strings.Repeat(" ", position),
+			strings.Repeat(" ", progressBarWidth-3-position), formatBytes(done))
+	}
+	return line + truncateName(t.name, p.width-1-len([]rune(line)))
+}
+
+// truncateName обрезает имя до width символов, сохраняя его конец.
+func truncateName(name string, width int) string {
+	runes := []rune(name)
+	if len(runes) <= width || width < 4 {
+		return name
+	}
+	return "..." + string(runes[len(runes)-width+3:])
+}
@@ -0,0 +1,165 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ds124wfegd/WB_L2/16/parser"
+)
+
+const (
+	// Максимальная длина цепочки перенаправлений
+	maxRedirects = 10
+
+	// Верхняя граница паузы между повторами (в том числе заданной Retry-After)
+	maxRetryDelay = 5 * time.Minute
+)
+
+// Перенаправление на адрес вне области обхода (другой хост, каталог, robots.txt)
+var errRedirectOutOfScope = errors.New("перенаправление за пределы области обхода")
+
+// Цепочка перенаправлений одного запроса; передается в CheckRedirect через контекст запроса
+type redirectChain struct {
+	task *Task
+	urls []string
+}
+
+type redirectChainKey struct{}
+
+// fetch выполняет запрос, повторяя его с экспоненциальной паузой при сетевых ошибках,
+// ответах 5xx и 429. Возвращает ответ и адреса, по которым он был перенаправлен.
+func (d *Downloader) fetch(ctx context.Context, req *http.Request, task *Task) (*http.Response, []string, error) {
+	for attempt := 0; ; attempt++ {
+		chain := &redirectChain{task: task}
+		attemptReq := req.Clone(context.WithValue(ctx, redirectChainKey{}, chain))
+		// Тело POST-запроса читается заново при каждом повторе
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, nil, err
+			}
+			attemptReq.Body = body
+		}
+		resp, err := d.client.Do(attemptReq)
+
+		retry, delay := d.retryDelay(ctx, resp, err, attempt)
+		if !retry || attempt >= d.config.Retries {
+			return resp, chain.urls, err
+		}
+
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		}
+		d.log.infof("Повтор %d/%d для %s через %v: %s", attempt+1, d.config.Retries, task.URL, delay.Round(time.Millisecond), reason)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+// retryDelay определяет, нужно ли повторить запрос, и паузу перед повтором.
+func (d *Downloader) retryDelay(ctx context.Context, resp *http.Response, err error, attempt int) (bool, time.Duration) {
+	if err != nil {
+		if ctx.Err() != nil || errors.Is(err, errRedirectOutOfScope) {
+			return false, 0
+		}
+		return true, d.backoff(attempt)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		if delay, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return true, min(delay, maxRetryDelay)
+		}
+		return true, d.backoff(attempt)
+	case resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented:
+		return true, d.backoff(attempt)
+	}
+	return false, 0
+}
+
+// backoff возвращает паузу base*2^attempt со случайным разбросом в половину ее величины.
+func (d *Downloader) backoff(attempt int) time.Duration {
+	delay := d.config.RetryBackoff << min(attempt, 20)
+	if delay <= 0 || delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay/2 + time.Duration(rand.Int64N(int64(delay/2)+1))
+}
+
+// retryAfter разбирает заголовок Retry-After: число секунд или дату HTTP.
+func retryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// checkRedirect запоминает цепочку перенаправлений и применяет к каждому адресу
+// правила области обхода и robots.txt.
+func (d *Downloader) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("слишком много перенаправлений (%d)", len(via))
+	}
+
+	// Учетные данные отправляются только хосту начального URL, в том числе после
+	// перенаправления (http.Client сохраняет Authorization для того же хоста с другим портом)
+	if d.seed != nil && !strings.EqualFold(req.URL.Host, d.seed.Host) && (d.config.BearerToken != "" || d.config.HTTPUser != "") {
+		req.Header.Del("Authorization")
+	}
+
+	chain, ok := req.Context().Value(redirectChainKey{}).(*redirectChain)
+	if !ok {
+		return nil
+	}
+
+	target := *req.URL
+	target.Fragment = ""
+	chain.urls = append(chain.urls, target.String())
+
+	// Перенаправление начального URL (например, на www.) задает новую область обхода;
+	// карты сайта тоже могут лежать на другом хосте
+	isSeed := d.seeds[chain.task.URL] || chain.task.Kind == parser.KindSitemap
+	if !isSeed && !d.allowed(parser.Link{URL: target.String(), Kind: chain.task.Kind}, chain.task.Requisite) {
+		return fmt.Errorf("%w: %s", errRedirectOutOfScope, target.String())
+	}
+	if !d.config.NoRobots && !d.robotsFor(req.Context(), &target).Allowed(target.RequestURI()) {
+		return fmt.Errorf("%w (robots.txt): %s", errRedirectOutOfScope, target.String())
+	}
+	return nil
+}
+
+// resolveRedirects возвращает конечный адрес ссылки с учетом записанных перенаправлений.
+func (d *Downloader) resolveRedirects(rawURL string) string {
+	for i := 0; i < maxRedirects; i++ {
+		target, ok := d.redirects.Load(rawURL)
+		if !ok {
+			break
+		}
+		rawURL = target.(string)
+	}
+	return rawURL
+}
@@ -0,0 +1,185 @@
+package downloader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ds124wfegd/WB_L2/16/config"
+)
+
+func newRetryDownloader(t *testing.T, server *httptest.Server, retries int) *Downloader {
+	t.Helper()
+	cfg := config.NewConfig(server.URL+"/", t.TempDir(), 0, 1)
+	cfg.NoRobots = true
+	cfg.Retries = retries
+	cfg.RetryBackoff = 10 * time.Millisecond
+	return newDownloader(t, cfg)
+}
+
+func TestRetryServerErrors(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= 2 {
+			http.Error(w, "busy", http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	d := newRetryDownloader(t, server, 3)
+	if err := d.download(context.Background(), &Task{URL: server.URL + "/file.dat"}); err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	if requests.Load() != 3 || !downloaded(d, server, "file.dat") {
+		t.Errorf("expected success on third request, got %d requests", requests.Load())
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Error(w, "broken", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	d := newRetryDownloader(t, server, 2)
+	if err := d.download(context.Background(), &Task{URL: server.URL + "/file.dat"}); err == nil {
+		t.Fatalf("download must fail after retries")
+	}
+	if requests.Load() != 3 {
+		t.Errorf("expected 3 requests (1 + 2 retries), got %d", requests.Load())
+	}
+}
+
+func TestNoRetryOnClientErrors(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	d := newRetryDownloader(t, server, 3)
+	d.download(context.Background(), &Task{URL: server.URL + "/missing.dat"})
+	if requests.Load() != 1 {
+		t.Errorf("404 must not be retried, got %d requests", requests.Load())
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header   string
+		expected time.Duration
+		ok       bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"0", 0, true},
+		{"Wed, 01 May 2024 12:00:10 GMT", 10 * time.Second, true},
+		{"Wed, 01 May 2024 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, test := range tests {
+		delay, ok := retryAfter(test.header, now)
+		if delay != test.expected || ok != test.ok {
+			t.Errorf("retryAfter(%q) = %v, %v; expected %v, %v", test.header, delay, ok, test.expected, test.ok)
+		}
+	}
+}
+
+func TestTooManyRequestsHonoursRetryAfter(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	d := newRetryDownloader(t, server, 1)
+	start := time.Now()
+	if err := d.download(context.Background(), &Task{URL: server.URL + "/file.dat"}); err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retry after 429 happened after %v, expected Retry-After of 1s", elapsed)
+	}
+}
+
+func TestBackoffGrowsWithJitter(t *testing.T) {
+	d := newDownloader(t, config.NewConfig("http://example.com/", t.TempDir(), 0, 1))
+	d.config.RetryBackoff = 100 * time.Millisecond
+
+	for attempt := 0; attempt < 4; attempt++ {
+		full := 100 * time.Millisecond << attempt
+		for i := 0; i < 50; i++ {
+			if delay := d.backoff(attempt); delay < full/2 || delay > full {
+				t.Fatalf("backoff(%d) = %v, expected within [%v, %v]", attempt, delay, full/2, full)
+			}
+		}
+	}
+}
+
+func TestRedirectStoredUnderFinalURL(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("external"))
+	}))
+	defer other.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old.html":
+			http.Redirect(w, r, "/moved/step.html", http.StatusMovedPermanently)
+		case "/moved/step.html":
+			http.Redirect(w, r, "/new/page.html", http.StatusFound)
+		case "/new/page.html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<a href="sibling.html">sibling</a>`))
+		case "/external.html":
+			http.Redirect(w, r, other.URL+"/page.html", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	d := newRetryDownloader(t, server, 0)
+	if err := d.download(context.Background(), &Task{URL: server.URL + "/old.html", Depth: 0}); err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	if !downloaded(d, server, "new/page.html") || downloaded(d, server, "old.html") {
+		t.Errorf("redirected page must be stored under the final URL")
+	}
+	if final := d.resolveRedirects(server.URL + "/old.html"); final != server.URL+"/new/page.html" {
+		t.Errorf("resolveRedirects = %s", final)
+	}
+
+	// Ссылки страницы разрешаются от конечного адреса
+	d.config.MaxDepth = 1
+	d.visited.Delete(server.URL + "/new/page.html")
+	d.visited.Delete(server.URL + "/old.html")
+	d.download(context.Background(), &Task{URL: server.URL + "/old.html"})
+	task, ok := d.frontier.Pop()
+	if !ok || task.URL != server.URL+"/new/sibling.html" {
+		t.Errorf("expected sibling link resolved against final URL, got %v", task)
+	}
+
+	if err := d.download(context.Background(), &Task{URL: server.URL + "/external.html"}); err != nil {
+		t.Fatalf("download external: %v", err)
+	}
+	otherHost := other.URL[len("http://"):]
+	if _, stored := d.files.Load(other.URL + "/page.html"); stored {
+		t.Errorf("redirect to %s must be out of scope", otherHost)
+	}
+}
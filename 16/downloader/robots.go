@@ -0,0 +1,117 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ds124wfegd/WB_L2/16/robots"
+)
+
+// Максимальный размер читаемого robots.txt
+const maxRobotsSize = 512 * 1024
+
+// robots.txt хоста, загружаемый один раз при первом обращении
+type robotsEntry struct {
+	once  sync.Once
+	rules *robots.Rules
+}
+
+// Ограничитель частоты запросов: для каждого хоста хранится момент, раньше которого
+// следующий запрос отправлять нельзя
+type hostLimiter struct {
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+func newHostLimiter() *hostLimiter {
+	return &hostLimiter{next: make(map[string]time.Time)}
+}
+
+// Wait ждет своей очереди к хосту и резервирует за собой интервал interval.
+// Возвращает ошибку ctx, если ожидание прервано.
+func (l *hostLimiter) Wait(ctx context.Context, host string, interval time.Duration) error {
+	if interval <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next[host]
+	if start.Before(now) {
+		start = now
+	}
+	l.next[host] = start.Add(interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(start))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// robotsFor возвращает правила robots.txt для хоста ссылки (загружая их при первом обращении).
+func (d *Downloader) robotsFor(ctx context.Context, target *url.URL) *robots.Rules {
+	origin := target.Scheme + "://" + target.Host
+
+	d.robotsMu.Lock()
+	entry, ok := d.robots[origin]
+	if !ok {
+		entry = &robotsEntry{}
+		d.robots[origin] = entry
+	}
+	d.robotsMu.Unlock()
+
+	entry.once.Do(func() {
+		entry.rules = d.fetchRobots(ctx, origin)
+	})
+	return entry.rules
+}
+
+// fetchRobots загружает robots.txt. Если файла нет или он недоступен, ограничений нет.
+func (d *Downloader) fetchRobots(ctx context.Context, origin string) *robots.Rules {
+	userAgent := d.config.UserAgent
+	req, err := d.newRequest(ctx, origin+"/robots.txt", nil)
+	if err != nil {
+		return robots.Parse("", userAgent)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		d.log.infof("Не удалось получить robots.txt для %s: %v", origin, err)
+		return robots.Parse("", userAgent)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return robots.Parse("", userAgent)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsSize))
+	if err != nil {
+		d.log.errorf("Ошибка чтения robots.txt для %s: %v", origin, err)
+		return robots.Parse("", userAgent)
+	}
+	return robots.Parse(string(content), userAgent)
+}
+
+// requestInterval возвращает паузу перед следующим запросом к хосту: наибольшее из --wait
+// и Crawl-delay, а с --random-wait - случайное значение от 0.5 до 1.5 этой величины.
+func (d *Downloader) requestInterval(rules *robots.Rules) time.Duration {
+	interval := d.config.Wait
+	if rules != nil {
+		interval = max(interval, rules.CrawlDelay())
+	}
+	if d.config.RandomWait {
+		interval = time.Duration(float64(interval) * (0.5 + rand.Float64()))
+	}
+	return interval
+}
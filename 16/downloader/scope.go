@@ -0,0 +1,177 @@
+package downloader
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/ds124wfegd/WB_L2/16/parser"
+)
+
+// Расширения страниц, которые скачиваются ради ссылок независимо от -accept/-reject
+var pageExtensions = []string{"", ".html", ".htm", ".shtml", ".xhtml", ".php", ".asp", ".aspx", ".jsp"}
+
+// allowed решает, входит ли ссылка в область обхода. Реквизиты страницы (при -page-requisites)
+// не ограничиваются каталогами и -no-parent, но подчиняются правилам хостов и фильтрам файлов.
+func (d *Downloader) allowed(link parser.Link, requisite bool) bool {
+	target, err := url.Parse(link.URL)
+	if err != nil {
+		return false
+	}
+
+	if !d.allowedHost(target) {
+		return false
+	}
+	if !requisite && !d.allowedDirectory(target) {
+		return false
+	}
+	return d.acceptedFile(link, target)
+}
+
+// follow решает, ставить ли в очередь ссылку со страницы page: ссылка должна входить
+// в область обхода и пройти фильтр OnLink.
+func (d *Downloader) follow(page string, link parser.Link, requisite bool) bool {
+	if !d.allowed(link, requisite) {
+		return false
+	}
+	return d.hooks.onLink == nil || d.hooks.onLink(page, link)
+}
+
+// scopeRoot возвращает начальный URL, от которого отсчитывается область обхода:
+// после перенаправления начального адреса - конечный адрес цепочки.
+func (d *Downloader) scopeRoot() *url.URL {
+	d.scopeMu.RLock()
+	defer d.scopeMu.RUnlock()
+	return d.scopeSeed
+}
+
+// allowedHost разрешает хосты начальных адресов, хосты из -domains (вместе с поддоменами),
+// а с -span-hosts без -domains - любые хосты.
+func (d *Downloader) allowedHost(target *url.URL) bool {
+	if seed := d.scopeRoot(); seed != nil && target.Host == seed.Host || d.seedHosts[target.Host] {
+		return true
+	}
+
+	host := strings.ToLower(target.Hostname())
+	for _, domain := range d.config.Domains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return d.config.SpanHosts && len(d.config.Domains) == 0
+}
+
+// allowedDirectory применяет -no-parent, -include-directories и -exclude-directories.
+func (d *Downloader) allowedDirectory(target *url.URL) bool {
+	urlPath := target.Path
+	if urlPath == "" {
+		urlPath = "/"
+	}
+
+	if seed := d.scopeRoot(); d.config.NoParent && seed != nil && target.Host == seed.Host {
+		parent := seed.Path
+		if !strings.HasSuffix(parent, "/") {
+			parent = path.Dir(parent)
+		}
+		if !inDirectory(urlPath, parent) {
+			return false
+		}
+	}
+
+	if len(d.config.IncludeDirectories) > 0 {
+		included := false
+		for _, directory := range d.config.IncludeDirectories {
+			if inDirectory(urlPath, directory) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+
+	for _, directory := range d.config.ExcludeDirectories {
+		if inDirectory(urlPath, directory) {
+			return false
+		}
+	}
+	return true
+}
+
+// acceptedFile применяет -accept/-reject к имени файла и -accept-regex/-reject-regex ко всему URL.
+// Списки суффиксов не действуют на HTML-страницы, иначе обход не сможет найти нужные файлы.
+func (d *Downloader) acceptedFile(link parser.Link, target *url.URL) bool {
+	if d.config.AcceptRegex != nil && !d.config.AcceptRegex.MatchString(link.URL) {
+		return false
+	}
+	if d.config.RejectRegex != nil && d.config.RejectRegex.MatchString(link.URL) {
+		return false
+	}
+
+	// Карты сайта и ленты, как и страницы, нужны ради ссылок
+	name := path.Base(target.Path)
+	if link.Kind == parser.KindPage && isPageExtension(path.Ext(name)) || link.Kind == parser.KindSitemap || link.Kind == parser.KindFeed {
+		return true
+	}
+
+	if len(d.config.Accept) > 0 && !matchesFileList(name, d.config.Accept) {
+		return false
+	}
+	return !matchesFileList(name, d.config.Reject)
+}
+
+// inDirectory проверяет, лежит ли путь в каталоге directory. Каталог может содержать
+// шаблоны (*, ?, [...]), которые сопоставляются с соответствующими компонентами пути.
+func inDirectory(urlPath, directory string) bool {
+	directory = "/" + strings.Trim(directory, "/")
+	if directory == "/" {
+		return true
+	}
+
+	if !strings.ContainsAny(directory, "*?[") {
+		return urlPath == directory || strings.HasPrefix(urlPath, directory+"/")
+	}
+
+	patternParts := strings.Split(directory, "/")
+	pathParts := strings.Split(urlPath, "/")
+	if len(pathParts) < len(patternParts) {
+		return false
+	}
+	for i, pattern := range patternParts {
+		if matched, err := path.Match(pattern, pathParts[i]); err != nil || !matched {
+			return false
+		}
+	}
+	// Последний компонент совпал с шаблоном каталога, но сам является файлом
+	return len(pathParts) > len(patternParts) || strings.HasSuffix(urlPath, "/")
+}
+
+// matchesFileList сопоставляет имя файла со списком суффиксов ("jpg", ".png") или шаблонов ("*.tar.gz").
+func matchesFileList(name string, list []string) bool {
+	lower := strings.ToLower(name)
+	for _, item := range list {
+		item = strings.ToLower(item)
+		if strings.ContainsAny(item, "*?[") {
+			if matched, _ := path.Match(item, lower); matched {
+				return true
+			}
+			continue
+		}
+		if strings.HasSuffix(lower, item) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPageExtension(extension string) bool {
+	extension = strings.ToLower(extension)
+	for _, page := range pageExtensions {
+		if extension == page {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package downloader
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/ds124wfegd/WB_L2/16/config"
+	"github.com/ds124wfegd/WB_L2/16/parser"
+)
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(cfg *config.Config)
+		link      parser.Link
+		requisite bool
+		allowed   bool
+	}{
+		{"same host", nil, parser.Link{URL: "http://example.com/a.html"}, false, true},
+		{"other host", nil, parser.Link{URL: "http://cdn.example.net/a.png", Kind: parser.KindImage}, false, false},
+		{"other port", nil, parser.Link{URL: "http://example.com:8080/a.html"}, false, false},
+		{"span hosts", func(cfg *config.Config) { cfg.SpanHosts = true }, parser.Link{URL: "http://other.org/"}, false, true},
+		{"apex in domains", func(cfg *config.Config) { cfg.Domains = []string{"example.com"} }, parser.Link{URL: "http://www.example.com/"}, false, true},
+		{"subdomain in domains", func(cfg *config.Config) { cfg.Domains = []string{"example.com"} }, parser.Link{URL: "http://static.example.com/x.css", Kind: parser.KindStylesheet}, false, true},
+		{"suffix is not subdomain", func(cfg *config.Config) { cfg.Domains = []string{"example.com"} }, parser.Link{URL: "http://badexample.com/"}, false, false},
+		{"domains limit span hosts", func(cfg *config.Config) { cfg.SpanHosts = true; cfg.Domains = []string{"example.net"} }, parser.Link{URL: "http://other.org/"}, false, false},
+		{"no parent inside", func(cfg *config.Config) { cfg.NoParent = true }, parser.Link{URL: "http://example.com/docs/guide/b.html"}, false, true},
+		{"no parent outside", func(cfg *config.Config) { cfg.NoParent = true }, parser.Link{URL: "http://example.com/blog/"}, false, false},
+		{"no parent prefix", func(cfg *config.Config) { cfg.NoParent = true }, parser.Link{URL: "http://example.com/docs-old/"}, false, false},
+		{"no parent requisite", func(cfg *config.Config) { cfg.NoParent = true }, parser.Link{URL: "http://example.com/static/a.png", Kind: parser.KindImage}, true, true},
+		{"include directory", func(cfg *config.Config) { cfg.IncludeDirectories = []string{"/docs"} }, parser.Link{URL: "http://example.com/docs/a.html"}, false, true},
+		{"include directory miss", func(cfg *config.Config) { cfg.IncludeDirectories = []string{"/docs"} }, parser.Link{URL: "http://example.com/blog/a.html"}, false, false},
+		{"include wildcard", func(cfg *config.Config) { cfg.IncludeDirectories = []string{"/v*/api"} }, parser.Link{URL: "http://example.com/v2/api/x.html"}, false, true},
+		{"exclude directory", func(cfg *config.Config) { cfg.ExcludeDirectories = []string{"/docs/old"} }, parser.Link{URL: "http://example.com/docs/old/a.html"}, false, false},
+		{"accept extension", func(cfg *config.Config) { cfg.Accept = []string{"jpg", "png"} }, parser.Link{URL: "http://example.com/a.PNG", Kind: parser.KindImage}, false, true},
+		{"accept miss", func(cfg *config.Config) { cfg.Accept = []string{"jpg", "png"} }, parser.Link{URL: "http://example.com/a.gif", Kind: parser.KindImage}, false, false},
+		{"accept keeps pages", func(cfg *config.Config) { cfg.Accept = []string{"jpg"} }, parser.Link{URL: "http://example.com/docs/index.html"}, false, true},
+		{"accept page link to file", func(cfg *config.Config) { cfg.Accept = []string{"jpg"} }, parser.Link{URL: "http://example.com/file.zip"}, false, false},
+		{"reject pattern", func(cfg *config.Config) { cfg.Reject = []string{"*.tar.gz"} }, parser.Link{URL: "http://example.com/src.tar.gz"}, false, false},
+		{"reject regex", func(cfg *config.Config) { cfg.RejectRegex = regexp.MustCompile(`[?&]sort=`) }, parser.Link{URL: "http://example.com/list?sort=asc"}, false, false},
+		{"accept regex", func(cfg *config.Config) { cfg.AcceptRegex = regexp.MustCompile(`/docs/`) }, parser.Link{URL: "http://example.com/blog/"}, false, false},
+	}
+
+	for _, test := range tests {
+		cfg := config.NewConfig("http://example.com/docs/index.html", t.TempDir(), 1, 1)
+		if test.configure != nil {
+			test.configure(cfg)
+		}
+		d := newDownloader(t, cfg)
+
+		if allowed := d.allowed(test.link, test.requisite); allowed != test.allowed {
+			t.Errorf("%s: allowed(%s) = %v, expected %v", test.name, test.link.URL, allowed, test.allowed)
+		}
+	}
+}
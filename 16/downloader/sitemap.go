@@ -0,0 +1,138 @@
+package downloader
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/ds124wfegd/WB_L2/16/parser"
+)
+
+// discoverSitemaps ставит в очередь карты сайта начальных хостов: из строк Sitemap в robots.txt,
+// а если их нет (или robots.txt не используется) - /sitemap.xml.
+func (d *Downloader) discoverSitemaps(ctx context.Context) {
+	origins := make(map[string]bool)
+	for _, seed := range d.seedURLs() {
+		target, err := url.Parse(seed)
+		if err != nil || origins[target.Scheme+"://"+target.Host] {
+			continue
+		}
+		origin := target.Scheme + "://" + target.Host
+		origins[origin] = true
+
+		var sitemaps []string
+		if !d.config.NoRobots {
+			robotsURL, _ := url.Parse(origin + "/robots.txt")
+			for _, sitemap := range d.robotsFor(ctx, target).Sitemaps() {
+				if resolved := parser.ResolveURL(sitemap, robotsURL); resolved != "" {
+					sitemaps = append(sitemaps, resolved)
+				}
+			}
+		}
+		if len(sitemaps) == 0 {
+			sitemaps = []string{origin + "/sitemap.xml"}
+		}
+		for _, sitemap := range sitemaps {
+			d.log.debugf("Карта сайта: %s", sitemap)
+			d.addTask(&Task{URL: sitemap, Kind: parser.KindSitemap})
+		}
+	}
+}
+
+// parseSitemap ставит в очередь адреса из карты сайта как начальные (глубина карты),
+// а вложенные карты индекса - как новые карты. Недавно измененные адреса скачиваются раньше.
+func (d *Downloader) parseSitemap(name string, task *Task) ([]parser.Link, error) {
+	content, err := d.storage.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	base, _ := url.Parse(task.URL)
+	sitemap, err := parser.ParseSitemap(content, base)
+	if err != nil {
+		return nil, err
+	}
+
+	var links []parser.Link
+	nested := 0
+	for _, entry := range sitemap.Sitemaps {
+		link := parser.Link{URL: entry.URL, Kind: parser.KindSitemap}
+		links = append(links, link)
+		if target, err := url.Parse(entry.URL); err != nil || !d.allowedHost(target) {
+			continue
+		}
+		if d.hooks.onLink != nil && !d.hooks.onLink(task.URL, link) {
+			continue
+		}
+		if _, visited := d.visited.Load(entry.URL); !visited {
+			d.addTask(&Task{URL: entry.URL, Depth: task.Depth, Kind: parser.KindSitemap, Priority: priority(entry)})
+			nested++
+		}
+	}
+
+	count := 0
+	for _, entry := range sitemap.Pages {
+		link := parser.Link{URL: entry.URL, Kind: parser.KindPage}
+		links = append(links, link)
+		if !d.follow(task.URL, link, false) {
+			continue
+		}
+		if _, visited := d.visited.Load(entry.URL); !visited {
+			d.addTask(&Task{URL: entry.URL, Depth: task.Depth, Kind: parser.KindPage, Priority: priority(entry)})
+			count++
+		}
+	}
+
+	d.log.infof("Карта сайта %s: %d адресов, %d вложенных карт", task.URL, count, nested)
+	return links, nil
+}
+
+// parseFeed ставит в очередь записи ленты RSS или Atom как ссылки страницы.
+func (d *Downloader) parseFeed(name string, task *Task) ([]parser.Link, error) {
+	content, err := d.storage.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	base, _ := url.Parse(task.URL)
+	entries, err := parser.ParseFeed(content, base)
+	if err != nil {
+		return nil, err
+	}
+
+	var links []parser.Link
+	count := 0
+	for _, entry := range entries {
+		link := parser.Link{URL: entry.URL, Kind: parser.KindPage}
+		links = append(links, link)
+		if task.Depth >= d.config.MaxDepth || d.linkLimitReached(count) || !d.follow(task.URL, link, false) {
+			continue
+		}
+		if _, visited := d.visited.Load(entry.URL); !visited {
+			d.addTask(&Task{URL: entry.URL, Depth: task.Depth + 1, Kind: parser.KindPage, Priority: priority(entry)})
+			count++
+		}
+	}
+
+	d.log.infof("Лента %s: %d новых записей", task.URL, count)
+	return links, nil
+}
+
+// priority возвращает приоритет адреса по дате изменения: более свежие раньше,
+// адреса без даты - наравне со ссылками со страниц.
+func priority(entry parser.DatedURL) int64 {
+	if entry.LastModified.IsZero() {
+		return 0
+	}
+	return max(entry.LastModified.Unix(), 1)
+}
+
+// seedURLs возвращает начальные адреса: URL и адреса из списка -i.
+func (d *Downloader) seedURLs() []string {
+	seeds := []string{d.config.URL}
+	for _, seed := range d.config.Seeds {
+		if seed != d.config.URL {
+			seeds = append(seeds, seed)
+		}
+	}
+	return seeds
+}
@@ -0,0 +1,161 @@
+package downloader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ds124wfegd/WB_L2/16/config"
+)
+
+func TestSitemapDiscovery(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+		<url><loc>/old.html</loc><lastmod>2020-01-01</lastmod></url>
+		<url><loc>/new.html</loc><lastmod>2024-01-01</lastmod></url>
+		<url><loc>/undated.html</loc></url>
+		<url><loc>http://other.invalid/page.html</loc></url>
+	</urlset>`))
+	writer.Close()
+
+	var mu sync.Mutex
+	var order []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		order = append(order, r.URL.Path)
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow:\nSitemap: /index.xml\n"))
+		case "/index.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			w.Write([]byte(`<sitemapindex><sitemap><loc>/pages.xml.gz</loc></sitemap></sitemapindex>`))
+		case "/pages.xml.gz":
+			w.Header().Set("Content-Type", "application/gzip")
+			w.Write(compressed.Bytes())
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<p>page</p>"))
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.NewConfig(server.URL+"/", t.TempDir(), 0, 1)
+	cfg.Sitemaps = true
+	cfg.Quiet = true
+	d := newDownloader(t, cfg)
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"new.html", "old.html", "undated.html"} {
+		if !downloaded(d, server, path) {
+			t.Errorf("%s from sitemap was not downloaded", path)
+		}
+	}
+
+	// Страницы с более поздней датой изменения скачиваются раньше
+	position := make(map[string]int)
+	for i, path := range order {
+		position[path] = i
+	}
+	if !(position["/new.html"] < position["/old.html"] && position["/old.html"] < position["/undated.html"]) {
+		t.Errorf("unexpected request order: %v", order)
+	}
+}
+
+func TestDefaultSitemapMissing(t *testing.T) {
+	var robotsRequests = 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Write([]byte("root"))
+			return
+		}
+		if r.URL.Path == "/robots.txt" {
+			robotsRequests++
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	cfg := config.NewConfig(server.URL+"/", t.TempDir(), 0, 1)
+	cfg.Sitemaps = true
+	cfg.NoRobots = true
+	cfg.Quiet = true
+	d := newDownloader(t, cfg)
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if summary := d.Summary(); summary.Errors != 0 {
+		t.Errorf("missing /sitemap.xml counted as error: %v", summary.ErrorsByKind)
+	}
+	if robotsRequests != 0 {
+		t.Errorf("robots.txt requested with -no-robots")
+	}
+}
+
+func TestFeedLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<link rel="alternate" type="application/atom+xml" href="/feed">`))
+		case "/feed":
+			w.Header().Set("Content-Type", "application/atom+xml")
+			w.Write([]byte(`<feed><entry><link href="/post.html"/></entry></feed>`))
+		default:
+			w.Write([]byte("post"))
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.NewConfig(server.URL+"/", t.TempDir(), 2, 1)
+	cfg.NoRobots = true
+	cfg.Quiet = true
+	d := newDownloader(t, cfg)
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !downloaded(d, server, "post.html") {
+		t.Error("feed entry was not downloaded")
+	}
+}
+
+func TestInputSeeds(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<a href="/linked.html">linked</a>`))
+			return
+		}
+		w.Write([]byte("linked"))
+	})
+	first := httptest.NewServer(handler)
+	defer first.Close()
+	second := httptest.NewServer(handler)
+	defer second.Close()
+
+	cfg := config.NewConfig(first.URL+"/", t.TempDir(), 1, 2)
+	cfg.Seeds = []string{first.URL + "/", second.URL + "/"}
+	cfg.NoRobots = true
+	cfg.Quiet = true
+	d := newDownloader(t, cfg)
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Хосты всех начальных адресов входят в область обхода
+	for _, server := range []*httptest.Server{first, second} {
+		if !downloaded(d, server, "linked.html") {
+			t.Errorf("%s: linked page was not downloaded", strings.TrimPrefix(server.URL, "http://"))
+		}
+	}
+}
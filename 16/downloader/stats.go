@@ -0,0 +1,271 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Количество самых медленных загрузок в итогах
+const slowestCount = 5
+
+// Ответ сервера с кодом ошибки
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("HTTP %d", e.code)
+}
+
+// Summary - итоги обхода
+type Summary struct {
+	Duration     time.Duration    `json:"-"`
+	Seconds      float64          `json:"duration_seconds"`
+	Files        int              `json:"files"`
+	NotModified  int              `json:"not_modified"`
+	Bytes        int64            `json:"bytes"`
+	Skipped      int              `json:"skipped_too_large"`
+	QuotaReached bool             `json:"quota_exceeded"`
+	Errors       int              `json:"errors"`
+	ErrorsByKind map[string]int   `json:"errors_by_status"` // код HTTP или "network"
+	Slowest      []SlowURL        `json:"slowest"`
+	ContentTypes map[string]int64 `json:"bytes_by_content_type"`
+}
+
+// SlowURL - длительность загрузки одного адреса
+type SlowURL struct {
+	URL      string        `json:"url"`
+	Duration time.Duration `json:"-"`
+	Seconds  float64       `json:"seconds"`
+}
+
+// Загрузка файла, отображаемая индикатором прогресса
+type transfer struct {
+	name  string
+	total int64 // -1, если размер неизвестен
+	done  atomic.Int64
+}
+
+// Чтение тела ответа с подсчетом байтов для статистики
+type transferReader struct {
+	reader   io.Reader
+	transfer *transfer
+	stats    *stats
+}
+
+func (r *transferReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.transfer.done.Add(int64(n))
+	r.stats.bytes.Add(int64(n))
+	return n, err
+}
+
+// stats собирает статистику обхода для индикатора прогресса и итогов
+type stats struct {
+	started time.Time
+	bytes   atomic.Int64
+	active  atomic.Int32 // задачи в работе у воркеров
+
+	quotaExceeded atomic.Bool
+
+	mu           sync.Mutex
+	files        int
+	notModified  int
+	skipped      int
+	errors       map[string]int
+	contentTypes map[string]int64
+	slowest      []SlowURL
+	transfers    []*transfer
+}
+
+func newStats() *stats {
+	return &stats{
+		started:      time.Now(),
+		errors:       make(map[string]int),
+		contentTypes: make(map[string]int64),
+	}
+}
+
+// startTransfer регистрирует загрузку для индикатора прогресса.
+func (s *stats) startTransfer(name string, total int64) *transfer {
+	t := &transfer{name: name, total: total}
+	s.mu.Lock()
+	s.transfers = append(s.transfers, t)
+	s.mu.Unlock()
+	return t
+}
+
+func (s *stats) finishTransfer(t *transfer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, active := range s.transfers {
+		if active == t {
+			s.transfers = append(s.transfers[:i], s.transfers[i+1:]...)
+			break
+		}
+	}
+}
+
+// activeTransfers возвращает текущие загрузки в порядке начала.
+func (s *stats) activeTransfers() []*transfer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*transfer(nil), s.transfers...)
+}
+
+// fileSaved учитывает сохраненный файл: размер по типу содержимого и время загрузки.
+func (s *stats) fileSaved(rawURL, contentType string, size int64, duration time.Duration) {
+	mimeType, _, _ := strings.Cut(contentType, ";")
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	if mimeType == "" {
+		mimeType = "unknown"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.files++
+	s.contentTypes[mimeType] += size
+
+	s.slowest = append(s.slowest, SlowURL{URL: rawURL, Duration: duration, Seconds: duration.Seconds()})
+	sort.SliceStable(s.slowest, func(i, j int) bool {
+		return s.slowest[i].Duration > s.slowest[j].Duration
+	})
+	if len(s.slowest) > slowestCount {
+		s.slowest = s.slowest[:slowestCount]
+	}
+}
+
+// fileSkipped учитывает файл, пропущенный из-за -max-file-size.
+func (s *stats) fileSkipped() {
+	s.mu.Lock()
+	s.skipped++
+	s.mu.Unlock()
+}
+
+func (s *stats) fileNotModified() {
+	s.mu.Lock()
+	s.notModified++
+	s.mu.Unlock()
+}
+
+// failed учитывает ошибку загрузки по коду ответа (или "network" для сетевых ошибок).
+func (s *stats) failed(err error) {
+	kind := "network"
+	var status *statusError
+	if errors.As(err, &status) {
+		kind = strconv.Itoa(status.code)
+	}
+
+	s.mu.Lock()
+	s.errors[kind]++
+	s.mu.Unlock()
+}
+
+func (s *stats) summary() Summary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	duration := time.Since(s.started)
+	summary := Summary{
+		Duration:     duration,
+		Seconds:      duration.Seconds(),
+		Files:        s.files,
+		NotModified:  s.notModified,
+		Skipped:      s.skipped,
+		QuotaReached: s.quotaExceeded.Load(),
+		Bytes:        s.bytes.Load(),
+		ErrorsByKind: make(map[string]int, len(s.errors)),
+		Slowest:      append([]SlowURL{}, s.slowest...),
+		ContentTypes: make(map[string]int64, len(s.contentTypes)),
+	}
+	for kind, count := range s.errors {
+		summary.ErrorsByKind[kind] = count
+		summary.Errors += count
+	}
+	for mimeType, size := range s.contentTypes {
+		summary.ContentTypes[mimeType] = size
+	}
+	return summary
+}
+
+// WriteText выводит итоги в читаемом виде.
+func (s Summary) WriteText(w io.Writer) {
+	rate := 0.0
+	if s.Seconds > 0 {
+		rate = float64(s.Bytes) / s.Seconds
+	}
+	fmt.Fprintf(w, "Итого: файлов %d, %s за %v (%s/s)", s.Files, formatBytes(s.Bytes), s.Duration.Round(time.Millisecond), formatBytes(int64(rate)))
+	if s.NotModified > 0 {
+		fmt.Fprintf(w, ", не изменилось %d", s.NotModified)
+	}
+	if s.Skipped > 0 {
+		fmt.Fprintf(w, ", пропущено больших %d", s.Skipped)
+	}
+	fmt.Fprintln(w)
+	if s.QuotaReached {
+		fmt.Fprintln(w, "Обход остановлен: превышена квота")
+	}
+
+	if s.Errors > 0 {
+		fmt.Fprintf(w, "Ошибки (%d):", s.Errors)
+		for _, kind := range sortedKeys(s.ErrorsByKind) {
+			label := kind
+			if kind != "network" {
+				label = "HTTP " + kind
+			}
+			fmt.Fprintf(w, " %s: %d;", label, s.ErrorsByKind[kind])
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(s.Slowest) > 0 {
+		fmt.Fprintln(w, "Самые долгие загрузки:")
+		for _, slow := range s.Slowest {
+			fmt.Fprintf(w, "  %8v  %s\n", slow.Duration.Round(time.Millisecond), slow.URL)
+		}
+	}
+
+	if len(s.ContentTypes) > 0 {
+		fmt.Fprintln(w, "Объем по типам содержимого:")
+		types := sortedKeys(s.ContentTypes)
+		sort.SliceStable(types, func(i, j int) bool {
+			return s.ContentTypes[types[i]] > s.ContentTypes[types[j]]
+		})
+		for _, mimeType := range types {
+			fmt.Fprintf(w, "  %10s  %s\n", formatBytes(s.ContentTypes[mimeType]), mimeType)
+		}
+	}
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatBytes форматирует размер в двоичных единицах: 512 B, 1.5 KB, 3.2 MB.
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size) / unit
+	for _, suffix := range []string{"KB", "MB", "GB"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+		value /= unit
+	}
+	return fmt.Sprintf("%.1f TB", value)
+}
@@ -0,0 +1,128 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ds124wfegd/WB_L2/16/config"
+)
+
+func TestSummary(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(`<a href="/slow.txt">1</a><a href="/missing">2</a><a href="/broken">3</a><a href="/gone">4</a>`))
+		case "/slow.txt":
+			time.Sleep(50 * time.Millisecond)
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("slow"))
+		case "/broken":
+			http.Error(w, "fail", http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.NewConfig(server.URL+"/", t.TempDir(), 1, 2)
+	cfg.NoRobots = true
+	cfg.Retries = 0
+	cfg.Quiet = true
+	d := newDownloader(t, cfg)
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	summary := d.Summary()
+	if summary.Files != 2 || summary.Errors != 3 {
+		t.Errorf("files = %d, errors = %d, expected 2 and 3", summary.Files, summary.Errors)
+	}
+	if summary.ErrorsByKind["404"] != 2 || summary.ErrorsByKind["500"] != 1 {
+		t.Errorf("errors by status = %v", summary.ErrorsByKind)
+	}
+	if summary.ContentTypes["text/plain"] != 4 || summary.ContentTypes["text/html"] == 0 {
+		t.Errorf("bytes by content type = %v", summary.ContentTypes)
+	}
+	if summary.Bytes != summary.ContentTypes["text/plain"]+summary.ContentTypes["text/html"] {
+		t.Errorf("total bytes = %d, by type %v", summary.Bytes, summary.ContentTypes)
+	}
+	if len(summary.Slowest) != 2 || summary.Slowest[0].URL != server.URL+"/slow.txt" {
+		t.Errorf("slowest = %v", summary.Slowest)
+	}
+
+	var text bytes.Buffer
+	summary.WriteText(&text)
+	for _, expected := range []string{"файлов 2", "HTTP 404: 2", "HTTP 500: 1", "/slow.txt", "text/plain"} {
+		if !strings.Contains(text.String(), expected) {
+			t.Errorf("text summary does not contain %q:\n%s", expected, text.String())
+		}
+	}
+}
+
+func TestProgressRedrawsAroundLogLines(t *testing.T) {
+	var output bytes.Buffer
+	stats := newStats()
+	p := newProgress(&output, stats, func() int { return 7 }, 4)
+	p.width = 100
+
+	transfer := stats.startTransfer("example.com/big.iso", 2048)
+	transfer.done.Store(1024)
+	stats.active.Store(1)
+
+	p.mu.Lock()
+	p.draw()
+	p.mu.Unlock()
+	if !strings.Contains(output.String(), " 50% [==========>") || !strings.Contains(output.String(), "example.com/big.iso") {
+		t.Errorf("progress bar not drawn:\n%q", output.String())
+	}
+	if !strings.Contains(output.String(), "Очередь: 7  Активно: 1/4") {
+		t.Errorf("status line not drawn:\n%q", output.String())
+	}
+
+	// Строка журнала выводится после стирания двух строк индикатора
+	output.Reset()
+	p.Write([]byte("log line\n"))
+	if !strings.HasPrefix(output.String(), "\r\033[2A\033[Jlog line\n") {
+		t.Errorf("log line not written above progress:\n%q", output.String())
+	}
+
+	stats.finishTransfer(transfer)
+	if len(stats.activeTransfers()) != 0 {
+		t.Error("transfer not removed after finish")
+	}
+}
+
+func TestLoggerLevels(t *testing.T) {
+	tests := []struct {
+		level    logLevel
+		expected []string
+	}{
+		{levelError, []string{"error"}},
+		{levelInfo, []string{"error", "info"}},
+		{levelDebug, []string{"error", "info", "debug"}},
+	}
+
+	for _, test := range tests {
+		var output bytes.Buffer
+		l := newLogger(&output, test.level)
+		l.errorf("error")
+		l.infof("info")
+		l.debugf("debug")
+
+		lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+		if len(lines) != len(test.expected) {
+			t.Fatalf("level %d: got %d lines, expected %d", test.level, len(lines), len(test.expected))
+		}
+		for i, message := range test.expected {
+			if !strings.HasSuffix(lines[i], message) {
+				t.Errorf("level %d: line %q, expected %q", test.level, lines[i], message)
+			}
+		}
+	}
+}
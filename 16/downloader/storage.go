@@ -0,0 +1,306 @@
+package downloader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Storage - хранилище скачанных файлов. Имена файлов - относительные пути
+// (host/dir/file.html); хранилище само создает нужные каталоги.
+type Storage interface {
+	// Create создает файл или очищает существующий
+	Create(name string) (io.WriteCloser, error)
+	// Append открывает существующий файл для дописывания (докачка)
+	Append(name string) (io.WriteCloser, error)
+	ReadFile(name string) ([]byte, error)
+	// Stat возвращает размер и время изменения файла; для отсутствующего файла - ошибку fs.ErrNotExist
+	Stat(name string) (fs.FileInfo, error)
+	Remove(name string) error
+}
+
+// Хранилище, умеющее задавать время изменения файла (для -timestamping)
+type timestamper interface {
+	Chtimes(name string, modified time.Time) error
+}
+
+// writeFile записывает файл в хранилище целиком.
+func writeFile(storage Storage, name string, content []byte) error {
+	file, err := storage.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// FileStorage хранит файлы в каталоге файловой системы.
+type FileStorage struct {
+	dir string
+}
+
+func NewFileStorage(dir string) *FileStorage {
+	return &FileStorage{dir: dir}
+}
+
+func (s *FileStorage) path(name string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(name))
+}
+
+func (s *FileStorage) Create(name string) (io.WriteCloser, error) {
+	fullPath := s.path(name)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return nil, err
+	}
+	return os.Create(fullPath)
+}
+
+func (s *FileStorage) Append(name string) (io.WriteCloser, error) {
+	return os.OpenFile(s.path(name), os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+func (s *FileStorage) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(s.path(name))
+}
+
+func (s *FileStorage) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(s.path(name))
+}
+
+func (s *FileStorage) Remove(name string) error {
+	return os.Remove(s.path(name))
+}
+
+func (s *FileStorage) Chtimes(name string, modified time.Time) error {
+	return os.Chtimes(s.path(name), modified, modified)
+}
+
+// MemoryStorage хранит файлы в памяти: для тестов и обработки страниц без записи на диск.
+type MemoryStorage struct {
+	mu    sync.RWMutex
+	files map[string]*memoryFile
+}
+
+type memoryFile struct {
+	content  []byte
+	modified time.Time
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{files: make(map[string]*memoryFile)}
+}
+
+func (s *MemoryStorage) Create(name string) (io.WriteCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name = filepath.ToSlash(name)
+	s.files[name] = &memoryFile{modified: time.Now()}
+	return &memoryWriter{storage: s, name: name}, nil
+}
+
+func (s *MemoryStorage) Append(name string) (io.WriteCloser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	name = filepath.ToSlash(name)
+	if _, ok := s.files[name]; !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &memoryWriter{storage: s, name: name}, nil
+}
+
+func (s *MemoryStorage) ReadFile(name string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	file, ok := s.files[filepath.ToSlash(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return bytes.Clone(file.content), nil
+}
+
+func (s *MemoryStorage) Stat(name string) (fs.FileInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	file, ok := s.files[filepath.ToSlash(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return memoryFileInfo{name: path.Base(filepath.ToSlash(name)), size: int64(len(file.content)), modified: file.modified}, nil
+}
+
+func (s *MemoryStorage) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name = filepath.ToSlash(name)
+	if _, ok := s.files[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(s.files, name)
+	return nil
+}
+
+func (s *MemoryStorage) Chtimes(name string, modified time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, ok := s.files[filepath.ToSlash(name)]
+	if !ok {
+		return &fs.PathError{Op: "chtimes", Path: name, Err: fs.ErrNotExist}
+	}
+	file.modified = modified
+	return nil
+}
+
+// Files возвращает имена сохраненных файлов в алфавитном порядке.
+func (s *MemoryStorage) Files() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.files))
+	for name := range s.files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Запись в файл MemoryStorage: данные сразу видны через ReadFile и Stat
+type memoryWriter struct {
+	storage *MemoryStorage
+	name    string
+}
+
+func (w *memoryWriter) Write(p []byte) (int, error) {
+	w.storage.mu.Lock()
+	defer w.storage.mu.Unlock()
+	file, ok := w.storage.files[w.name]
+	if !ok {
+		return 0, &fs.PathError{Op: "write", Path: w.name, Err: fs.ErrNotExist}
+	}
+	file.content = append(file.content, p...)
+	file.modified = time.Now()
+	return len(p), nil
+}
+
+func (w *memoryWriter) Close() error {
+	return nil
+}
+
+type memoryFileInfo struct {
+	name     string
+	size     int64
+	modified time.Time
+}
+
+func (i memoryFileInfo) Name() string       { return i.name }
+func (i memoryFileInfo) Size() int64        { return i.size }
+func (i memoryFileInfo) Mode() fs.FileMode  { return 0644 }
+func (i memoryFileInfo) ModTime() time.Time { return i.modified }
+func (i memoryFileInfo) IsDir() bool        { return false }
+func (i memoryFileInfo) Sys() any           { return nil }
+
+// ArchiveStorage собирает файлы в tar- или zip-архив. Во время обхода файлы лежат
+// во временном каталоге (их нужно перечитывать для разбора ссылок и преобразовывать),
+// архив записывается при Close.
+type ArchiveStorage struct {
+	*FileStorage
+	writer io.Writer
+	zip    bool
+}
+
+// NewTarStorage создает хранилище, которое при Close запишет файлы в w в формате tar.
+func NewTarStorage(w io.Writer) (*ArchiveStorage, error) {
+	return newArchiveStorage(w, false)
+}
+
+// NewZipStorage создает хранилище, которое при Close запишет файлы в w в формате zip.
+func NewZipStorage(w io.Writer) (*ArchiveStorage, error) {
+	return newArchiveStorage(w, true)
+}
+
+func newArchiveStorage(w io.Writer, zip bool) (*ArchiveStorage, error) {
+	dir, err := os.MkdirTemp("", "archive-files-*")
+	if err != nil {
+		return nil, err
+	}
+	return &ArchiveStorage{FileStorage: NewFileStorage(dir), writer: w, zip: zip}, nil
+}
+
+// Close записывает архив (файлы в алфавитном порядке) и удаляет временный каталог.
+// Закрыть сам w должен вызывающий.
+func (s *ArchiveStorage) Close() error {
+	defer os.RemoveAll(s.dir)
+
+	var names []string
+	err := filepath.WalkDir(s.dir, func(fullPath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(s.dir, fullPath)
+		names = append(names, filepath.ToSlash(name))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	slices.Sort(names)
+
+	if s.zip {
+		return s.writeZip(names)
+	}
+	return s.writeTar(names)
+}
+
+func (s *ArchiveStorage) writeTar(names []string) error {
+	archive := tar.NewWriter(s.writer)
+	for _, name := range names {
+		if err := s.addFile(name, func(info fs.FileInfo) (io.Writer, error) {
+			header := &tar.Header{Name: name, Mode: 0644, Size: info.Size(), ModTime: info.ModTime(), Typeflag: tar.TypeReg}
+			return archive, archive.WriteHeader(header)
+		}); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func (s *ArchiveStorage) writeZip(names []string) error {
+	archive := zip.NewWriter(s.writer)
+	for _, name := range names {
+		if err := s.addFile(name, func(info fs.FileInfo) (io.Writer, error) {
+			return archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: info.ModTime()})
+		}); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// addFile копирует файл временного каталога в запись архива, созданную create.
+func (s *ArchiveStorage) addFile(name string, create func(fs.FileInfo) (io.Writer, error)) error {
+	file, err := os.Open(s.path(name))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	entry, err := create(info)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, file)
+	return err
+}
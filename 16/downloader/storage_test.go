@@ -0,0 +1,140 @@
+package downloader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+	"time"
+)
+
+func TestStorage(t *testing.T) {
+	backends := []struct {
+		name    string
+		storage Storage
+	}{
+		{"file", NewFileStorage(t.TempDir())},
+		{"memory", NewMemoryStorage()},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			storage := backend.storage
+			if err := writeFile(storage, "example.com/dir/page.html", []byte("hello")); err != nil {
+				t.Fatal(err)
+			}
+
+			file, err := storage.Append("example.com/dir/page.html")
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.Write([]byte(", world"))
+			file.Close()
+
+			content, err := storage.ReadFile("example.com/dir/page.html")
+			if err != nil || string(content) != "hello, world" {
+				t.Errorf("ReadFile() = %q, %v", content, err)
+			}
+
+			modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			storage.(timestamper).Chtimes("example.com/dir/page.html", modified)
+			info, err := storage.Stat("example.com/dir/page.html")
+			if err != nil || info.Size() != 12 || !info.ModTime().Equal(modified) {
+				t.Errorf("Stat() = %v, %v", info, err)
+			}
+
+			if _, err := storage.Append("example.com/missing.html"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Append(missing) = %v, expected fs.ErrNotExist", err)
+			}
+			if err := storage.Remove("example.com/dir/page.html"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := storage.Stat("example.com/dir/page.html"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Stat(removed) = %v, expected fs.ErrNotExist", err)
+			}
+		})
+	}
+}
+
+func TestArchiveStorage(t *testing.T) {
+	files := map[string]string{
+		"example.com/index.html":   "<a href=style.css>",
+		"example.com/style.css":    "body {}",
+		"example.com/img/logo.png": "png",
+	}
+
+	tests := []struct {
+		name   string
+		create func(io.Writer) (*ArchiveStorage, error)
+		read   func(t *testing.T, archive []byte) map[string]string
+	}{
+		{"tar", NewTarStorage, readTar},
+		{"zip", NewZipStorage, readZip},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var archive bytes.Buffer
+			storage, err := test.create(&archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, content := range files {
+				writeFile(storage, name, []byte(content))
+			}
+			writeFile(storage, "example.com/partial.bin", nil)
+			storage.Remove("example.com/partial.bin")
+
+			if err := storage.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			got := test.read(t, archive.Bytes())
+			if len(got) != len(files) {
+				t.Errorf("archive has %d files, expected %d: %v", len(got), len(files), got)
+			}
+			for name, content := range files {
+				if got[name] != content {
+					t.Errorf("%s = %q, expected %q", name, got[name], content)
+				}
+			}
+		})
+	}
+}
+
+func readTar(t *testing.T, archive []byte) map[string]string {
+	files := make(map[string]string)
+	reader := tar.NewReader(bytes.NewReader(archive))
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(reader)
+		files[header.Name] = string(content)
+	}
+}
+
+func readZip(t *testing.T, archive []byte) map[string]string {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, file := range reader.File {
+		entry, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(entry)
+		entry.Close()
+		files[file.Name] = string(content)
+	}
+	return files
+}
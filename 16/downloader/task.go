@@ -1,6 +1,18 @@
 package downloader
 
+import "github.com/ds124wfegd/WB_L2/16/parser"
+
 type Task struct {
 	URL   string
 	Depth int
+
+	// Реквизит страницы (изображение, стиль, скрипт): скачивается независимо от глубины
+	Requisite bool
+
+	// Тип ссылки, по которой найдена задача
+	Kind parser.LinkKind
+
+	// Приоритет в очереди: задачи с большим значением скачиваются раньше
+	// (для адресов из карт сайта и лент - время последнего изменения)
+	Priority int64
 }
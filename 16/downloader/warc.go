@@ -0,0 +1,233 @@
+package downloader
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ds124wfegd/WB_L2/16/parser"
+	"github.com/ds124wfegd/WB_L2/16/warc"
+)
+
+// warcTransport записывает в WARC каждый обмен с сервером, включая перенаправления,
+// повторы и robots.txt. Тело ответа копируется во временный файл по мере чтения,
+// а записи request и response создаются при закрытии тела.
+type warcTransport struct {
+	next      http.RoundTripper
+	writer    *warc.Writer
+	log       *logger
+	mu        sync.Mutex
+	responses map[string]string                 // URL -> ID последней записи response
+	bodies    map[*http.Response]*recordingBody // тела ответов, еще не записанные в WARC
+}
+
+func newWARCTransport(next http.RoundTripper, writer *warc.Writer, log *logger) *warcTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &warcTransport{
+		next:      next,
+		writer:    writer,
+		log:       log,
+		responses: make(map[string]string),
+		bodies:    make(map[*http.Response]*recordingBody),
+	}
+}
+
+func (t *warcTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	request, err := httputil.DumpRequestOut(req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	spool, err := os.CreateTemp("", "warc-body-*")
+	if err != nil {
+		t.log.errorf("Ошибка записи WARC для %s: %v", req.URL, err)
+		return resp, nil
+	}
+	body := &recordingBody{
+		body:      resp.Body,
+		spool:     spool,
+		transport: t,
+		request:   request,
+		response:  resp,
+	}
+	// http.Client может обернуть тело (таймаут), поэтому оно находится по ответу
+	t.mu.Lock()
+	t.bodies[resp] = body
+	t.mu.Unlock()
+	resp.Body = body
+	return resp, nil
+}
+
+// responseID возвращает ID записи response для адреса (пустая строка, если ее нет).
+func (t *warcTransport) responseID(rawURL string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.responses[rawURL]
+}
+
+// archive записывает пару request/response; тело ответа берется из временного файла.
+func (t *warcTransport) archive(request []byte, resp *http.Response, body *os.File, truncated string) error {
+	targetURI := resp.Request.URL.String()
+	requestID, err := t.writer.WriteRequest(targetURI, request)
+	if err != nil {
+		return err
+	}
+
+	var header bytes.Buffer
+	fmt.Fprintf(&header, "HTTP/%d.%d %s\r\n", resp.ProtoMajor, resp.ProtoMinor, resp.Status)
+	resp.Header.Write(&header)
+	header.WriteString("\r\n")
+
+	mimeType, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+	responseID, err := t.writer.WriteResponse(warc.Response{
+		TargetURI:    targetURI,
+		ConcurrentTo: requestID,
+		Header:       header.Bytes(),
+		Body:         body,
+		Status:       resp.StatusCode,
+		MimeType:     strings.TrimSpace(mimeType),
+		Location:     resp.Header.Get("Location"),
+		Truncated:    truncated,
+	})
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.responses[targetURI] = responseID
+	t.mu.Unlock()
+	return nil
+}
+
+// recordingBody копирует прочитанное тело ответа во временный файл
+type recordingBody struct {
+	body      io.ReadCloser
+	spool     *os.File
+	transport *warcTransport
+	request   []byte
+	response  *http.Response
+	complete  bool
+	truncated string // причина, по которой тело закрыто недочитанным (WARC-Truncated)
+	once      sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		if _, writeErr := b.spool.Write(p[:n]); writeErr != nil {
+			return n, writeErr
+		}
+	}
+	if err == io.EOF {
+		b.complete = true
+	}
+	return n, err
+}
+
+// Close дочитывает тело (его могут закрыть, не дочитав, например при перенаправлении)
+// и записывает обмен в WARC. Оборванные ответы не архивируются, а отброшенные
+// через Downloader.discardBody архивируются с полученной частью тела без дочитывания.
+func (b *recordingBody) Close() error {
+	var err error
+	b.once.Do(func() {
+		if !b.complete && b.truncated == "" {
+			_, drainErr := io.Copy(b.spool, b.body)
+			b.complete = drainErr == nil
+		}
+		err = b.body.Close()
+		b.transport.mu.Lock()
+		delete(b.transport.bodies, b.response)
+		b.transport.mu.Unlock()
+
+		if b.complete || b.truncated != "" {
+			if archiveErr := b.transport.archive(b.request, b.response, b.spool, b.truncated); archiveErr != nil {
+				b.transport.log.errorf("Ошибка записи WARC для %s: %v", b.response.Request.URL, archiveErr)
+			}
+		}
+		b.spool.Close()
+		os.Remove(b.spool.Name())
+	})
+	return err
+}
+
+// discardBody закрывает тело ответа, не дочитывая его, чтобы пропущенный файл не скачивался
+// целиком ради WARC. reason - значение WARC-Truncated для записи с полученной частью тела.
+func (d *Downloader) discardBody(resp *http.Response, reason string) {
+	if d.warc != nil {
+		d.warc.mu.Lock()
+		if body, ok := d.warc.bodies[resp]; ok && !body.complete {
+			body.truncated = reason
+		}
+		d.warc.mu.Unlock()
+	}
+	resp.Body.Close()
+}
+
+// openWARC создает WARC-файл и подключает запись обменов к HTTP-клиенту.
+func (d *Downloader) openWARC() error {
+	robotsPolicy := "obey"
+	if d.config.NoRobots {
+		robotsPolicy = "ignore"
+	}
+	writer, err := warc.Create(d.config.WARCFile, warc.Fields{
+		{Name: "software", Value: d.config.UserAgent},
+		{Name: "format", Value: "WARC File Format 1.1"},
+		{Name: "conformsTo", Value: "http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/"},
+		{Name: "robots", Value: robotsPolicy},
+		{Name: "description", Value: "Crawl of " + d.config.URL},
+	})
+	if err != nil {
+		return err
+	}
+
+	d.warc = newWARCTransport(d.client.Transport, writer, d.log)
+	d.client.Transport = d.warc
+	return nil
+}
+
+// closeWARC закрывает WARC-файл и записывает CDX-индекс.
+func (d *Downloader) closeWARC() {
+	if d.warc == nil {
+		return
+	}
+	if err := d.warc.writer.Close(); err != nil {
+		d.log.errorf("Ошибка записи WARC: %v", err)
+		return
+	}
+	d.log.infof("WARC записан: %s", d.warc.writer.Filename())
+}
+
+// archiveMetadata записывает запись metadata страницы: исходный адрес перенаправления
+// и найденные ссылки. Тело ответа должно быть уже закрыто, чтобы была известна запись response.
+func (d *Downloader) archiveMetadata(task *Task, pageURL string, links []parser.Link) {
+	if d.warc == nil {
+		return
+	}
+
+	var fields warc.Fields
+	if pageURL != task.URL {
+		fields = append(fields, warc.Field{Name: "via", Value: task.URL})
+	}
+	for _, link := range links {
+		fields = append(fields, warc.Field{Name: "outlink", Value: link.URL + " " + link.Kind.String()})
+	}
+	if len(fields) == 0 {
+		return
+	}
+
+	if _, err := d.warc.writer.WriteMetadata(pageURL, d.warc.responseID(pageURL), fields); err != nil {
+		d.log.errorf("Ошибка записи WARC для %s: %v", pageURL, err)
+	}
+}
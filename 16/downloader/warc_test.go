@@ -0,0 +1,115 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ds124wfegd/WB_L2/16/config"
+	"github.com/ds124wfegd/WB_L2/16/warc"
+)
+
+func TestWARCRoundTrip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<a href="/old">old</a><img src="/logo.png">`))
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/new":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("new page"))
+		case "/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("PNG"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.NewConfig(server.URL+"/", t.TempDir(), 1, 2)
+	cfg.WARCFile = filepath.Join(t.TempDir(), "crawl")
+	cfg.WARCOnly = true
+	d := newDownloader(t, cfg)
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// При -warc-only в выходном каталоге остается только журнал
+	entries, _ := os.ReadDir(cfg.Output)
+	for _, entry := range entries {
+		if entry.Name() != journalName {
+			t.Errorf("unexpected file in output directory: %s", entry.Name())
+		}
+	}
+
+	file, err := os.Open(cfg.WARCFile + ".warc.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	reader, err := warc.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responses := make(map[string]*warc.Record)
+	var requests, metadata []*warc.Record
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch record.Type() {
+		case warc.TypeRequest:
+			requests = append(requests, record)
+		case warc.TypeResponse:
+			responses[strings.TrimPrefix(record.Header.Get("WARC-Target-URI"), server.URL)] = record
+		case warc.TypeMetadata:
+			metadata = append(metadata, record)
+		}
+	}
+
+	for _, path := range []string{"/robots.txt", "/", "/old", "/new"} {
+		if _, ok := responses[path]; !ok {
+			t.Errorf("no response record for %s", path)
+		}
+	}
+	if len(requests) != len(responses) {
+		t.Errorf("%d request records for %d responses", len(requests), len(responses))
+	}
+	if block := responses["/new"].Block; !bytes.HasPrefix(block, []byte("HTTP/1.1 200 OK\r\n")) || !bytes.HasSuffix(block, []byte("\r\n\r\nnew page")) {
+		t.Errorf("response block for /new = %q", block)
+	}
+	if block := responses["/old"].Block; !bytes.Contains(block, []byte("Location: /new")) {
+		t.Errorf("redirect not archived: %q", block)
+	}
+
+	var outlinks, via bool
+	for _, record := range metadata {
+		outlinks = outlinks || bytes.Contains(record.Block, []byte("outlink: "+server.URL+"/old page"))
+		via = via || bytes.Contains(record.Block, []byte("via: "+server.URL+"/old"))
+	}
+	if !outlinks || !via {
+		t.Errorf("metadata records missing outlinks (%v) or via (%v)", outlinks, via)
+	}
+
+	index, err := os.ReadFile(cfg.WARCFile + ".cdx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(index), "\n"); lines != len(responses)+1 {
+		t.Errorf("CDX has %d lines, expected %d", lines, len(responses)+1)
+	}
+}
@@ -3,3 +3,5 @@ module github.com/ds124wfegd/WB_L2/16
 go 1.24.0
 
 toolchain go1.24.7
+
+require golang.org/x/net v0.44.0
@@ -1,10 +1,20 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/ds124wfegd/WB_L2/16/config"
 	"github.com/ds124wfegd/WB_L2/16/downloader"
@@ -15,8 +25,61 @@ func main() {
 	output := flag.String("output", "./download", "Output directory")
 	depth := flag.Int("depth", 2, "Max recursion depth")
 	workers := flag.Int("workers", 3, "Number of workers")
+	convertLinks := flag.Bool("convert-links", false, "Rewrite links in downloaded pages to point to local files")
+	wait := flag.Duration("wait", 0, "Delay between requests to the same host")
+	randomWait := flag.Bool("random-wait", false, "Vary the delay between 0.5 and 1.5 times -wait")
+	noRobots := flag.Bool("no-robots", false, "Ignore robots.txt")
+	resume := flag.Bool("continue", false, "Resume an interrupted crawl and partially downloaded files")
+	timestamping := flag.Bool("timestamping", false, "Don't re-download files unchanged on the server")
+	domains := flag.String("domains", "", "Comma-separated list of domains to follow (subdomains included)")
+	spanHosts := flag.Bool("span-hosts", false, "Follow links to other hosts")
+	includeDirectories := flag.String("include-directories", "", "Comma-separated list of directories to follow")
+	excludeDirectories := flag.String("exclude-directories", "", "Comma-separated list of directories to skip")
+	noParent := flag.Bool("no-parent", false, "Don't ascend above the starting directory")
+	accept := flag.String("accept", "", "Comma-separated list of accepted file suffixes or patterns")
+	reject := flag.String("reject", "", "Comma-separated list of rejected file suffixes or patterns")
+	acceptRegex := flag.String("accept-regex", "", "Regular expression the URL must match")
+	rejectRegex := flag.String("reject-regex", "", "Regular expression the URL must not match")
+	pageRequisites := flag.Bool("page-requisites", false, "Download images, styles and scripts needed to display pages")
+	maxLinks := flag.Int("max-links", 50, "Max page links followed from one page (0 - unlimited)")
+	retries := flag.Int("retries", 3, "Retries on network errors, 5xx and 429 responses")
+	retryBackoff := flag.Duration("retry-backoff", time.Second, "Initial delay between retries (doubled on each retry)")
+	warcFile := flag.String("warc-file", "", "Write requests and responses to FILE.warc.gz with a CDX index")
+	warcOnly := flag.Bool("warc-only", false, "With -warc-file, don't save files to the output directory")
+	userAgent := flag.String("user-agent", config.DefaultUserAgent, "User-Agent header value")
+	var headers stringList
+	flag.Var(&headers, "header", "Additional request header \"Name: value\" (repeatable)")
+	httpUser := flag.String("http-user", "", "User name for HTTP Basic authentication")
+	httpPassword := flag.String("http-password", "", "Password for HTTP Basic authentication")
+	bearerToken := flag.String("bearer-token", "", "Token for HTTP Bearer authentication")
+	loadCookies := flag.String("load-cookies", "", "Load cookies from a Netscape cookies.txt file")
+	saveCookies := flag.String("save-cookies", "", "Save cookies to a Netscape cookies.txt file")
+	postData := flag.String("post-data", "", "Send the starting URL as a POST request with this body")
+	proxy := flag.String("proxy", "", "HTTP(S) proxy URL (default: HTTP_PROXY/HTTPS_PROXY)")
+	caCertificate := flag.String("ca-certificate", "", "PEM file with additional trusted CA certificates")
+	noCheckCertificate := flag.Bool("no-check-certificate", false, "Don't verify server TLS certificates")
+	quiet := flag.Bool("quiet", false, "Log only errors")
+	verbose := flag.Bool("verbose", false, "Log worker activity and discovered links")
+	summary := flag.String("summary", "", "Summary format at the end: text, json or none (default text, none with -quiet)")
+	inputFile := flag.String("i", "", "Read starting URLs from a file, one per line (- for stdin)")
+	sitemaps := flag.Bool("sitemaps", false, "Discover sitemaps (robots.txt, /sitemap.xml) and crawl their URLs")
+	limitRate := flag.String("limit-rate", "", "Limit total download speed, bytes per second (k, m, g suffixes)")
+	maxHostConnections := flag.Int("max-host-connections", 0, "Max simultaneous connections to one host (0 - unlimited)")
+	quota := flag.String("quota", "", "Stop the crawl after downloading this many bytes (k, m, g suffixes)")
+	maxFileSize := flag.String("max-file-size", "", "Skip files larger than this size (k, m, g suffixes)")
 	flag.Parse()
 
+	var seeds []string
+	if *inputFile != "" {
+		var err error
+		if seeds, err = readURLList(*inputFile); err != nil {
+			log.Fatal("Ошибка чтения -i: ", err)
+		}
+		if *url == "" && len(seeds) > 0 {
+			*url = seeds[0]
+		}
+	}
+
 	if *url == "" {
 		fmt.Println("Ошибка: необходим URL")
 		flag.Usage()
@@ -24,13 +87,204 @@ func main() {
 	}
 
 	cfg := config.NewConfig(*url, *output, *depth, *workers)
+	cfg.ConvertLinks = *convertLinks
+	cfg.Wait = *wait
+	cfg.RandomWait = *randomWait
+	cfg.NoRobots = *noRobots
+	cfg.Continue = *resume
+	cfg.Timestamping = *timestamping
+	cfg.Domains = splitList(*domains)
+	cfg.SpanHosts = *spanHosts
+	cfg.IncludeDirectories = splitList(*includeDirectories)
+	cfg.ExcludeDirectories = splitList(*excludeDirectories)
+	cfg.NoParent = *noParent
+	cfg.Accept = splitList(*accept)
+	cfg.Reject = splitList(*reject)
+	cfg.PageRequisites = *pageRequisites
+	cfg.MaxLinksPerPage = *maxLinks
+	cfg.Retries = *retries
+	cfg.RetryBackoff = *retryBackoff
+	cfg.WARCFile = *warcFile
+	cfg.WARCOnly = *warcOnly
+	cfg.UserAgent = *userAgent
+	cfg.Headers = headers
+	cfg.HTTPUser = *httpUser
+	cfg.HTTPPassword = *httpPassword
+	cfg.BearerToken = *bearerToken
+	cfg.LoadCookies = *loadCookies
+	cfg.SaveCookies = *saveCookies
+	cfg.PostData = *postData
+	cfg.Proxy = *proxy
+	cfg.CACertificate = *caCertificate
+	cfg.NoCheckCertificate = *noCheckCertificate
+	cfg.Quiet = *quiet
+	cfg.Verbose = *verbose
+	cfg.Progress = !*quiet && isTerminal(os.Stderr)
+	cfg.Seeds = seeds
+	cfg.Sitemaps = *sitemaps
 
-	dl := downloader.NewDownloader(cfg)
-	log.Printf("Начало загрузки c %s в дирректорию %s", cfg.URL, cfg.Output)
+	if *quiet && *verbose {
+		log.Fatal("Ошибка: -quiet и -verbose несовместимы")
+	}
+	if *summary == "" {
+		*summary = "text"
+		if *quiet {
+			*summary = "none"
+		}
+	}
+	if *summary != "text" && *summary != "json" && *summary != "none" {
+		log.Fatalf("Ошибка в -summary %q: ожидается text, json или none", *summary)
+	}
+
+	if cfg.WARCOnly && cfg.WARCFile == "" {
+		log.Fatal("Ошибка: -warc-only требует -warc-file")
+	}
+
+	for _, header := range headers {
+		if name, _, found := strings.Cut(header, ":"); !found || strings.TrimSpace(name) == "" {
+			log.Fatalf("Ошибка в -header %q: ожидается \"Name: value\"", header)
+		}
+	}
+
+	var err error
+	if cfg.LimitRate, err = parseSize(*limitRate); err != nil {
+		log.Fatal("Ошибка в -limit-rate: ", err)
+	}
+	if cfg.Quota, err = parseSize(*quota); err != nil {
+		log.Fatal("Ошибка в -quota: ", err)
+	}
+	if cfg.MaxFileSize, err = parseSize(*maxFileSize); err != nil {
+		log.Fatal("Ошибка в -max-file-size: ", err)
+	}
+	cfg.MaxHostConnections = *maxHostConnections
 
-	if err := dl.Start(); err != nil {
+	if cfg.AcceptRegex, err = compileRegex(*acceptRegex); err != nil {
+		log.Fatal("Ошибка в -accept-regex: ", err)
+	}
+	if cfg.RejectRegex, err = compileRegex(*rejectRegex); err != nil {
+		log.Fatal("Ошибка в -reject-regex: ", err)
+	}
+
+	dl, err := downloader.NewDownloader(cfg)
+	if err != nil {
+		log.Fatal("Ошибка: ", err)
+	}
+	if !cfg.Quiet {
+		log.Printf("Начало загрузки c %s в дирректорию %s", cfg.URL, cfg.Output)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = dl.Start(ctx)
+	printSummary(dl.Summary(), *summary)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			log.Println("Загрузка прервана")
+			os.Exit(130)
+		}
 		log.Fatal("Ошибка:", err)
 	}
 
-	log.Println("Загрузка завершена!")
+	if !cfg.Quiet {
+		log.Println("Загрузка завершена!")
+	}
+}
+
+// printSummary выводит итоги: текст - в stderr, JSON - в stdout для обработки другими программами
+func printSummary(summary downloader.Summary, format string) {
+	switch format {
+	case "text":
+		summary.WriteText(os.Stderr)
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(summary)
+	}
+}
+
+// isTerminal сообщает, выводит ли файл в терминал
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// stringList - значение флага, который можно указать несколько раз
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// readURLList читает адреса из файла (по одному в строке); пустые строки и комментарии # пропускаются
+func readURLList(path string) ([]string, error) {
+	input := os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	var urls []string
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			urls = append(urls, line)
+		}
+	}
+	return urls, scanner.Err()
+}
+
+// splitList разбирает список значений через запятую, пропуская пустые
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseSize разбирает размер в байтах с необязательным суффиксом k, m или g (степени 1024): "200k", "1.5m"
+func parseSize(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	multiplier := 1.0
+	switch strings.ToLower(value[len(value)-1:]) {
+	case "k":
+		multiplier = 1 << 10
+	case "m":
+		multiplier = 1 << 20
+	case "g":
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		value = value[:len(value)-1]
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("неверный размер %q", value)
+	}
+	return int64(number * multiplier), nil
+}
+
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
 }
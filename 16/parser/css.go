@@ -0,0 +1,149 @@
+package parser
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Ссылка, найденная в CSS, с положением ее значения в исходном тексте
+type cssReference struct {
+	value  string
+	kind   LinkKind
+	start  int // начало значения (включая кавычки, если они есть)
+	end    int
+	quoted bool
+}
+
+// ExtractCSSLinks возвращает абсолютные ссылки из url(...) и @import в тексте CSS
+// (файла стилей, блока <style> или атрибута style).
+func ExtractCSSLinks(css string, base *url.URL) []Link {
+	return resolveLinks(extractCSSRaw(css), base)
+}
+
+// extractCSSRaw находит ссылки url(...) и @import в тексте CSS (без разрешения относительно базы).
+func extractCSSRaw(css string) []rawLink {
+	var links []rawLink
+	for _, reference := range scanCSS(css) {
+		links = append(links, rawLink{value: reference.value, kind: reference.kind})
+	}
+	return links
+}
+
+// scanCSS разбирает CSS и возвращает ссылки url(...) и @import. Комментарии /* ... */ пропускаются.
+func scanCSS(css string) []cssReference {
+	var references []cssReference
+	lower := strings.ToLower(css)
+
+	for position := 0; position < len(css); {
+		switch {
+		case strings.HasPrefix(css[position:], "/*"):
+			end := strings.Index(css[position+2:], "*/")
+			if end < 0 {
+				return references
+			}
+			position += end + 4
+
+		case strings.HasPrefix(lower[position:], "@import"):
+			position += len("@import")
+			for position < len(css) && isSpace(css[position]) {
+				position++
+			}
+			if position < len(css) && (css[position] == '"' || css[position] == '\'') {
+				reference := readCSSString(css, position)
+				reference.kind = KindStylesheet
+				references = append(references, reference)
+				position = reference.end
+			} else if strings.HasPrefix(lower[position:], "url(") {
+				reference, next := readCSSURL(css, position+len("url("))
+				reference.kind = KindStylesheet
+				references = append(references, reference)
+				position = next
+			}
+
+		case strings.HasPrefix(lower[position:], "url(") && (position == 0 || !isCSSNameChar(css[position-1])):
+			reference, next := readCSSURL(css, position+len("url("))
+			reference.kind = kindFromExtension(reference.value, KindImage)
+			references = append(references, reference)
+			position = next
+
+		case css[position] == '"' || css[position] == '\'':
+			position = readCSSString(css, position).end
+
+		default:
+			position++
+		}
+	}
+
+	return references
+}
+
+// readCSSString читает строку в кавычках, начинающуюся в position.
+func readCSSString(css string, position int) cssReference {
+	reference := cssReference{start: position, quoted: true}
+	quote := css[position]
+	var value strings.Builder
+	position++
+	for position < len(css) && css[position] != quote {
+		if css[position] == '\\' && position+1 < len(css) {
+			position++
+		}
+		value.WriteByte(css[position])
+		position++
+	}
+
+	reference.value = value.String()
+	reference.end = min(position+1, len(css))
+	return reference
+}
+
+// readCSSURL читает аргумент url(...) начиная сразу после открывающей скобки
+// и возвращает позицию после закрывающей скобки.
+func readCSSURL(css string, position int) (cssReference, int) {
+	for position < len(css) && isSpace(css[position]) {
+		position++
+	}
+	if position < len(css) && (css[position] == '"' || css[position] == '\'') {
+		reference := readCSSString(css, position)
+		next := reference.end
+		if end := strings.IndexByte(css[next:], ')'); end >= 0 {
+			next += end + 1
+		}
+		return reference, next
+	}
+
+	end := strings.IndexByte(css[position:], ')')
+	next := position + end + 1
+	if end < 0 {
+		end = len(css) - position
+		next = len(css)
+	}
+	value := strings.TrimRight(css[position:position+end], " \t\r\n\f")
+	return cssReference{value: value, start: position, end: position + len(value)}, next
+}
+
+// RewriteCSSLinks заменяет ссылки url(...) и @import в CSS по правилам RewriteLinks.
+func RewriteCSSLinks(css string, base *url.URL, rewrite func(Link) (string, bool)) string {
+	var result strings.Builder
+	last := 0
+
+	for _, reference := range scanCSS(css) {
+		replacement := rewriteReference(reference.value, reference.kind, base, rewrite)
+		if replacement == reference.value {
+			continue
+		}
+
+		result.WriteString(css[last:reference.start])
+		if reference.quoted || strings.ContainsAny(replacement, " \t\r\n\f()'\"\\") {
+			replacement = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(replacement) + `"`
+		}
+		result.WriteString(replacement)
+		last = reference.end
+	}
+
+	result.WriteString(css[last:])
+	return result.String()
+}
+
+func isCSSNameChar(char byte) bool {
+	return char == '-' || char == '_' || (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9')
+}
@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"net/url"
+	"strings"
+)
+
+// ParseFeed возвращает адреса записей ленты RSS (2.0 и 1.0) или Atom с датами публикации.
+// Ссылки самой ленты (<channel><link>, <feed><link>) и вложения не учитываются.
+func ParseFeed(content []byte, base *url.URL) ([]DatedURL, error) {
+	var entries []DatedURL
+	var entry DatedURL
+	var guid string
+	seen := make(map[string]bool)
+
+	err := walkXML(content, func(path []string, text string, attrs map[string]string) {
+		if len(path) < 2 {
+			return
+		}
+		parent, name := path[len(path)-2], path[len(path)-1]
+		if parent != "item" && parent != "entry" {
+			return
+		}
+
+		switch name {
+		case "link":
+			// В Atom ссылка на запись - <link href> с rel="alternate" или без rel
+			if href, ok := attrs["href"]; ok {
+				if rel := attrs["rel"]; rel != "" && rel != "alternate" {
+					return
+				}
+				text = href
+			}
+			if entry.URL == "" {
+				entry.URL = ResolveURL(text, base)
+			}
+		case "guid":
+			// guid с isPermaLink="false" - идентификатор, а не адрес
+			if attrs["ispermalink"] != "false" {
+				guid = text
+			}
+		case "pubdate", "date", "updated", "published":
+			if date := parseDate(text); !date.IsZero() && date.After(entry.LastModified) {
+				entry.LastModified = date
+			}
+		}
+	}, func(name string) {
+		if name != "item" && name != "entry" {
+			return
+		}
+		if entry.URL == "" && guid != "" {
+			entry.URL = ResolveURL(guid, base)
+		}
+		if entry.URL != "" && !seen[entry.URL] {
+			seen[entry.URL] = true
+			entries = append(entries, entry)
+		}
+		entry, guid = DatedURL{}, ""
+	})
+	return entries, err
+}
+
+// IsFeedType сообщает, является ли тип содержимого лентой RSS или Atom.
+func IsFeedType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return strings.Contains(contentType, "rss+xml") || strings.Contains(contentType, "atom+xml") || strings.Contains(contentType, "rdf+xml")
+}
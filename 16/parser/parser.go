@@ -2,46 +2,400 @@ package parser
 
 import (
 	"net/url"
+	"path"
 	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
 )
 
-func ExtractLinks(html string, base *url.URL) []string {
-	var links []string
-	seen := make(map[string]bool)
+type LinkKind int
+
+const (
+	KindPage LinkKind = iota
+	KindStylesheet
+	KindScript
+	KindImage
+	KindMedia
+	KindSitemap // карта сайта (sitemap.xml или индекс карт)
+	KindFeed    // лента RSS или Atom
+)
+
+func (k LinkKind) String() string {
+	switch k {
+	case KindStylesheet:
+		return "stylesheet"
+	case KindScript:
+		return "script"
+	case KindImage:
+		return "image"
+	case KindMedia:
+		return "media"
+	case KindSitemap:
+		return "sitemap"
+	case KindFeed:
+		return "feed"
+	}
+	return "page"
+}
+
+// IsRequisite сообщает, нужен ли ресурс для отображения страницы (стили, скрипты, изображения, медиа).
+func (k LinkKind) IsRequisite() bool {
+	switch k {
+	case KindStylesheet, KindScript, KindImage, KindMedia:
+		return true
+	}
+	return false
+}
+
+type Link struct {
+	URL  string
+	Kind LinkKind
+}
+
+type rawLink struct {
+	value string
+	kind  LinkKind
+}
 
-	patterns := []string{"href=\"", "src=\"", "action=\""}
+// Формат значения атрибута со ссылкой
+type attributeFormat int
 
-	for _, pattern := range patterns {
-		start := 0
-		for {
-			idx := strings.Index(html[start:], pattern)
-			if idx == -1 {
-				break
+const (
+	formatURL     attributeFormat = iota
+	formatSrcset                  // список кандидатов "url дескриптор, ..."
+	formatRefresh                 // content вида "5; url=page.html"
+	formatCSS                     // встроенные стили (атрибут style)
+)
+
+// Атрибут тега, содержащий ссылку на ресурс указанного типа
+type linkAttribute struct {
+	name   string
+	kind   LinkKind
+	format attributeFormat
+}
+
+// ExtractLinks разбирает HTML токенизатором HTML5 и возвращает абсолютные ссылки с их типом.
+// Учитывается <base href>, srcset, <meta http-equiv=refresh>, url() и @import внутри <style> и атрибутов style;
+// содержимое комментариев и скриптов игнорируется.
+func ExtractLinks(document string, base *url.URL) []Link {
+	base = documentBase(document, base)
+	tokenizer := html.NewTokenizer(strings.NewReader(document))
+
+	var raw []rawLink
+	inStyle := false
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+
+		switch tokenType {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if token.DataAtom == atom.Style && tokenType == html.StartTagToken {
+				inStyle = true
 			}
+			raw = append(raw, tagLinks(token)...)
 
-			attrStart := start + idx + len(pattern)
-			end := strings.Index(html[attrStart:], "\"")
-			if end == -1 {
-				break
+		case html.EndTagToken:
+			if tokenizer.Token().DataAtom == atom.Style {
+				inStyle = false
 			}
 
-			link := html[attrStart : attrStart+end]
+		case html.TextToken:
+			if inStyle {
+				raw = append(raw, extractCSSRaw(string(tokenizer.Text()))...)
+			}
+		}
+	}
 
-			if isValidLink(link) {
-				absoluteLink := ResolveURL(link, base)
-				if absoluteLink != "" && !seen[absoluteLink] {
-					seen[absoluteLink] = true
-					links = append(links, absoluteLink)
-				}
+	return resolveLinks(raw, base)
+}
+
+// documentBase возвращает адрес, относительно которого разрешаются ссылки документа:
+// href первого тега <base> или адрес самого документа.
+func documentBase(document string, base *url.URL) *url.URL {
+	tokenizer := html.NewTokenizer(strings.NewReader(document))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return base
+		}
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := tokenizer.Token()
+		if token.DataAtom != atom.Base {
+			continue
+		}
+		if href, ok := attribute(token, "href"); ok {
+			if resolved, err := base.Parse(strings.TrimSpace(href)); err == nil {
+				return resolved
 			}
+			return base
+		}
+	}
+}
+
+func resolveLinks(raw []rawLink, base *url.URL) []Link {
+	var links []Link
+	seen := make(map[string]bool)
 
-			start = attrStart + end + 1
+	for _, link := range raw {
+		value := strings.TrimSpace(link.value)
+		if !isValidLink(value) {
+			continue
+		}
+		absoluteLink := ResolveURL(value, base)
+		if absoluteLink != "" && !seen[absoluteLink] {
+			seen[absoluteLink] = true
+			links = append(links, Link{URL: absoluteLink, Kind: link.kind})
 		}
 	}
 
 	return links
 }
 
+func tagLinks(token html.Token) []rawLink {
+	var links []rawLink
+	for _, attr := range linkAttributes(token) {
+		value, ok := attribute(token, attr.name)
+		if !ok {
+			continue
+		}
+		if attr.format == formatCSS {
+			links = append(links, extractCSSRaw(value)...)
+			continue
+		}
+		for _, target := range attributeTargets(value, attr.format) {
+			links = append(links, rawLink{value: target, kind: attr.kind})
+		}
+	}
+	return links
+}
+
+// linkAttributes перечисляет атрибуты тега, которые могут содержать ссылки.
+func linkAttributes(token html.Token) []linkAttribute {
+	var attributes []linkAttribute
+	add := func(name string, kind LinkKind, format attributeFormat) {
+		attributes = append(attributes, linkAttribute{name: name, kind: kind, format: format})
+	}
+
+	switch token.DataAtom {
+	case atom.A, atom.Area:
+		add("href", KindPage, formatURL)
+	case atom.Form:
+		add("action", KindPage, formatURL)
+	case atom.Iframe, atom.Frame:
+		add("src", KindPage, formatURL)
+	case atom.Link:
+		if kind, ok := linkRelKind(token); ok {
+			add("href", kind, formatURL)
+			add("imagesrcset", KindImage, formatSrcset)
+		}
+	case atom.Script:
+		add("src", KindScript, formatURL)
+	case atom.Img:
+		add("src", KindImage, formatURL)
+		add("srcset", KindImage, formatSrcset)
+	case atom.Input:
+		if typ, _ := attribute(token, "type"); strings.EqualFold(typ, "image") {
+			add("src", KindImage, formatURL)
+		}
+	case atom.Source:
+		add("src", KindMedia, formatURL)
+		add("srcset", KindImage, formatSrcset)
+	case atom.Video:
+		add("src", KindMedia, formatURL)
+		add("poster", KindImage, formatURL)
+	case atom.Audio, atom.Track, atom.Embed:
+		add("src", KindMedia, formatURL)
+	case atom.Object:
+		add("data", KindMedia, formatURL)
+	case atom.Meta:
+		if equiv, _ := attribute(token, "http-equiv"); strings.EqualFold(equiv, "refresh") {
+			add("content", KindPage, formatRefresh)
+		}
+	}
+
+	// Атрибут background у body и ячеек таблиц
+	if token.DataAtom == atom.Body || token.DataAtom == atom.Table || token.DataAtom == atom.Td || token.DataAtom == atom.Th {
+		add("background", KindImage, formatURL)
+	}
+
+	if _, ok := attribute(token, "style"); ok {
+		add("style", KindImage, formatCSS)
+	}
+
+	return attributes
+}
+
+// attributeTargets извлекает ссылки из значения атрибута в соответствии с его форматом.
+func attributeTargets(value string, format attributeFormat) []string {
+	switch format {
+	case formatSrcset:
+		return ParseSrcset(value)
+	case formatRefresh:
+		if target := parseMetaRefresh(value); target != "" {
+			return []string{target}
+		}
+		return nil
+	}
+	return []string{value}
+}
+
+// linkRelKind определяет тип ресурса <link> по атрибутам rel и as.
+// Ссылки без загружаемого ресурса (preconnect, dns-prefetch и т.п.) пропускаются.
+func linkRelKind(token html.Token) (LinkKind, bool) {
+	rel, _ := attribute(token, "rel")
+	as, _ := attribute(token, "as")
+
+	for _, value := range strings.Fields(strings.ToLower(rel)) {
+		switch value {
+		case "stylesheet":
+			return KindStylesheet, true
+		case "icon", "apple-touch-icon", "apple-touch-icon-precomposed", "mask-icon":
+			return KindImage, true
+		case "modulepreload":
+			return KindScript, true
+		case "preload", "prefetch":
+			switch strings.ToLower(as) {
+			case "style":
+				return KindStylesheet, true
+			case "script", "worker":
+				return KindScript, true
+			case "image":
+				return KindImage, true
+			case "font", "audio", "video", "track":
+				return KindMedia, true
+			}
+			return KindPage, true
+		case "alternate":
+			if typ, _ := attribute(token, "type"); IsFeedType(typ) {
+				return KindFeed, true
+			}
+			return KindPage, true
+		case "next", "prev", "canonical", "manifest":
+			return KindPage, true
+		}
+	}
+	return KindPage, false
+}
+
+func attribute(token html.Token, name string) (string, bool) {
+	for _, attr := range token.Attr {
+		if attr.Namespace == "" && attr.Key == name {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+// Кандидат из атрибута srcset: URL и дескриптор ширины или плотности
+type srcsetCandidate struct {
+	url        string
+	descriptor string
+}
+
+// ParseSrcset возвращает URL кандидатов из значения атрибута srcset
+// ("a.jpg 1x, b.jpg 2x" или "small.jpg 480w, large.jpg 1080w").
+func ParseSrcset(value string) []string {
+	var urls []string
+	for _, candidate := range parseSrcsetCandidates(value) {
+		urls = append(urls, candidate.url)
+	}
+	return urls
+}
+
+func parseSrcsetCandidates(value string) []srcsetCandidate {
+	var candidates []srcsetCandidate
+	position := 0
+
+	for position < len(value) {
+		for position < len(value) && (isSpace(value[position]) || value[position] == ',') {
+			position++
+		}
+		if position >= len(value) {
+			break
+		}
+
+		start := position
+		for position < len(value) && !isSpace(value[position]) {
+			position++
+		}
+		candidate := srcsetCandidate{url: value[start:position]}
+
+		// URL, оканчивающийся запятой, не имеет дескрипторов
+		if strings.HasSuffix(candidate.url, ",") {
+			candidate.url = strings.TrimRight(candidate.url, ",")
+		} else {
+			descriptorStart := position
+			depth := 0
+			for position < len(value) {
+				char := value[position]
+				if char == '(' {
+					depth++
+				} else if char == ')' && depth > 0 {
+					depth--
+				} else if char == ',' && depth == 0 {
+					break
+				}
+				position++
+			}
+			candidate.descriptor = strings.TrimSpace(value[descriptorStart:position])
+		}
+
+		if candidate.url != "" {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	return candidates
+}
+
+// parseMetaRefresh извлекает URL из content вида "5; url=page.html".
+func parseMetaRefresh(content string) string {
+	_, rest, found := strings.Cut(content, ";")
+	if !found {
+		_, rest, found = strings.Cut(content, ",")
+		if !found {
+			return ""
+		}
+	}
+	rest = strings.TrimSpace(rest)
+	if len(rest) >= 4 && strings.EqualFold(rest[:3], "url") {
+		after := strings.TrimSpace(rest[3:])
+		if strings.HasPrefix(after, "=") {
+			rest = strings.TrimSpace(after[1:])
+		}
+	}
+	rest = strings.Trim(rest, `"'`)
+	return strings.TrimSpace(rest)
+}
+
+// kindFromExtension уточняет тип ресурса по расширению файла в URL.
+func kindFromExtension(rawURL string, fallback LinkKind) LinkKind {
+	withoutQuery, _, _ := strings.Cut(rawURL, "?")
+	switch strings.ToLower(path.Ext(withoutQuery)) {
+	case ".css":
+		return KindStylesheet
+	case ".js", ".mjs":
+		return KindScript
+	case ".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp", ".avif", ".ico", ".bmp":
+		return KindImage
+	case ".woff", ".woff2", ".ttf", ".otf", ".eot", ".mp3", ".mp4", ".webm", ".ogg", ".wav":
+		return KindMedia
+	}
+	return fallback
+}
+
+func isSpace(char byte) bool {
+	return char == ' ' || char == '\t' || char == '\n' || char == '\r' || char == '\f'
+}
+
 func isValidLink(link string) bool {
 	if link == "" {
 		return false
@@ -51,8 +405,9 @@ func isValidLink(link string) bool {
 		"#", "javascript:", "mailto:", "data:", "tel:", "ftp:",
 	}
 
+	lower := strings.ToLower(link)
 	for _, prefix := range invalidPrefixes {
-		if strings.HasPrefix(link, prefix) {
+		if strings.HasPrefix(lower, prefix) {
 			return false
 		}
 	}
@@ -60,19 +415,20 @@ func isValidLink(link string) bool {
 	return true
 }
 
+// ResolveURL приводит ссылку к абсолютному виду относительно base и отбрасывает фрагмент (#...).
 func ResolveURL(link string, base *url.URL) string {
 	if link == "" {
 		return ""
 	}
 
-	if strings.HasPrefix(link, "http://") || strings.HasPrefix(link, "https://") {
-		return link
-	}
-
 	absolute, err := base.Parse(link)
 	if err != nil {
 		return ""
 	}
+	if absolute.Scheme != "http" && absolute.Scheme != "https" {
+		return ""
+	}
+	absolute.Fragment = ""
 
 	return absolute.String()
 }
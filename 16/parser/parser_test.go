@@ -0,0 +1,299 @@
+package parser
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExtractLinks(t *testing.T) {
+	base, _ := url.Parse("http://example.com/dir/page.html")
+
+	document := `<!DOCTYPE html>
+<html>
+<head>
+	<base href="http://example.com/root/">
+	<meta http-equiv="refresh" content="5; URL='next.html'">
+	<link rel="stylesheet" href="style.css">
+	<link rel="icon" href="/favicon.ico">
+	<link rel="preconnect" href="http://cdn.example.com/">
+	<style>
+		body { background: url("bg.png"); }
+		@import 'print.css';
+		/* url(commented.png) */
+	</style>
+	<script src="app.js"></script>
+	<script>var s = "<a href='fake.html'>";</script>
+</head>
+<body>
+	<!-- <a href="hidden.html">hidden</a> -->
+	<a href="about.html#team">About</a>
+	<a href="about.html">About again</a>
+	<a href="mailto:info@example.com">Mail</a>
+	<img src="logo.png" srcset="logo-2x.png 2x, logo-3x.png 3x">
+	<video poster="poster.jpg"><source src="movie.mp4"></video>
+	<a href="javascript:void(0)">Nothing</a>
+</body>
+</html>`
+
+	expected := []Link{
+		{"http://example.com/root/next.html", KindPage},
+		{"http://example.com/root/style.css", KindStylesheet},
+		{"http://example.com/favicon.ico", KindImage},
+		{"http://example.com/root/bg.png", KindImage},
+		{"http://example.com/root/print.css", KindStylesheet},
+		{"http://example.com/root/app.js", KindScript},
+		{"http://example.com/root/about.html", KindPage},
+		{"http://example.com/root/logo.png", KindImage},
+		{"http://example.com/root/logo-2x.png", KindImage},
+		{"http://example.com/root/logo-3x.png", KindImage},
+		{"http://example.com/root/poster.jpg", KindImage},
+		{"http://example.com/root/movie.mp4", KindMedia},
+	}
+
+	result := ExtractLinks(document, base)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("ExtractLinks() =\n%v\nexpected\n%v", result, expected)
+	}
+}
+
+func TestParseSrcset(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"a.jpg", []string{"a.jpg"}},
+		{"a.jpg 1x, b.jpg 2x", []string{"a.jpg", "b.jpg"}},
+		{"small.jpg 480w,large.jpg 1080w", []string{"small.jpg", "large.jpg"}},
+		{"img,1.jpg 1x", []string{"img,1.jpg"}},
+		{"  ", nil},
+	}
+
+	for _, test := range tests {
+		result := ParseSrcset(test.input)
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("ParseSrcset(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestParseMetaRefresh(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0; url=http://example.com/", "http://example.com/"},
+		{"5;URL='page.html'", "page.html"},
+		{"3, next.html", "next.html"},
+		{"10", ""},
+	}
+
+	for _, test := range tests {
+		if result := parseMetaRefresh(test.input); result != test.expected {
+			t.Errorf("parseMetaRefresh(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestRewriteLinks(t *testing.T) {
+	base, _ := url.Parse("http://example.com/dir/page.html")
+
+	document := `<html><head><base href="/dir/">` +
+		`<link rel="stylesheet" href="style.css"><style>div { background: url(img/bg.png) }</style></head>` +
+		`<body class=main><a href="other.html#top">Other</a> <a href="missing.html">Missing</a> <a href="#local">Local</a>` +
+		`<img src="logo.png" srcset="logo.png 1x, logo-2x.png 2x"></body></html>`
+
+	local := map[string]string{
+		"http://example.com/dir/style.css":  "style.css",
+		"http://example.com/dir/other.html": "other.html",
+		"http://example.com/dir/img/bg.png": "img/bg.png",
+		"http://example.com/dir/logo.png":   "logo.png",
+	}
+	rewrite := func(link Link) (string, bool) {
+		path, ok := local[link.URL]
+		return path, ok
+	}
+
+	expected := `<html><head>` +
+		`<link rel="stylesheet" href="style.css"><style>div { background: url(img/bg.png) }</style></head>` +
+		`<body class=main><a href="other.html#top">Other</a> <a href="http://example.com/dir/missing.html">Missing</a> <a href="#local">Local</a>` +
+		`<img src="logo.png" srcset="logo.png 1x, http://example.com/dir/logo-2x.png 2x"></body></html>`
+
+	if result := RewriteLinks(document, base, rewrite); result != expected {
+		t.Errorf("RewriteLinks() =\n%s\nexpected\n%s", result, expected)
+	}
+}
+
+func TestRewriteCSSLinks(t *testing.T) {
+	base, _ := url.Parse("http://example.com/css/site.css")
+	css := `@import "reset.css"; /* url(skip.png) */ .a { background: url( 'a b.png' ) } .b { src: url(font.woff) }`
+
+	rewrite := func(link Link) (string, bool) {
+		if link.URL == "http://example.com/css/font.woff" {
+			return "../fonts/font.woff", true
+		}
+		return "", false
+	}
+
+	expected := `@import "http://example.com/css/reset.css"; /* url(skip.png) */ .a { background: url( "http://example.com/css/a%20b.png" ) } .b { src: url(../fonts/font.woff) }`
+	if result := RewriteCSSLinks(css, base, rewrite); result != expected {
+		t.Errorf("RewriteCSSLinks() =\n%s\nexpected\n%s", result, expected)
+	}
+}
+
+func TestExtractCSSLinks(t *testing.T) {
+	base, _ := url.Parse("http://example.com/css/site.css")
+	css := `@import url("base.css") screen;
+@import 'theme.css';
+/* .old { background: url(old.png) } */
+@font-face { font-family: X; src: url(../fonts/x.woff2) format("woff2"), url('../fonts/x.ttf'); }
+.logo { background-image: url( img/logo.svg ); content: "url(not-a-link.png)"; }
+.data { background: url(data:image/png;base64,AAAA); }
+.icon { mask: url(#mask); }`
+
+	expected := []Link{
+		{"http://example.com/css/base.css", KindStylesheet},
+		{"http://example.com/css/theme.css", KindStylesheet},
+		{"http://example.com/fonts/x.woff2", KindMedia},
+		{"http://example.com/fonts/x.ttf", KindMedia},
+		{"http://example.com/css/img/logo.svg", KindImage},
+	}
+
+	result := ExtractCSSLinks(css, base)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("ExtractCSSLinks() =\n%v\nexpected\n%v", result, expected)
+	}
+}
+
+func TestExtractLinksStyleAttribute(t *testing.T) {
+	base, _ := url.Parse("http://example.com/")
+	document := `<div style="background: url('hero.jpg')"></div><p style="color: red">text</p>`
+
+	expected := []Link{{"http://example.com/hero.jpg", KindImage}}
+	if result := ExtractLinks(document, base); !reflect.DeepEqual(result, expected) {
+		t.Errorf("ExtractLinks() = %v, expected %v", result, expected)
+	}
+}
+
+func TestParseSitemap(t *testing.T) {
+	base, _ := url.Parse("http://example.com/sitemap.xml")
+	urlset := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:image="http://www.google.com/schemas/sitemap-image/1.1">
+	<url><loc>http://example.com/a.html</loc><lastmod>2024-05-01</lastmod></url>
+	<url>
+		<loc> http://example.com/b.html?x=1&amp;y=2 </loc>
+		<lastmod>2024-06-02T10:30:00+03:00</lastmod>
+		<image:image><image:loc>http://example.com/b.jpg</image:loc></image:image>
+	</url>
+	<url><loc>/relative.html</loc></url>
+</urlset>`
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(urlset))
+	writer.Close()
+
+	expected := &Sitemap{Pages: []DatedURL{
+		{"http://example.com/a.html", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"http://example.com/b.html?x=1&y=2", time.Date(2024, 6, 2, 7, 30, 0, 0, time.UTC)},
+		{"http://example.com/relative.html", time.Time{}},
+	}}
+	for _, content := range [][]byte{[]byte(urlset), compressed.Bytes()} {
+		sitemap, err := ParseSitemap(content, base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sitemap.Pages) != len(expected.Pages) || len(sitemap.Sitemaps) != 0 {
+			t.Fatalf("ParseSitemap() = %+v, expected %+v", sitemap, expected)
+		}
+		for i, page := range sitemap.Pages {
+			if page.URL != expected.Pages[i].URL || !page.LastModified.Equal(expected.Pages[i].LastModified) {
+				t.Errorf("page %d = %+v, expected %+v", i, page, expected.Pages[i])
+			}
+		}
+	}
+
+	index := `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap><loc>http://example.com/pages.xml.gz</loc><lastmod>2024-01-01</lastmod></sitemap>
+</sitemapindex>`
+	sitemap, err := ParseSitemap([]byte(index), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sitemap.Pages) != 0 || len(sitemap.Sitemaps) != 1 || sitemap.Sitemaps[0].URL != "http://example.com/pages.xml.gz" {
+		t.Errorf("ParseSitemap(index) = %+v", sitemap)
+	}
+
+	sitemap, err = ParseSitemap([]byte("http://example.com/one\n\nhttp://example.com/two\nnot a url\n"), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sitemap.Pages) != 3 || sitemap.Pages[1].URL != "http://example.com/two" {
+		t.Errorf("ParseSitemap(text) = %+v", sitemap)
+	}
+}
+
+func TestParseFeed(t *testing.T) {
+	base, _ := url.Parse("http://example.com/feed")
+	tests := []struct {
+		name     string
+		feed     string
+		expected []DatedURL
+	}{
+		{
+			"rss",
+			`<rss version="2.0"><channel><link>http://example.com/</link>
+				<item><title>One</title><link>http://example.com/posts/1</link><pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate></item>
+				<item><guid>http://example.com/posts/2</guid></item>
+				<item><guid isPermaLink="false">id-3</guid></item>
+			</channel></rss>`,
+			[]DatedURL{
+				{"http://example.com/posts/1", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+				{"http://example.com/posts/2", time.Time{}},
+			},
+		},
+		{
+			"atom",
+			`<feed xmlns="http://www.w3.org/2005/Atom"><link rel="self" href="/feed"/>
+				<entry>
+					<link rel="enclosure" href="/audio.mp3"/>
+					<link rel="alternate" type="text/html" href="/posts/a"/>
+					<published>2024-01-01T00:00:00Z</published><updated>2024-02-01T00:00:00Z</updated>
+				</entry>
+				<entry><link href="http://example.com/posts/b"/></entry>
+			</feed>`,
+			[]DatedURL{
+				{"http://example.com/posts/a", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+				{"http://example.com/posts/b", time.Time{}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		entries, err := ParseFeed([]byte(test.feed), base)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if len(entries) != len(test.expected) {
+			t.Fatalf("%s: ParseFeed() = %+v, expected %+v", test.name, entries, test.expected)
+		}
+		for i, entry := range entries {
+			if entry.URL != test.expected[i].URL || !entry.LastModified.Equal(test.expected[i].LastModified) {
+				t.Errorf("%s: entry %d = %+v, expected %+v", test.name, i, entry, test.expected[i])
+			}
+		}
+	}
+}
+
+func TestExtractLinksFeed(t *testing.T) {
+	base, _ := url.Parse("http://example.com/")
+	document := `<link rel="alternate" type="application/rss+xml" href="/rss.xml"><link rel="alternate" hreflang="de" href="/de/">`
+
+	expected := []Link{{"http://example.com/rss.xml", KindFeed}, {"http://example.com/de/", KindPage}}
+	if result := ExtractLinks(document, base); !reflect.DeepEqual(result, expected) {
+		t.Errorf("ExtractLinks() = %v, expected %v", result, expected)
+	}
+}
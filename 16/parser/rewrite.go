@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// RewriteLinks заменяет ссылки в HTML-документе. Для каждой ссылки вызывается rewrite с ее
+// абсолютным адресом (без фрагмента); если он возвращает true, ссылка заменяется результатом,
+// иначе - абсолютным адресом. Фрагмент (#...) сохраняется, а тег <base> удаляется,
+// так как замененные ссылки относительны самого файла. Остальной текст документа не меняется.
+func RewriteLinks(document string, base *url.URL, rewrite func(Link) (string, bool)) string {
+	base = documentBase(document, base)
+	tokenizer := html.NewTokenizer(strings.NewReader(document))
+
+	var result strings.Builder
+	inStyle := false
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+		raw := string(tokenizer.Raw())
+
+		switch tokenType {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if token.DataAtom == atom.Base {
+				if _, ok := attribute(token, "href"); ok {
+					continue
+				}
+			}
+			if token.DataAtom == atom.Style && tokenType == html.StartTagToken {
+				inStyle = true
+			}
+			if rewriteTag(&token, base, rewrite) {
+				raw = token.String()
+			}
+
+		case html.EndTagToken:
+			if tokenizer.Token().DataAtom == atom.Style {
+				inStyle = false
+			}
+
+		case html.TextToken:
+			if inStyle {
+				raw = RewriteCSSLinks(raw, base, rewrite)
+			}
+		}
+
+		result.WriteString(raw)
+	}
+
+	return result.String()
+}
+
+// rewriteTag заменяет ссылки в атрибутах тега и сообщает, изменился ли он.
+func rewriteTag(token *html.Token, base *url.URL, rewrite func(Link) (string, bool)) bool {
+	changed := false
+	for _, link := range linkAttributes(*token) {
+		for i := range token.Attr {
+			attr := &token.Attr[i]
+			if attr.Namespace != "" || attr.Key != link.name {
+				continue
+			}
+			if value := rewriteAttribute(attr.Val, link, base, rewrite); value != attr.Val {
+				attr.Val = value
+				changed = true
+			}
+			break
+		}
+	}
+	return changed
+}
+
+func rewriteAttribute(value string, link linkAttribute, base *url.URL, rewrite func(Link) (string, bool)) string {
+	switch link.format {
+	case formatSrcset:
+		candidates := parseSrcsetCandidates(value)
+		parts := make([]string, 0, len(candidates))
+		for _, candidate := range candidates {
+			part := rewriteReference(candidate.url, link.kind, base, rewrite)
+			if candidate.descriptor != "" {
+				part += " " + candidate.descriptor
+			}
+			parts = append(parts, part)
+		}
+		return strings.Join(parts, ", ")
+
+	case formatCSS:
+		return RewriteCSSLinks(value, base, rewrite)
+
+	case formatRefresh:
+		target := parseMetaRefresh(value)
+		if target == "" {
+			return value
+		}
+		index := strings.LastIndex(value, target)
+		return value[:index] + rewriteReference(target, link.kind, base, rewrite) + value[index+len(target):]
+	}
+
+	return rewriteReference(value, link.kind, base, rewrite)
+}
+
+// rewriteReference возвращает новое значение одной ссылки; служебные ссылки
+// (якоря, mailto:, javascript: и т.п.) и ссылки на другие схемы не меняются.
+func rewriteReference(value string, kind LinkKind, base *url.URL, rewrite func(Link) (string, bool)) string {
+	trimmed := strings.TrimSpace(value)
+	if !isValidLink(trimmed) {
+		return value
+	}
+
+	absolute, err := base.Parse(trimmed)
+	if err != nil || (absolute.Scheme != "http" && absolute.Scheme != "https") {
+		return value
+	}
+
+	fragment := ""
+	if absolute.Fragment != "" {
+		fragment = "#" + absolute.EscapedFragment()
+	}
+	absolute.Fragment = ""
+	absolute.RawFragment = ""
+
+	if local, ok := rewrite(Link{URL: absolute.String(), Kind: kind}); ok {
+		return local + fragment
+	}
+	return absolute.String() + fragment
+}
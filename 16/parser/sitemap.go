@@ -0,0 +1,179 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Максимальный размер карты сайта после распаковки (ограничение протокола sitemaps.org)
+const maxSitemapSize = 50 << 20
+
+// DatedURL - адрес из карты сайта или ленты с датой последнего изменения (нулевой, если неизвестна)
+type DatedURL struct {
+	URL          string
+	LastModified time.Time
+}
+
+// Sitemap - содержимое карты сайта: адреса страниц и вложенные карты (для индекса карт)
+type Sitemap struct {
+	Pages    []DatedURL
+	Sitemaps []DatedURL
+}
+
+// ParseSitemap разбирает карту сайта в формате XML (<urlset> или индекс <sitemapindex>)
+// или текстовом (по адресу в строке). Сжатые gzip карты распаковываются.
+func ParseSitemap(content []byte, base *url.URL) (*Sitemap, error) {
+	content, err := decompress(content)
+	if err != nil {
+		return nil, err
+	}
+
+	sitemap := &Sitemap{}
+	if !bytes.HasPrefix(bytes.TrimSpace(content), []byte("<")) {
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			if link := ResolveURL(strings.TrimSpace(scanner.Text()), base); link != "" {
+				sitemap.Pages = append(sitemap.Pages, DatedURL{URL: link})
+			}
+		}
+		return sitemap, scanner.Err()
+	}
+
+	var entry DatedURL
+	err = walkXML(content, func(path []string, text string, _ map[string]string) {
+		if len(path) < 2 {
+			return
+		}
+		parent := path[len(path)-2]
+		if parent != "url" && parent != "sitemap" {
+			return
+		}
+		switch path[len(path)-1] {
+		case "loc":
+			entry.URL = ResolveURL(text, base)
+		case "lastmod":
+			entry.LastModified = parseDate(text)
+		}
+	}, func(name string) {
+		if entry.URL != "" {
+			switch name {
+			case "url":
+				sitemap.Pages = append(sitemap.Pages, entry)
+			case "sitemap":
+				sitemap.Sitemaps = append(sitemap.Sitemaps, entry)
+			}
+		}
+		if name == "url" || name == "sitemap" {
+			entry = DatedURL{}
+		}
+	})
+	return sitemap, err
+}
+
+// decompress распаковывает содержимое, если оно сжато gzip.
+func decompress(content []byte) ([]byte, error) {
+	if len(content) < 2 || content[0] != 0x1f || content[1] != 0x8b {
+		return content, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	decompressed, err := io.ReadAll(io.LimitReader(reader, maxSitemapSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(decompressed) > maxSitemapSize {
+		return nil, errors.New("карта сайта больше 50 МБ")
+	}
+	return decompressed, nil
+}
+
+// Элемент XML: локальное имя и атрибуты без учета пространств имен
+type xmlElement struct {
+	name  string
+	attrs map[string]string
+}
+
+// walkXML обходит элементы XML без учета пространств имен: element вызывается при закрытии
+// каждого элемента с путем из локальных имен, текстом и атрибутами элемента; end - после него.
+func walkXML(content []byte, element func(path []string, text string, attrs map[string]string), end func(name string)) error {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	decoder.Strict = false
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var stack []xmlElement
+	var path []string
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			current := xmlElement{name: strings.ToLower(token.Name.Local), attrs: make(map[string]string)}
+			for _, attr := range token.Attr {
+				current.attrs[strings.ToLower(attr.Name.Local)] = strings.TrimSpace(attr.Value)
+			}
+			stack = append(stack, current)
+			path = append(path, current.name)
+			text.Reset()
+		case xml.CharData:
+			text.Write(token)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				continue
+			}
+			current := stack[len(stack)-1]
+			element(path, strings.TrimSpace(text.String()), current.attrs)
+			text.Reset()
+			stack = stack[:len(stack)-1]
+			path = path[:len(path)-1]
+			end(current.name)
+		}
+	}
+}
+
+// Форматы дат карт сайта (W3C Datetime) и лент (RFC 822/1123 в RSS, RFC 3339 в Atom)
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC822Z,
+	time.RFC822,
+}
+
+// parseDate разбирает дату; нераспознанная дата считается неизвестной.
+func parseDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,179 @@
+package robots
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Правило Allow/Disallow
+type rule struct {
+	pattern string
+	allow   bool
+}
+
+// Группа правил для одного или нескольких user-agent
+type group struct {
+	agents     []string
+	rules      []rule
+	crawlDelay time.Duration
+}
+
+// Rules - правила robots.txt, применимые к нашему user-agent
+type Rules struct {
+	rules      []rule
+	crawlDelay time.Duration
+	sitemaps   []string
+}
+
+// Parse разбирает robots.txt и выбирает группы для userAgent: группы с наиболее длинным
+// совпадающим именем агента (без учета регистра), иначе группы "*".
+func Parse(content, userAgent string) *Rules {
+	groups, sitemaps := parseGroups(content)
+	agent := strings.ToLower(userAgent)
+
+	best := -1
+	var selected []*group
+	for _, g := range groups {
+		score := -1
+		for _, name := range g.agents {
+			if name == "*" {
+				score = max(score, 0)
+			} else if strings.Contains(agent, name) {
+				score = max(score, len(name))
+			}
+		}
+
+		if score < 0 || score < best {
+			continue
+		}
+		if score > best {
+			best = score
+			selected = nil
+		}
+		selected = append(selected, g)
+	}
+
+	result := &Rules{sitemaps: sitemaps}
+	for _, g := range selected {
+		result.rules = append(result.rules, g.rules...)
+		result.crawlDelay = max(result.crawlDelay, g.crawlDelay)
+	}
+	return result
+}
+
+// parseGroups возвращает группы правил и адреса карт сайта (строки Sitemap не относятся к группам).
+func parseGroups(content string) ([]*group, []string) {
+	var groups []*group
+	var sitemaps []string
+	var current *group
+	inAgents := false
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "sitemap":
+			if value != "" {
+				sitemaps = append(sitemaps, value)
+			}
+			continue
+
+		case "user-agent":
+			// Подряд идущие строки User-agent относятся к одной группе
+			if !inAgents {
+				current = &group{}
+				groups = append(groups, current)
+				inAgents = true
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			continue
+
+		case "allow", "disallow":
+			// Пустой Disallow ничего не запрещает
+			if current != nil && value != "" {
+				current.rules = append(current.rules, rule{pattern: value, allow: key == "allow"})
+			}
+
+		case "crawl-delay":
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && current != nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+		inAgents = false
+	}
+
+	return groups, sitemaps
+}
+
+// Allowed сообщает, разрешен ли путь (вместе со строкой запроса). Побеждает самое длинное
+// совпавшее правило; при равной длине Allow имеет приоритет.
+func (r *Rules) Allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+
+	allowed := true
+	longest := -1
+	for _, rule := range r.rules {
+		if !matchPattern(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > longest || (len(rule.pattern) == longest && rule.allow) {
+			longest = len(rule.pattern)
+			allowed = rule.allow
+		}
+	}
+	return allowed
+}
+
+// CrawlDelay возвращает минимальный интервал между запросами к хосту (0 - не задан).
+func (r *Rules) CrawlDelay() time.Duration {
+	return r.crawlDelay
+}
+
+// Sitemaps возвращает адреса карт сайта из строк Sitemap.
+func (r *Rules) Sitemaps() []string {
+	return r.sitemaps
+}
+
+// matchPattern сопоставляет путь с шаблоном robots.txt: шаблон задает префикс пути,
+// '*' соответствует любой последовательности символов, '$' в конце - концу пути.
+func matchPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	if len(parts) == 1 {
+		return !anchored || rest == ""
+	}
+
+	for _, part := range parts[1 : len(parts)-1] {
+		index := strings.Index(rest, part)
+		if index < 0 {
+			return false
+		}
+		rest = rest[index+len(part):]
+	}
+
+	last := parts[len(parts)-1]
+	if anchored {
+		return strings.HasSuffix(rest, last)
+	}
+	return strings.Contains(rest, last)
+}
@@ -0,0 +1,113 @@
+package robots
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const robotsFile = `# robots.txt
+User-agent: *
+Disallow: /private/
+Disallow: /*.pdf$
+Allow: /private/public*
+Crawl-delay: 1
+
+User-agent: MyDownloader
+User-agent: OtherBot
+Disallow: /tmp
+Allow: /tmp/open
+Crawl-delay: 0.5
+
+User-agent: BadBot
+Disallow: /
+`
+
+func TestParseSelectsGroup(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		path      string
+		allowed   bool
+	}{
+		{"Mozilla/5.0 (compatible; SomeBot/1.0)", "/private/data.html", false},
+		{"Mozilla/5.0 (compatible; SomeBot/1.0)", "/private/public/a.html", true},
+		{"Mozilla/5.0 (compatible; SomeBot/1.0)", "/docs/report.pdf", false},
+		{"Mozilla/5.0 (compatible; SomeBot/1.0)", "/docs/report.pdf?download=1", true},
+		{"Mozilla/5.0 (compatible; SomeBot/1.0)", "/tmp/file", true},
+		{"Mozilla/5.0 (compatible; MyDownloader/1.0)", "/private/data.html", true},
+		{"Mozilla/5.0 (compatible; MyDownloader/1.0)", "/tmp/file", false},
+		{"Mozilla/5.0 (compatible; MyDownloader/1.0)", "/tmp/open/file", true},
+		{"BadBot/2.0", "/index.html", false},
+		{"BadBot/2.0", "/robots.txt", true},
+	}
+
+	for _, test := range tests {
+		rules := Parse(robotsFile, test.userAgent)
+		if allowed := rules.Allowed(test.path); allowed != test.allowed {
+			t.Errorf("Parse(%q).Allowed(%q) = %v, expected %v", test.userAgent, test.path, allowed, test.allowed)
+		}
+	}
+}
+
+func TestCrawlDelay(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		expected  time.Duration
+	}{
+		{"SomeBot", time.Second},
+		{"MyDownloader/1.0", 500 * time.Millisecond},
+		{"BadBot", 0},
+	}
+
+	for _, test := range tests {
+		if delay := Parse(robotsFile, test.userAgent).CrawlDelay(); delay != test.expected {
+			t.Errorf("CrawlDelay for %q = %v, expected %v", test.userAgent, delay, test.expected)
+		}
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		matched bool
+	}{
+		{"/", "/anything", true},
+		{"/fish", "/fish.html", true},
+		{"/fish", "/Fish.html", false},
+		{"/fish*", "/fishheads/yummy.html", true},
+		{"/*.php", "/folder/filename.php?parameters", true},
+		{"/*.php$", "/filename.php", true},
+		{"/*.php$", "/filename.php?parameters", false},
+		{"/fish*.php", "/fishheads/catfish.php?parameters", true},
+		{"/fish*.php", "/Fish.PHP", false},
+		{"/a$", "/a", true},
+		{"/a$", "/ab", false},
+	}
+
+	for _, test := range tests {
+		if matched := matchPattern(test.pattern, test.path); matched != test.matched {
+			t.Errorf("matchPattern(%q, %q) = %v, expected %v", test.pattern, test.path, matched, test.matched)
+		}
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	rules := Parse("", "MyDownloader")
+	if !rules.Allowed("/any/path") || rules.CrawlDelay() != 0 {
+		t.Errorf("empty robots.txt must allow everything without delay")
+	}
+}
+
+func TestSitemaps(t *testing.T) {
+	content := "Sitemap: http://example.com/sitemap.xml\nUser-agent: *\nDisallow: /private/\nSitemap: http://example.com/news.xml.gz\n"
+	rules := Parse(content, "MyDownloader")
+
+	expected := []string{"http://example.com/sitemap.xml", "http://example.com/news.xml.gz"}
+	if sitemaps := rules.Sitemaps(); !reflect.DeepEqual(sitemaps, expected) {
+		t.Errorf("Sitemaps() = %v, expected %v", sitemaps, expected)
+	}
+	if rules.Allowed("/private/a") {
+		t.Error("Sitemap line broke the rule group")
+	}
+}
@@ -0,0 +1,97 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Record - прочитанная запись WARC
+type Record struct {
+	Header Fields
+	Block  []byte
+}
+
+// Type возвращает значение WARC-Type.
+func (r *Record) Type() string {
+	return r.Header.Get("WARC-Type")
+}
+
+// Reader последовательно читает записи WARC-файла (сжатого или нет)
+type Reader struct {
+	reader *bufio.Reader
+}
+
+// NewReader создает Reader; сжатие gzip определяется автоматически.
+func NewReader(r io.Reader) (*Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		decompressed, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+		return &Reader{reader: bufio.NewReader(decompressed)}, nil
+	}
+	return &Reader{reader: buffered}, nil
+}
+
+// Next возвращает следующую запись или io.EOF в конце файла.
+func (r *Reader) Next() (*Record, error) {
+	line, err := r.readLine()
+	for err == nil && line == "" {
+		line, err = r.readLine()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "WARC/") {
+		return nil, fmt.Errorf("warc: unexpected record start %q", line)
+	}
+
+	record := &Record{}
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, fmt.Errorf("warc: reading header: %w", err)
+		}
+		if line == "" {
+			break
+		}
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			return nil, fmt.Errorf("warc: malformed header line %q", line)
+		}
+		record.Header = append(record.Header, Field{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+	}
+
+	length, err := strconv.ParseInt(record.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("warc: bad Content-Length: %w", err)
+	}
+	record.Block = make([]byte, length)
+	if _, err := io.ReadFull(r.reader, record.Block); err != nil {
+		return nil, fmt.Errorf("warc: reading block: %w", err)
+	}
+
+	trailer := make([]byte, 4)
+	if _, err := io.ReadFull(r.reader, trailer); err != nil || !bytes.Equal(trailer, []byte("\r\n\r\n")) {
+		return nil, fmt.Errorf("warc: missing record trailer")
+	}
+	return record, nil
+}
+
+func (r *Reader) readLine() (string, error) {
+	line, err := r.reader.ReadString('\n')
+	if err != nil {
+		if err == io.EOF && line != "" {
+			return "", io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,402 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const version = "WARC/1.1"
+
+// Типы записей WARC
+const (
+	TypeWarcinfo = "warcinfo"
+	TypeRequest  = "request"
+	TypeResponse = "response"
+	TypeMetadata = "metadata"
+)
+
+// Field - поле заголовка записи или строка warcinfo/metadata ("name: value")
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Fields - упорядоченный набор полей
+type Fields []Field
+
+// Get возвращает значение первого поля с указанным именем (без учета регистра).
+func (f Fields) Get(name string) string {
+	for _, field := range f {
+		if strings.EqualFold(field.Name, name) {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// Bytes сериализует поля в формат "name: value\r\n" (тело записей warcinfo и metadata).
+func (f Fields) Bytes() []byte {
+	var buffer bytes.Buffer
+	for _, field := range f {
+		fmt.Fprintf(&buffer, "%s: %s\r\n", field.Name, field.Value)
+	}
+	return buffer.Bytes()
+}
+
+// Response описывает HTTP-ответ для записи response и строки CDX-индекса
+type Response struct {
+	TargetURI    string
+	ConcurrentTo string // ID записи запроса
+	Header       []byte // строка статуса и заголовки HTTP, включая пустую строку
+	Body         io.ReadSeeker
+	Status       int
+	MimeType     string
+	Location     string // адрес перенаправления для 3xx
+	Truncated    string // причина неполного тела для WARC-Truncated ("length", "disconnect", "unspecified")
+}
+
+// Строка CDX-индекса
+type cdxEntry struct {
+	urlKey    string
+	timestamp string
+	original  string
+	mimeType  string
+	status    int
+	digest    string
+	redirect  string
+	length    int64
+	offset    int64
+}
+
+// Writer записывает WARC-файл, сжимая каждую запись отдельным gzip-блоком,
+// и при закрытии создает CDX-индекс ответов
+type Writer struct {
+	mu       sync.Mutex
+	file     *os.File
+	filename string
+	cdxPath  string
+	offset   int64
+	entries  []cdxEntry
+}
+
+// Create создает WARC-файл name (расширение .warc.gz добавляется, если его нет)
+// и записывает запись warcinfo с полями info.
+func Create(name string, info Fields) (*Writer, error) {
+	base := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".warc")
+	filename := base + ".warc.gz"
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Writer{file: file, filename: filename, cdxPath: base + ".cdx"}
+	header := Fields{
+		{"WARC-Type", TypeWarcinfo},
+		{"WARC-Filename", filepath.Base(filename)},
+		{"Content-Type", "application/warc-fields"},
+	}
+	if _, err := w.writeRecord(header, bytes.NewReader(info.Bytes()), ""); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Filename возвращает имя WARC-файла.
+func (w *Writer) Filename() string {
+	return w.filename
+}
+
+// WriteRequest записывает запись request с сырым HTTP-запросом и возвращает ID записи.
+func (w *Writer) WriteRequest(targetURI string, request []byte) (string, error) {
+	header := Fields{
+		{"WARC-Type", TypeRequest},
+		{"WARC-Target-URI", targetURI},
+		{"Content-Type", "application/http;msgtype=request"},
+	}
+	id, _, err := w.writeRecordLocked(header, bytes.NewReader(request), "")
+	return id, err
+}
+
+// WriteResponse записывает запись response (заголовки HTTP + тело) и добавляет ее в CDX-индекс.
+func (w *Writer) WriteResponse(response Response) (string, error) {
+	header := Fields{
+		{"WARC-Type", TypeResponse},
+		{"WARC-Target-URI", response.TargetURI},
+		{"Content-Type", "application/http;msgtype=response"},
+	}
+	if response.ConcurrentTo != "" {
+		header = append(header, Field{"WARC-Concurrent-To", response.ConcurrentTo})
+	}
+
+	payloadDigest, err := digest(response.Body)
+	if err != nil {
+		return "", err
+	}
+	header = append(header, Field{"WARC-Payload-Digest", payloadDigest})
+	if response.Truncated != "" {
+		header = append(header, Field{"WARC-Truncated", response.Truncated})
+	}
+
+	block := &multiReadSeeker{head: response.Header, body: response.Body}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	offset := w.offset
+	id, date, err := w.writeRecordUnlocked(header, block, "")
+	if err != nil {
+		return "", err
+	}
+
+	w.entries = append(w.entries, cdxEntry{
+		urlKey:    URLKey(response.TargetURI),
+		timestamp: date.Format("20060102150405"),
+		original:  response.TargetURI,
+		mimeType:  response.MimeType,
+		status:    response.Status,
+		digest:    strings.TrimPrefix(payloadDigest, "sha1:"),
+		redirect:  response.Location,
+		length:    w.offset - offset,
+		offset:    offset,
+	})
+	return id, nil
+}
+
+// WriteMetadata записывает запись metadata, относящуюся к записи concurrentTo (может быть пустым).
+func (w *Writer) WriteMetadata(targetURI, concurrentTo string, fields Fields) (string, error) {
+	header := Fields{
+		{"WARC-Type", TypeMetadata},
+		{"WARC-Target-URI", targetURI},
+		{"Content-Type", "application/warc-fields"},
+	}
+	if concurrentTo != "" {
+		header = append(header, Field{"WARC-Concurrent-To", concurrentTo})
+	}
+	id, _, err := w.writeRecordLocked(header, bytes.NewReader(fields.Bytes()), "")
+	return id, err
+}
+
+// Close закрывает WARC-файл и записывает CDX-индекс.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	return w.writeCDX()
+}
+
+func (w *Writer) writeRecord(header Fields, block io.ReadSeeker, id string) (string, error) {
+	id, _, err := w.writeRecordLocked(header, block, id)
+	return id, err
+}
+
+func (w *Writer) writeRecordLocked(header Fields, block io.ReadSeeker, id string) (string, time.Time, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writeRecordUnlocked(header, block, id)
+}
+
+// writeRecordUnlocked дописывает запись отдельным gzip-блоком (вызывается под мьютексом).
+func (w *Writer) writeRecordUnlocked(header Fields, block io.ReadSeeker, id string) (string, time.Time, error) {
+	if id == "" {
+		id = newRecordID()
+	}
+	date := time.Now().UTC()
+
+	blockDigest, err := digest(block)
+	if err != nil {
+		return "", date, err
+	}
+	length, err := block.Seek(0, io.SeekEnd)
+	if err != nil {
+		return "", date, err
+	}
+	if _, err := block.Seek(0, io.SeekStart); err != nil {
+		return "", date, err
+	}
+
+	fields := Fields{
+		{"WARC-Type", header.Get("WARC-Type")},
+		{"WARC-Record-ID", id},
+		{"WARC-Date", date.Format(time.RFC3339)},
+	}
+	for _, field := range header {
+		if field.Name != "WARC-Type" {
+			fields = append(fields, field)
+		}
+	}
+	fields = append(fields,
+		Field{"WARC-Block-Digest", blockDigest},
+		Field{"Content-Length", strconv.FormatInt(length, 10)},
+	)
+
+	counter := &countingWriter{writer: w.file}
+	compressed := gzip.NewWriter(counter)
+	buffered := bufio.NewWriter(compressed)
+
+	buffered.WriteString(version + "\r\n")
+	buffered.Write(fields.Bytes())
+	buffered.WriteString("\r\n")
+	if _, err := io.Copy(buffered, block); err != nil {
+		return "", date, err
+	}
+	buffered.WriteString("\r\n\r\n")
+
+	if err := buffered.Flush(); err != nil {
+		return "", date, err
+	}
+	if err := compressed.Close(); err != nil {
+		return "", date, err
+	}
+
+	w.offset += counter.count
+	return id, date, nil
+}
+
+// writeCDX записывает индекс в формате CDX с полями N b a m s k r M S V g.
+func (w *Writer) writeCDX() error {
+	entries := append([]cdxEntry(nil), w.entries...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].urlKey != entries[j].urlKey {
+			return entries[i].urlKey < entries[j].urlKey
+		}
+		return entries[i].timestamp < entries[j].timestamp
+	})
+
+	var buffer bytes.Buffer
+	buffer.WriteString(" CDX N b a m s k r M S V g\n")
+	name := filepath.Base(w.filename)
+	for _, entry := range entries {
+		fmt.Fprintf(&buffer, "%s %s %s %s %s %s %s - %d %d %s\n",
+			entry.urlKey, entry.timestamp, entry.original, orDash(entry.mimeType), orDash(strconv.Itoa(entry.status)),
+			orDash(entry.digest), orDash(entry.redirect), entry.length, entry.offset, name)
+	}
+	return os.WriteFile(w.cdxPath, buffer.Bytes(), 0644)
+}
+
+// URLKey возвращает ключ SURT для CDX: "com,example)/path?query".
+func URLKey(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return strings.ToLower(rawURL)
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	parts := strings.Split(host, ".")
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	key := strings.Join(parts, ",")
+	if port := parsed.Port(); port != "" && port != "80" && port != "443" {
+		key += ":" + port
+	}
+
+	path := parsed.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	key += ")" + strings.ToLower(path)
+	if parsed.RawQuery != "" {
+		key += "?" + strings.ToLower(parsed.RawQuery)
+	}
+	return key
+}
+
+func orDash(value string) string {
+	if value == "" || value == "0" {
+		return "-"
+	}
+	return value
+}
+
+// digest вычисляет SHA-1 содержимого в формате "sha1:BASE32" и возвращает позицию в начало.
+func digest(content io.ReadSeeker) (string, error) {
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	hash := sha1.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return "sha1:" + base32.StdEncoding.EncodeToString(hash.Sum(nil)), nil
+}
+
+func newRecordID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", id[0:4], id[4:6], id[6:8], id[8:10], id[10:])
+}
+
+// Счетчик записанных байтов (для смещений в CDX)
+type countingWriter struct {
+	writer io.Writer
+	count  int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.writer.Write(p)
+	c.count += int64(n)
+	return n, err
+}
+
+// multiReadSeeker склеивает заголовки HTTP и тело в один блок с поддержкой Seek
+type multiReadSeeker struct {
+	head     []byte
+	body     io.ReadSeeker
+	position int64
+}
+
+func (m *multiReadSeeker) Read(p []byte) (int, error) {
+	if m.position < int64(len(m.head)) {
+		n := copy(p, m.head[m.position:])
+		m.position += int64(n)
+		return n, nil
+	}
+	n, err := m.body.Read(p)
+	m.position += int64(n)
+	return n, err
+}
+
+func (m *multiReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekEnd:
+		size, err := m.body.Seek(0, io.SeekEnd)
+		if err != nil {
+			return 0, err
+		}
+		offset += int64(len(m.head)) + size
+	default:
+		return 0, fmt.Errorf("warc: unsupported seek whence %d", whence)
+	}
+
+	m.position = offset
+	bodyOffset := max(offset-int64(len(m.head)), 0)
+	if _, err := m.body.Seek(bodyOffset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return offset, nil
+}
@@ -0,0 +1,152 @@
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriterRoundTrip(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "crawl")
+	writer, err := Create(base, Fields{{"software", "test"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requestID, err := writer.WriteRequest("http://example.com/", []byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := "<html>hello</html>"
+	_, err = writer.WriteResponse(Response{
+		TargetURI:    "http://example.com/",
+		ConcurrentTo: requestID,
+		Header:       []byte("HTTP/1.1 200 OK\r\nContent-Type: text/html\r\n\r\n"),
+		Body:         strings.NewReader(body),
+		Status:       200,
+		MimeType:     "text/html",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.WriteMetadata("http://example.com/", "", Fields{{"outlink", "http://example.com/a page"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(base + ".warc.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	reader, err := NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []*Record
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+
+	types := []string{TypeWarcinfo, TypeRequest, TypeResponse, TypeMetadata}
+	if len(records) != len(types) {
+		t.Fatalf("read %d records, expected %d", len(records), len(types))
+	}
+	for i, record := range records {
+		if record.Type() != types[i] {
+			t.Errorf("record %d type = %q, expected %q", i, record.Type(), types[i])
+		}
+		if digest, _ := digestOf(record.Block); record.Header.Get("WARC-Block-Digest") != digest {
+			t.Errorf("record %d block digest mismatch", i)
+		}
+	}
+
+	response := records[2]
+	if response.Header.Get("WARC-Concurrent-To") != requestID {
+		t.Errorf("WARC-Concurrent-To = %q, expected %q", response.Header.Get("WARC-Concurrent-To"), requestID)
+	}
+	if !bytes.HasSuffix(response.Block, []byte(body)) {
+		t.Errorf("response block = %q", response.Block)
+	}
+	if digest, _ := digestOf([]byte(body)); response.Header.Get("WARC-Payload-Digest") != digest {
+		t.Errorf("payload digest = %q, expected %q", response.Header.Get("WARC-Payload-Digest"), digest)
+	}
+}
+
+func TestCDXOffsets(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "crawl.warc.gz")
+	writer, err := Create(base, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/b", "/a"} {
+		_, err := writer.WriteResponse(Response{
+			TargetURI: "http://www.Example.com" + path,
+			Header:    []byte("HTTP/1.1 301 Moved Permanently\r\nLocation: /new\r\n\r\n"),
+			Body:      strings.NewReader(""),
+			Status:    301,
+			Location:  "/new",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := os.ReadFile(strings.TrimSuffix(base, ".warc.gz") + ".cdx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(index), "\n"), "\n")
+	if len(lines) != 3 || lines[0] != " CDX N b a m s k r M S V g" {
+		t.Fatalf("unexpected CDX:\n%s", index)
+	}
+
+	content, err := os.ReadFile(writer.Filename())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []string{"com,example)/a", "com,example)/b"} {
+		fields := strings.Fields(lines[i+1])
+		if len(fields) != 11 || fields[0] != expected || fields[4] != "301" || fields[6] != "/new" {
+			t.Fatalf("CDX line %q", lines[i+1])
+		}
+
+		// По смещению и длине из индекса читается ровно одна запись
+		length, _ := strconv.ParseInt(fields[8], 10, 64)
+		offset, _ := strconv.ParseInt(fields[9], 10, 64)
+		member, err := gzip.NewReader(bytes.NewReader(content[offset : offset+length]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader, _ := NewReader(member)
+		record, err := reader.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.Header.Get("WARC-Target-URI") != fields[2] {
+			t.Errorf("record at offset %d is %q, expected %q", offset, record.Header.Get("WARC-Target-URI"), fields[2])
+		}
+	}
+}
+
+func digestOf(content []byte) (string, error) {
+	return digest(bytes.NewReader(content))
+}
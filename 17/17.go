@@ -1,10 +1,9 @@
 package main
 
 import (
-	"bufio"
+	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"net"
 	"os"
 	"os/signal"
@@ -12,91 +11,121 @@ import (
 	"time"
 )
 
+// Коды завершения
+const (
+	exitOK            = 0
+	exitError         = 1 // неверные параметры, ошибка ввода-вывода, команды или сценария
+	exitConnectFailed = 2 // не удалось подключиться (в том числе за --timeout)
+	exitTimeout       = 3 // нет данных дольше --read-timeout/--idle-timeout или не дождались expect
+	exitRemoteClosed  = 4 // сервер закрыл соединение раньше, чем закончился ввод
+)
+
 func main() {
+	os.Exit(run())
+}
+
+// run разбирает параметры и запускает клиент или сервер; возвращает код завершения.
+func run() int {
 	timeout := flag.Int("timeout", 10, "Connection timeout in seconds")
-	flag.Parse()
 
-	args := flag.Args()
-	if len(args) < 2 {
-		fmt.Fprintf(os.Stderr, "Usage: %s [--timeout=10] host port\n", os.Args[0])
-		os.Exit(1)
-	}
+	var client clientOptions
+	flag.BoolVar(&client.raw, "raw", false, "Plain TCP mode without telnet option negotiation")
+	flag.DurationVar(&client.readTimeout, "read-timeout", 0, "Exit if nothing is received for this long (e.g. 30s)")
+	flag.DurationVar(&client.idleTimeout, "idle-timeout", 0, "Exit if nothing is sent or received for this long")
+	flag.BoolVar(&client.reconnect, "reconnect", false, "Reconnect with exponential backoff when connecting fails or the connection is lost")
+	flag.DurationVar(&client.reconnectDelay, "reconnect-delay", time.Second, "Initial --reconnect delay, doubled after each failure up to 30s")
+
+	var options dialOptions
+	flag.BoolVar(&options.udp, "u", false, "UDP mode: each input line is sent as one datagram (implies --raw)")
+	flag.BoolVar(&options.unix, "U", false, "Connect to a unix socket path instead of host and port")
+	flag.BoolVar(&options.ipv4, "4", false, "Use IPv4 only")
+	flag.BoolVar(&options.ipv6, "6", false, "Use IPv6 only")
+	flag.BoolVar(&options.tls, "tls", false, "Connect over TLS")
+	flag.StringVar(&options.serverName, "sni", "", "TLS server name (SNI) and name to verify, defaults to host")
+	flag.BoolVar(&options.insecure, "insecure", false, "Don't verify the TLS server certificate")
+	flag.StringVar(&options.caFile, "ca", "", "PEM file with CA certificates to verify the server (with -l, to require client certificates)")
+	flag.StringVar(&options.certFile, "cert", "", "PEM file with TLS client certificate (with -l, server certificate)")
+	flag.StringVar(&options.keyFile, "key", "", "PEM file with TLS private key for --cert")
+	flag.DurationVar(&options.keepAlive.Idle, "keepalive", 0, "TCP keepalive idle time before the first probe (0 means 15s)")
+	flag.DurationVar(&options.keepAlive.Interval, "keepalive-interval", 0, "Interval between TCP keepalive probes (0 means 15s)")
+	flag.IntVar(&options.keepAlive.Count, "keepalive-count", 0, "Unanswered TCP keepalive probes before the connection is dropped (0 means 9)")
+	noKeepAlive := flag.Bool("no-keepalive", false, "Disable TCP keepalive")
 
-	host := args[0]
-	port := args[1]
-	address := net.JoinHostPort(host, port)
+	listen := flag.Bool("l", false, "Listen for a connection on [host] port (or unix socket path with -U) instead of connecting")
+	var server serverOptions
+	flag.BoolVar(&server.keep, "k", false, "With -l, keep listening and serve several clients at once; input lines go to all of them")
+	flag.BoolVar(&server.chat, "chat", false, "With -l, relay lines of every client to all other clients (implies -k)")
+	flag.StringVar(&server.command, "e", "", "Run command with sh -c and wire its stdin/stdout to the connection")
 
-	connTimeout := time.Duration(*timeout) * time.Second
+	hexdump := flag.Bool("hexdump", false, "Show sent and received data as an annotated hex dump instead of raw output")
+	logFile := flag.String("log", "", "Write a timestamped transcript of the session to file")
+	scriptFile := flag.String("script", "", "Run an expect-style script (send/write/expect/timeout/sleep) from file, - for stdin")
+	flag.Parse()
 
-	conn, err := net.DialTimeout("tcp", address, connTimeout)
+	args := flag.Args()
+	switch {
+	case options.unix && len(args) == 1:
+		options.address = args[0]
+	case !options.unix && len(args) == 2:
+		options.address = net.JoinHostPort(args[0], args[1])
+	case !options.unix && *listen && len(args) == 1:
+		options.address = net.JoinHostPort("", args[0])
+	default:
+		fmt.Fprintf(os.Stderr, "Usage: %s [--timeout=10] [--raw] [-u] [-4|-6] [--tls [--sni name] [--insecure] [--ca file] [--cert file --key file]] [-e cmd] host port\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s -l [-k] [--chat] [-4|-6] [--tls --cert file --key file [--ca file]] [-e cmd] [host] port\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s -U [-l] [options] path\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Exit codes: 0 ok, 1 error, 2 connection failed, 3 timeout, 4 closed by remote host\n")
+		return exitError
+	}
+	err := options.validate()
+	if err == nil {
+		err = server.validate(*listen, &options)
+	}
+	if err == nil && *scriptFile != "" && (*listen || server.command != "") {
+		err = errors.New("--script can't be used with -l or -e")
+	}
+	if err == nil && client.reconnect && (*listen || server.command != "" || *scriptFile != "") {
+		err = errors.New("--reconnect can't be used with -l, -e or --script")
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Connection error: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
 	}
-	defer conn.Close()
+	options.timeout = time.Duration(*timeout) * time.Second
+	options.keepAlive.Enable = !*noKeepAlive
+	client.command = server.command
 
-	done := make(chan struct{})
-
-	// Горутина для чтения из сокета и вывода в STDOUT
-	go func() {
-		defer close(done)
-		reader := bufio.NewReader(conn)
-		buffer := make([]byte, 1024)
-
-		for {
-			n, err := reader.Read(buffer)
-			if err != nil {
-				if err != io.EOF {
-					// Вывод ошибок в STDERR, а не в STDOUT
-					fmt.Fprintf(os.Stderr, "Read error: %v\n", err)
-				}
-				return
-			}
-			if n > 0 {
-				os.Stdout.Write(buffer[:n])
-			}
+	if *scriptFile != "" {
+		if client.script, err = loadScript(*scriptFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Script error: %v\n", err)
+			return exitError
 		}
-	}()
-
-	// Горутина для чтения из STDIN и отправки в сокет
-	go func() {
-		reader := bufio.NewReader(os.Stdin)
-		writer := bufio.NewWriter(conn)
-
-		for {
-			line, err := reader.ReadString('\n')
-			if err != nil {
-				if err == io.EOF {
-					conn.Close()
-					return
-				}
-				fmt.Fprintf(os.Stderr, "StdIn read error: %v\n", err)
-				conn.Close()
-				return
-			}
+	}
 
-			_, err = writer.WriteString(line)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Write error: %v\n", err)
-				conn.Close()
-				return
-			}
-			err = writer.Flush()
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Flush error: %v\n", err)
-				conn.Close()
-				return
-			}
+	var traffic monitor
+	if *hexdump {
+		traffic.hexdump = os.Stdout
+	}
+	if *logFile != "" {
+		file, err := os.Create(*logFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitError
 		}
-	}()
+		defer file.Close()
+		traffic.log = file
+	}
 
-	// Обработка сигналов (Ctrl+C)
+	// Обработка сигналов (Ctrl+C): клиент и сервер завершаются одинаково
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
 
-	select {
-	case <-sigCh:
-		conn.Close()
-	case <-done:
+	if *listen {
+		if err := serve(&options, &server, &traffic, sigCh); err != nil {
+			fmt.Fprintf(os.Stderr, "Listen error: %v\n", err)
+			return exitError
+		}
+		return exitOK
 	}
+	return runClient(&options, &client, &traffic, sigCh)
 }
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Максимальная задержка перед повторным подключением (--reconnect)
+const maxReconnectDelay = 30 * time.Second
+
+// Параметры клиента
+type clientOptions struct {
+	raw            bool
+	command        string       // -e
+	script         []scriptStep // --script
+	reconnect      bool
+	reconnectDelay time.Duration
+	readTimeout    time.Duration
+	idleTimeout    time.Duration
+}
+
+// Чем закончился сеанс
+type sessionResult int
+
+const (
+	sessionDone         sessionResult = iota // ввод закончился, ответ сервера получен полностью
+	sessionInterrupted                       // получен сигнал
+	sessionRemoteClosed                      // сервер закрыл соединение раньше, чем закончился ввод
+	sessionTimeout                           // сработал --read-timeout или --idle-timeout
+	sessionFailed                            // ошибка чтения или записи
+)
+
+func (r sessionResult) exitCode() int {
+	switch r {
+	case sessionRemoteClosed:
+		return exitRemoteClosed
+	case sessionTimeout:
+		return exitTimeout
+	case sessionFailed:
+		return exitError
+	}
+	return exitOK
+}
+
+// runClient подключается и ведет сеанс; с --reconnect при неудачном подключении или потере
+// соединения подключается снова, удваивая задержку. Задержка сбрасывается, если соединение
+// продержалось дольше maxReconnectDelay. Возвращает код завершения.
+func runClient(dial *dialOptions, options *clientOptions, traffic *monitor, sigCh <-chan os.Signal) int {
+	// STDIN читается одной горутиной на все подключения, чтобы при переподключении ввод не терялся
+	var input *sessionInput
+	if options.command == "" && options.script == nil {
+		input = readInput(os.Stdin)
+	}
+
+	delay := options.reconnectDelay
+	for {
+		connected := time.Now()
+		result, code := connectAndRun(dial, options, traffic, input, sigCh)
+		if !options.reconnect || result == sessionDone || result == sessionInterrupted {
+			return code
+		}
+
+		if time.Since(connected) > maxReconnectDelay {
+			delay = options.reconnectDelay
+		}
+		fmt.Fprintf(os.Stderr, "Reconnecting in %v\n", delay)
+		select {
+		case <-time.After(delay):
+		case <-sigCh:
+			return exitOK
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// connectAndRun выполняет одно подключение: команду -e, сценарий или интерактивный сеанс.
+// Неудачное подключение считается потерей соединения (для --reconnect).
+func connectAndRun(dial *dialOptions, options *clientOptions, traffic *monitor, input *sessionInput, sigCh <-chan os.Signal) (sessionResult, int) {
+	conn, err := dialWithTimeout(dial)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Connection error: %v\n", err)
+		return sessionFailed, exitConnectFailed
+	}
+	if description := describeTLS(conn); description != "" {
+		fmt.Fprintf(os.Stderr, "TLS: %s\n", description)
+	}
+	conn = traffic.wrap(conn, "")
+	defer conn.Close()
+
+	if options.command != "" {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-sigCh:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		if err := runCommand(ctx, conn, options.command); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "Command error: %v\n", err)
+			return sessionFailed, exitError
+		}
+		return sessionDone, exitOK
+	}
+
+	// В режиме telnet сервер может взять эхо на себя (например, при вводе пароля):
+	// тогда локальное эхо терминала выключается до конца сеанса или отказа сервера
+	var echoDisabled atomic.Bool
+	var stream io.ReadWriter = conn
+	var telnet *telnetConn
+	// Для датаграмм согласование опций telnet не имеет смысла
+	if !options.raw && !dial.udp {
+		telnet = newTelnetConn(conn, func(remote bool) {
+			if setEcho(int(os.Stdin.Fd()), !remote) == nil {
+				echoDisabled.Store(remote)
+			}
+		})
+		stream = telnet
+	}
+	defer func() {
+		if echoDisabled.Load() {
+			setEcho(int(os.Stdin.Fd()), true)
+		}
+	}()
+
+	if options.script != nil {
+		err := runScript(stream, options.script, traffic.output(), dial.timeout, sigCh)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Script error: %v\n", err)
+		}
+		switch {
+		case err == nil:
+			return sessionDone, exitOK
+		case errors.Is(err, errTimeout):
+			return sessionTimeout, exitTimeout
+		case errors.Is(err, errRemoteClosed):
+			return sessionRemoteClosed, exitRemoteClosed
+		}
+		return sessionFailed, exitError
+	}
+
+	// Датаграмма читается и отправляется целиком, поэтому буферы - под максимальный размер UDP
+	bufferSize := 4096
+	if dial.udp {
+		bufferSize = 65536
+	}
+	timeouts := &sessionTimeouts{read: options.readTimeout, idle: options.idleTimeout}
+	result := interact(conn, stream, traffic.output(), bufferSize, input, sigCh, telnet, timeouts)
+	return result, result.exitCode()
+}
+
+// dialWithTimeout подключается; ошибку таймаута подключения поясняет.
+func dialWithTimeout(options *dialOptions) (net.Conn, error) {
+	conn, err := dial(options)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return nil, fmt.Errorf("no connection after %v: %w", options.timeout, err)
+	}
+	return conn, err
+}
+
+// Строки ввода, общие для всех сеансов. Строка, которую не удалось отправить
+// в оборвавшемся сеансе, возвращается и отправляется первой в следующем (--reconnect)
+type sessionInput struct {
+	lines  <-chan string // закрывается в конце ввода
+	unsent chan string   // не больше одной строки: сеанс отправляет строки по одной
+}
+
+func newSessionInput(lines <-chan string) *sessionInput {
+	return &sessionInput{lines: lines, unsent: make(chan string, 1)}
+}
+
+// next возвращает следующую строку, начиная с возвращенной. end - ввод закончился,
+// stopped - сеанс завершился (закрыт done) раньше, чем появилась строка.
+func (in *sessionInput) next(done <-chan struct{}) (line string, end, stopped bool) {
+	select {
+	case line = <-in.unsent:
+		return line, false, false
+	default:
+	}
+	select {
+	case line, ok := <-in.lines:
+		return line, !ok, false
+	case <-done:
+		return "", false, true
+	}
+}
+
+// unread возвращает неотправленную строку для следующего сеанса.
+func (in *sessionInput) unread(line string) {
+	in.unsent <- line
+}
+
+// readInput читает строки r в фоне; ввод заканчивается вместе с r.
+func readInput(r io.Reader) *sessionInput {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadString('\n')
+			if len(line) > 0 {
+				lines <- line
+			}
+			if err != nil {
+				if err != io.EOF {
+					fmt.Fprintf(os.Stderr, "StdIn read error: %v\n", err)
+				}
+				return
+			}
+		}
+	}()
+	return newSessionInput(lines)
+}
+
+// Таймауты сеанса: read - ничего не получено от сервера, idle - нет данных ни в одну сторону.
+// Реализованы дедлайном чтения, который сдвигается при получении и отправке данных
+type sessionTimeouts struct {
+	read time.Duration
+	idle time.Duration
+
+	mu           sync.Mutex
+	conn         net.Conn
+	lastRead     time.Time
+	lastActivity time.Time
+}
+
+// start запускает отсчет таймаутов для соединения.
+func (t *sessionTimeouts) start(conn net.Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.conn = conn
+	t.lastRead = time.Now()
+	t.lastActivity = t.lastRead
+	t.arm()
+}
+
+func (t *sessionTimeouts) received() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastRead = time.Now()
+	t.lastActivity = t.lastRead
+	t.arm()
+}
+
+func (t *sessionTimeouts) sent() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastActivity = time.Now()
+	t.arm()
+}
+
+// arm ставит дедлайн чтения на ближайший из таймаутов; вызывается под t.mu.
+func (t *sessionTimeouts) arm() {
+	var deadline time.Time
+	if t.read > 0 {
+		deadline = t.lastRead.Add(t.read)
+	}
+	if t.idle > 0 {
+		if idle := t.lastActivity.Add(t.idle); deadline.IsZero() || idle.Before(deadline) {
+			deadline = idle
+		}
+	}
+	if !deadline.IsZero() {
+		t.conn.SetReadDeadline(deadline)
+	}
+}
+
+// describe поясняет, какой из таймаутов сработал.
+func (t *sessionTimeouts) describe() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.read > 0 && time.Since(t.lastRead) >= t.read {
+		return fmt.Sprintf("nothing received for %v", t.read)
+	}
+	return fmt.Sprintf("no data in either direction for %v", t.idle)
+}
+
+// halfClose закрывает передачу (TCP FIN), оставляя прием открытым, чтобы дочитать ответ
+// сервера. Соединения без полузакрытия (UDP) закрываются полностью.
+func halfClose(conn net.Conn) {
+	if writer, ok := conn.(interface{ CloseWrite() error }); ok && writer.CloseWrite() == nil {
+		return
+	}
+	conn.Close()
+}
+
+// interact связывает ввод и output с соединением. В конце ввода передача закрывается,
+// а ответ сервера дочитывается до закрытия соединения сервером. Сеанс прерывается
+// сигналом из stop (nil - сигналы обрабатывает вызывающий) и таймаутами.
+// Для telnet-соединения изменения размера окна передаются серверу.
+func interact(conn net.Conn, stream io.ReadWriter, output io.Writer, bufferSize int, input *sessionInput, stop <-chan os.Signal, telnet *telnetConn, timeouts *sessionTimeouts) sessionResult {
+	done := make(chan struct{})
+	written := make(chan struct{})
+	var inputDone, writeFailed, interrupted atomic.Bool
+	result := sessionDone
+	timeouts.start(conn)
+
+	// Горутина для чтения из сокета и вывода в output
+	go func() {
+		defer close(done)
+		reader := bufio.NewReaderSize(stream, bufferSize)
+		buffer := make([]byte, 1024)
+
+		for {
+			n, err := reader.Read(buffer)
+			if n > 0 {
+				timeouts.received()
+				output.Write(buffer[:n])
+			}
+			if err == nil {
+				continue
+			}
+
+			switch {
+			case interrupted.Load():
+				result = sessionInterrupted
+			case errors.Is(err, os.ErrDeadlineExceeded):
+				fmt.Fprintf(os.Stderr, "Timeout: %s\n", timeouts.describe())
+				result = sessionTimeout
+			case writeFailed.Load():
+				result = sessionFailed
+			case inputDone.Load() && (err == io.EOF || errors.Is(err, net.ErrClosed)):
+				result = sessionDone
+			case err == io.EOF:
+				fmt.Fprintln(os.Stderr, "Connection closed by remote host")
+				result = sessionRemoteClosed
+			default:
+				// Вывод ошибок в STDERR, а не в STDOUT
+				fmt.Fprintf(os.Stderr, "Read error: %v\n", err)
+				result = sessionRemoteClosed
+			}
+			return
+		}
+	}()
+
+	// Горутина для чтения ввода и отправки в сокет. Строка, взятая после конца сеанса
+	// или не записанная из-за ошибки, возвращается во ввод для следующего подключения
+	go func() {
+		defer close(written)
+		writer := bufio.NewWriterSize(stream, bufferSize)
+
+		for {
+			line, end, stopped := input.next(done)
+			if stopped {
+				return
+			}
+			if end {
+				inputDone.Store(true)
+				halfClose(conn)
+				return
+			}
+			select {
+			case <-done:
+				input.unread(line)
+				return
+			default:
+			}
+
+			_, err := writer.WriteString(line)
+			if err == nil {
+				err = writer.Flush()
+			}
+			if err != nil {
+				input.unread(line)
+				// После конца сеанса или прерывания ошибка записи ожидаема и не выводится
+				finished := interrupted.Load()
+				select {
+				case <-done:
+					finished = true
+				default:
+				}
+				if !finished {
+					fmt.Fprintf(os.Stderr, "Write error: %v\n", err)
+					writeFailed.Store(true)
+					conn.Close()
+				}
+				return
+			}
+			timeouts.sent()
+		}
+	}()
+
+	// Изменение размера окна передается серверу (NAWS)
+	resizeCh := make(chan os.Signal, 1)
+	if telnet != nil {
+		notifyResize(resizeCh)
+		defer signal.Stop(resizeCh)
+	}
+
+	for {
+		select {
+		case <-resizeCh:
+			telnet.WindowChanged()
+		case <-stop:
+			interrupted.Store(true)
+			conn.Close()
+			<-done
+			<-written
+			return sessionInterrupted
+		case <-done:
+			// Запись могла зависнуть на оборванном соединении: закрытие ее прерывает.
+			// Возвращенная строка должна попасть во ввод до начала следующего сеанса
+			conn.Close()
+			<-written
+			return result
+		}
+	}
+}
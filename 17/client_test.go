@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestInteractExitCodes(t *testing.T) {
+	tests := []struct {
+		name      string
+		serve     func(conn net.Conn)
+		read      time.Duration
+		idle      time.Duration
+		interrupt bool
+		result    sessionResult
+		code      int
+		output    string
+	}{
+		{
+			name: "remote closed",
+			serve: func(conn net.Conn) {
+				conn.Write([]byte("bye\n"))
+				conn.Close()
+			},
+			result: sessionRemoteClosed,
+			code:   exitRemoteClosed,
+			output: "bye\n",
+		},
+		{
+			name:   "read timeout",
+			serve:  func(conn net.Conn) {},
+			read:   50 * time.Millisecond,
+			result: sessionTimeout,
+			code:   exitTimeout,
+		},
+		{
+			name: "idle timeout after data",
+			serve: func(conn net.Conn) {
+				conn.Write([]byte("hello"))
+			},
+			idle:   50 * time.Millisecond,
+			result: sessionTimeout,
+			code:   exitTimeout,
+			output: "hello",
+		},
+		{
+			name:      "interrupted",
+			serve:     func(conn net.Conn) {},
+			interrupt: true,
+			result:    sessionInterrupted,
+			code:      exitOK,
+		},
+	}
+
+	for _, test := range tests {
+		client, server := net.Pipe()
+		go test.serve(server)
+
+		stop := make(chan os.Signal, 1)
+		if test.interrupt {
+			stop <- syscall.SIGINT
+		}
+		var output bytes.Buffer
+		// Ввод не заканчивается: сеанс завершает сервер, таймаут или сигнал
+		timeouts := &sessionTimeouts{read: test.read, idle: test.idle}
+		result := interact(client, client, &output, 4096, newSessionInput(make(chan string)), stop, nil, timeouts)
+		server.Close()
+		client.Close()
+
+		if result != test.result || result.exitCode() != test.code {
+			t.Errorf("%s: result = %d (exit code %d), expected %d (exit code %d)", test.name, result, result.exitCode(), test.result, test.code)
+		}
+		if output.String() != test.output {
+			t.Errorf("%s: output = %q, expected %q", test.name, output.String(), test.output)
+		}
+	}
+}
+
+func TestInteractHalfClose(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// Сервер отвечает только после конца ввода клиента
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		data, _ := io.ReadAll(conn)
+		conn.Write(append([]byte("got "), data...))
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	input := make(chan string, 2)
+	input <- "hello\n"
+	close(input)
+	var output bytes.Buffer
+	result := interact(conn, conn, &output, 4096, newSessionInput(input), nil, nil, &sessionTimeouts{read: 5 * time.Second})
+
+	if result != sessionDone || result.exitCode() != exitOK {
+		t.Errorf("result = %d (exit code %d), expected done", result, result.exitCode())
+	}
+	if output.String() != "got hello\n" {
+		t.Errorf("output = %q, expected reply read after half-close", output.String())
+	}
+}
+
+func TestInteractKeepsUnsentLine(t *testing.T) {
+	lines := make(chan string)
+	input := newSessionInput(lines)
+	go func() {
+		lines <- "first\n"
+		lines <- "second\n"
+		close(lines)
+	}()
+
+	// Первый сервер закрывает соединение, ничего не прочитав: строка, которую
+	// сеанс успел взять, не должна потеряться
+	client, server := net.Pipe()
+	server.Close()
+	var output bytes.Buffer
+	interact(client, client, &output, 4096, input, nil, nil, &sessionTimeouts{})
+	client.Close()
+
+	// Следующий сеанс (как при --reconnect) отправляет весь ввод по порядку;
+	// для полузакрытия нужно TCP-соединение
+	conn, remote := tcpPair(t)
+	received := make(chan string, 1)
+	go func() {
+		data, _ := io.ReadAll(remote)
+		received <- string(data)
+		remote.Close()
+	}()
+	result := interact(conn, conn, &output, 4096, input, nil, nil, &sessionTimeouts{read: 5 * time.Second})
+
+	if result != sessionDone {
+		t.Errorf("result = %d, expected done", result)
+	}
+	if data := <-received; data != "first\nsecond\n" {
+		t.Errorf("second session sent %q, expected all input lines", data)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Направление данных в дампе и журнале сеанса
+const (
+	dirSent     = '>'
+	dirReceived = '<'
+)
+
+// Наблюдатель трафика: шестнадцатеричный дамп (--hexdump) и журнал сеанса с отметками
+// времени (--log). Общий для всех соединений, чтобы записи не перемешивались.
+type monitor struct {
+	mu      sync.Mutex
+	hexdump io.Writer // nil - без дампа
+	log     io.Writer // nil - без журнала
+}
+
+// active сообщает, нужно ли оборачивать соединения.
+func (m *monitor) active() bool {
+	return m.hexdump != nil || m.log != nil
+}
+
+// output возвращает, куда выводить полученные данные: с дампом они выводятся только в нем.
+func (m *monitor) output() io.Writer {
+	if m.hexdump != nil {
+		return io.Discard
+	}
+	return os.Stdout
+}
+
+// wrap возвращает соединение, данные которого попадают в дамп и журнал.
+// label подписывает строки дампа (адрес клиента в режиме сервера).
+func (m *monitor) wrap(conn net.Conn, label string) net.Conn {
+	if !m.active() {
+		return conn
+	}
+	c := &monitoredConn{Conn: conn, monitor: m, label: label, peer: conn.RemoteAddr().String()}
+	m.event(c.peer, "connected")
+	return c
+}
+
+// event записывает в журнал событие соединения.
+func (m *monitor) event(peer, message string) {
+	if m.log == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fmt.Fprintf(m.log, "%s * %s %s\n", timestamp(), peer, message)
+}
+
+// record выводит переданные или полученные данные.
+func (m *monitor) record(c *monitoredConn, direction byte, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.log != nil {
+		fmt.Fprintf(m.log, "%s %c %s %s\n", timestamp(), direction, c.peer, strconv.Quote(string(data)))
+	}
+	if m.hexdump != nil {
+		offset := &c.received
+		if direction == dirSent {
+			offset = &c.sent
+		}
+		writeHexdump(m.hexdump, direction, c.label, *offset, data)
+		*offset += int64(len(data))
+	}
+}
+
+// timestamp возвращает время записи журнала с миллисекундами.
+func timestamp() string {
+	return time.Now().Format("2006-01-02T15:04:05.000Z07:00")
+}
+
+// writeHexdump выводит данные в формате hexdump -C: направление, подпись,
+// смещение от начала потока этого направления, 16 байтов и их символы.
+func writeHexdump(w io.Writer, direction byte, label string, offset int64, data []byte) {
+	prefix := string(direction)
+	if label != "" {
+		prefix += " " + label
+	}
+
+	var line strings.Builder
+	for start := 0; start < len(data); start += 16 {
+		chunk := data[start:min(start+16, len(data))]
+
+		line.Reset()
+		fmt.Fprintf(&line, "%s %08x  ", prefix, offset+int64(start))
+		for i := range 16 {
+			if i < len(chunk) {
+				fmt.Fprintf(&line, "%02x ", chunk[i])
+			} else {
+				line.WriteString("   ")
+			}
+			if i == 7 {
+				line.WriteByte(' ')
+			}
+		}
+
+		line.WriteString(" |")
+		for _, b := range chunk {
+			if b < 32 || b > 126 {
+				b = '.'
+			}
+			line.WriteByte(b)
+		}
+		line.WriteString("|\n")
+		io.WriteString(w, line.String())
+	}
+}
+
+// Соединение, передающее весь трафик наблюдателю
+type monitoredConn struct {
+	net.Conn
+	monitor *monitor
+	label   string
+	peer    string
+
+	// Смещения для дампа (под monitor.mu)
+	sent     int64
+	received int64
+
+	closeOnce sync.Once
+}
+
+func (c *monitoredConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.monitor.record(c, dirReceived, p[:n])
+	}
+	return n, err
+}
+
+func (c *monitoredConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	if n > 0 {
+		c.monitor.record(c, dirSent, p[:n])
+	}
+	return n, err
+}
+
+// CloseWrite передает полузакрытие нижележащему соединению (TCP, unix, TLS).
+func (c *monitoredConn) CloseWrite() error {
+	writer, ok := c.Conn.(interface{ CloseWrite() error })
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	c.monitor.event(c.peer, "half-closed")
+	return writer.CloseWrite()
+}
+
+func (c *monitoredConn) Close() error {
+	c.closeOnce.Do(func() { c.monitor.event(c.peer, "closed") })
+	return c.Conn.Close()
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ошибки expect, по которым выбирается код завершения
+var (
+	errTimeout      = errors.New("timeout")
+	errRemoteClosed = errors.New("connection closed by remote host")
+)
+
+// Шаг сценария (--script). Команды:
+//
+//	send TEXT       отправить TEXT и CR LF
+//	write TEXT      отправить TEXT без конца строки
+//	expect TEXT     ждать, пока в полученных данных появится TEXT
+//	expect /REGEX/  ждать совпадения с регулярным выражением (многострочный режим: ^ и $ - границы
+//	                строк, CR LF считается концом строки)
+//	timeout 5s      время ожидания для следующих expect
+//	sleep 500ms     пауза
+//
+// TEXT - остаток строки или строка в кавычках с экранированием Go ("QUIT\r\n").
+// Совпадение expect и все полученное до него при следующих expect не учитываются.
+type scriptStep struct {
+	line     int
+	command  string
+	text     string
+	pattern  *regexp.Regexp
+	duration time.Duration
+}
+
+// loadScript читает сценарий из файла ("-" - из STDIN).
+func loadScript(filename string) ([]scriptStep, error) {
+	if filename == "-" {
+		return parseScript(os.Stdin)
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseScript(file)
+}
+
+func parseScript(r io.Reader) ([]scriptStep, error) {
+	var steps []scriptStep
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		command, argument, _ := strings.Cut(line, " ")
+		argument = strings.TrimSpace(argument)
+		step := scriptStep{line: number, command: command}
+
+		var err error
+		switch command {
+		case "send", "write":
+			step.text, err = parseText(argument)
+			if command == "send" {
+				step.text += "\r\n"
+			}
+		case "expect":
+			if len(argument) >= 2 && strings.HasPrefix(argument, "/") && strings.HasSuffix(argument, "/") {
+				step.pattern, err = regexp.Compile("(?m)" + argument[1:len(argument)-1])
+				break
+			}
+			step.text, err = parseText(argument)
+			if err == nil && step.text == "" {
+				err = errors.New("expect needs text or /regex/")
+			}
+		case "timeout", "sleep":
+			step.duration, err = time.ParseDuration(argument)
+		default:
+			err = fmt.Errorf("unknown command %q", command)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", number, err)
+		}
+		steps = append(steps, step)
+	}
+	return steps, scanner.Err()
+}
+
+// parseText разбирает аргумент: строку в кавычках с экранированием или текст как есть.
+func parseText(argument string) (string, error) {
+	if strings.HasPrefix(argument, `"`) {
+		return strconv.Unquote(argument)
+	}
+	return argument, nil
+}
+
+// describe возвращает ожидаемое значение шага для сообщений об ошибках.
+func (s *scriptStep) describe() string {
+	if s.pattern != nil {
+		return "/" + strings.TrimPrefix(s.pattern.String(), "(?m)") + "/"
+	}
+	return strconv.Quote(s.text)
+}
+
+// Выполнение сценария: полученные данные копятся в буфере, из которого expect
+// удаляет все до конца совпадения
+type scriptRunner struct {
+	stream   io.Writer
+	received <-chan []byte // закрывается, когда соединение закрыто
+	stop     <-chan os.Signal
+	timeout  time.Duration
+	buffer   []byte
+}
+
+// runScript выполняет шаги сценария; полученные данные выводятся в output.
+func runScript(stream io.ReadWriter, steps []scriptStep, output io.Writer, timeout time.Duration, stop <-chan os.Signal) error {
+	received := make(chan []byte)
+	go func() {
+		defer close(received)
+		buffer := make([]byte, 4096)
+		for {
+			n, err := stream.Read(buffer)
+			if n > 0 {
+				output.Write(buffer[:n])
+				received <- append([]byte(nil), buffer[:n]...)
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	runner := &scriptRunner{stream: stream, received: received, stop: stop, timeout: timeout}
+	for _, step := range steps {
+		if err := runner.run(&step); err != nil {
+			return fmt.Errorf("line %d: %w", step.line, err)
+		}
+	}
+	return nil
+}
+
+func (r *scriptRunner) run(step *scriptStep) error {
+	switch step.command {
+	case "send", "write":
+		_, err := io.WriteString(r.stream, step.text)
+		return err
+	case "timeout":
+		r.timeout = step.duration
+		return nil
+	case "sleep":
+		select {
+		case <-time.After(step.duration):
+			return nil
+		case <-r.stop:
+			return errors.New("interrupted")
+		}
+	}
+	return r.expect(step)
+}
+
+// expect ждет совпадения в полученных данных не дольше r.timeout.
+func (r *scriptRunner) expect(step *scriptStep) error {
+	timer := time.NewTimer(r.timeout)
+	defer timer.Stop()
+
+	for {
+		if end := r.match(step); end >= 0 {
+			r.buffer = r.buffer[end:]
+			return nil
+		}
+
+		select {
+		case data, ok := <-r.received:
+			if !ok {
+				return fmt.Errorf("%w while waiting for %s; received %s", errRemoteClosed, step.describe(), r.tail())
+			}
+			r.buffer = append(r.buffer, data...)
+		case <-timer.C:
+			return fmt.Errorf("%w after %v waiting for %s; received %s", errTimeout, r.timeout, step.describe(), r.tail())
+		case <-r.stop:
+			return errors.New("interrupted")
+		}
+	}
+}
+
+// match возвращает конец совпадения в буфере или -1.
+func (r *scriptRunner) match(step *scriptStep) int {
+	if step.pattern != nil {
+		text, positions := normalizeNewlines(r.buffer)
+		if location := step.pattern.FindIndex(text); location != nil {
+			return positions[location[1]]
+		}
+		return -1
+	}
+	if index := strings.Index(string(r.buffer), step.text); index >= 0 {
+		return index + len(step.text)
+	}
+	return -1
+}
+
+// normalizeNewlines заменяет CR LF на LF, чтобы $ совпадал с концом строки в протоколах
+// вроде SMTP и Redis. positions[i] - смещение в data, соответствующее i-му байту результата
+// (последний элемент - len(data)).
+func normalizeNewlines(data []byte) ([]byte, []int) {
+	text := make([]byte, 0, len(data))
+	positions := make([]int, 0, len(data)+1)
+	for i := 0; i < len(data); i++ {
+		positions = append(positions, i)
+		if data[i] == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+			i++
+		}
+		text = append(text, data[i])
+	}
+	return text, append(positions, len(data))
+}
+
+// tail возвращает конец буфера для сообщения об ошибке.
+func (r *scriptRunner) tail() string {
+	const limit = 200
+	if len(r.buffer) > limit {
+		return "..." + strconv.Quote(string(r.buffer[len(r.buffer)-limit:]))
+	}
+	return strconv.Quote(string(r.buffer))
+}
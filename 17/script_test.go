@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseScript(t *testing.T) {
+	script := `# SMTP
+expect /^220 /
+send EHLO example.com
+write "DATA\r\n"
+timeout 2s
+
+expect 250 OK
+sleep 10ms
+`
+	steps, err := parseScript(strings.NewReader(script))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []scriptStep{
+		{line: 2, command: "expect"},
+		{line: 3, command: "send", text: "EHLO example.com\r\n"},
+		{line: 4, command: "write", text: "DATA\r\n"},
+		{line: 5, command: "timeout", duration: 2 * time.Second},
+		{line: 7, command: "expect", text: "250 OK"},
+		{line: 8, command: "sleep", duration: 10 * time.Millisecond},
+	}
+	if len(steps) != len(expected) {
+		t.Fatalf("%d steps, expected %d", len(steps), len(expected))
+	}
+	for i, step := range steps {
+		want := expected[i]
+		if step.line != want.line || step.command != want.command || step.text != want.text || step.duration != want.duration {
+			t.Errorf("step %d = %+v, expected %+v", i, step, want)
+		}
+	}
+	if steps[0].pattern == nil || steps[0].describe() != "/^220 /" {
+		t.Errorf("expect /^220 / parsed as %q", steps[0].describe())
+	}
+}
+
+func TestParseScriptErrors(t *testing.T) {
+	tests := []struct {
+		script  string
+		message string
+	}{
+		{"jump 3", `line 1: unknown command "jump"`},
+		{"\nexpect", "line 2: expect needs text or /regex/"},
+		{"expect /(/", "line 1: error parsing regexp"},
+		{`send "unterminated`, "line 1: invalid syntax"},
+		{"timeout soon", `line 1: time: invalid duration`},
+	}
+
+	for _, test := range tests {
+		_, err := parseScript(strings.NewReader(test.script))
+		if err == nil || !strings.Contains(err.Error(), test.message) {
+			t.Errorf("parseScript(%q) error = %v, expected %q", test.script, err, test.message)
+		}
+	}
+}
+
+func TestNormalizeNewlines(t *testing.T) {
+	tests := []struct {
+		data      string
+		text      string
+		positions []int
+	}{
+		{"", "", []int{0}},
+		{"ab", "ab", []int{0, 1, 2}},
+		{"a\r\nb", "a\nb", []int{0, 1, 3, 4}},
+		{"a\rb\r", "a\rb\r", []int{0, 1, 2, 3, 4}},
+		{"\r\n\r\n", "\n\n", []int{0, 2, 4}},
+	}
+
+	for _, test := range tests {
+		text, positions := normalizeNewlines([]byte(test.data))
+		if string(text) != test.text || !slices.Equal(positions, test.positions) {
+			t.Errorf("normalizeNewlines(%q) = %q, %v, expected %q, %v", test.data, text, positions, test.text, test.positions)
+		}
+	}
+}
+
+func TestScriptMatch(t *testing.T) {
+	tests := []struct {
+		buffer string
+		expect string
+		end    int
+	}{
+		{"220 ready\r\n", "ready", 9},
+		{"220 ready\r\n", "250", -1},
+		{"220-hello\r\n220 ready\r\n", "/^220 .*$/", 20},
+		{"220-hello\r\n", "/^220 /", -1},
+		{"+PONG\r\n", `/^\+PONG$/`, 5},
+		{"a\r\nb\r\n", "/b\\n/", 6},
+	}
+
+	for _, test := range tests {
+		steps, err := parseScript(strings.NewReader("expect " + test.expect))
+		if err != nil {
+			t.Fatal(err)
+		}
+		runner := &scriptRunner{buffer: []byte(test.buffer)}
+		if end := runner.match(&steps[0]); end != test.end {
+			t.Errorf("match(%q, %s) = %d, expected %d", test.buffer, test.expect, end, test.end)
+		}
+	}
+}
+
+func TestScriptExpect(t *testing.T) {
+	steps, err := parseScript(strings.NewReader("expect /^250 /\nexpect 354"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Совпадение и все до него удаляются из буфера
+	received := make(chan []byte, 2)
+	received <- []byte("250-first\r\n250 ok\r\n3")
+	received <- []byte("54 go\r\n")
+	runner := &scriptRunner{received: received, timeout: time.Second}
+	for _, step := range steps {
+		if err := runner.expect(&step); err != nil {
+			t.Fatalf("expect %s: %v", step.describe(), err)
+		}
+	}
+	if string(runner.buffer) != " go\r\n" {
+		t.Errorf("buffer after expect = %q", runner.buffer)
+	}
+
+	runner = &scriptRunner{received: make(chan []byte), timeout: 10 * time.Millisecond}
+	if err := runner.expect(&steps[0]); !errors.Is(err, errTimeout) {
+		t.Errorf("expect without data = %v, expected timeout", err)
+	}
+
+	closed := make(chan []byte)
+	close(closed)
+	runner = &scriptRunner{received: closed, timeout: time.Second}
+	if err := runner.expect(&steps[0]); !errors.Is(err, errRemoteClosed) {
+		t.Errorf("expect on closed connection = %v, expected remote closed", err)
+	}
+}
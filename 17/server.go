@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Параметры режима сервера (-l)
+type serverOptions struct {
+	keep    bool   // -k: принимать новых клиентов, не дожидаясь отключения предыдущих
+	chat    bool   // --chat: пересылать строки каждого клиента остальным
+	command string // -e: команда, подключаемая к каждому соединению
+}
+
+// validate проверяет сочетания флагов режима сервера.
+func (o *serverOptions) validate(listen bool, dial *dialOptions) error {
+	switch {
+	case !listen && (o.keep || o.chat):
+		return errors.New("-k and --chat require -l")
+	case !listen:
+		return nil
+	case dial.udp:
+		return errors.New("-l is not supported in UDP mode")
+	case dial.serverName != "" || dial.insecure:
+		return errors.New("--sni and --insecure can't be used with -l")
+	case dial.tls && dial.certFile == "":
+		return errors.New("--tls with -l requires --cert and --key")
+	case o.chat && o.command != "":
+		return errors.New("--chat cannot be combined with -e")
+	}
+	return nil
+}
+
+// Сервер: принимает соединения и связывает их с STDIN/STDOUT или с командой -e
+type server struct {
+	options  *serverOptions
+	traffic  *monitor
+	listener net.Listener
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	mu       sync.Mutex
+	clients  map[net.Conn]bool
+	wg       sync.WaitGroup
+	outputMu sync.Mutex // строки разных клиентов не перемешиваются в выводе
+}
+
+// serve слушает адрес и обслуживает клиентов до сигнала, а без -k - до конца первого соединения.
+// По сигналу новые соединения не принимаются, открытые закрываются, команды -e получают SIGINT.
+func serve(dial *dialOptions, options *serverOptions, traffic *monitor, sigCh <-chan os.Signal) error {
+	listener, err := listen(dial)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Listening on %s\n", listener.Addr())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &server{
+		options:  options,
+		traffic:  traffic,
+		listener: listener,
+		ctx:      ctx,
+		cancel:   cancel,
+		clients:  make(map[net.Conn]bool),
+	}
+	defer s.shutdown()
+	go func() {
+		select {
+		case <-sigCh:
+			fmt.Fprintln(os.Stderr, "Shutting down")
+			s.shutdown()
+		case <-ctx.Done():
+		}
+	}()
+
+	if !options.keep && !options.chat {
+		return s.serveOne()
+	}
+
+	// Ввод рассылается всем клиентам; с -e ввод не используется
+	if options.command == "" {
+		go s.broadcastInput()
+	}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return err
+		}
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+	s.wg.Wait()
+	return nil
+}
+
+// listen открывает слушающий сокет; с --tls клиенты с --ca обязаны предъявить сертификат.
+func listen(o *dialOptions) (net.Listener, error) {
+	network := o.network()
+	listener, err := o.listenConfig().Listen(context.Background(), network, o.address)
+	if err != nil || !o.tls {
+		return listener, err
+	}
+
+	certificate, err := tls.LoadX509KeyPair(o.certFile, o.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{certificate}}
+	if o.caFile != "" {
+		if config.ClientCAs, err = loadCertPool(o.caFile); err != nil {
+			listener.Close()
+			return nil, err
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tls.NewListener(listener, config), nil
+}
+
+// serveOne принимает одно соединение и закрывает слушающий сокет, как nc -l.
+func (s *server) serveOne() error {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		if s.ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	s.listener.Close()
+	conn = s.traffic.wrap(conn, "")
+	if s.options.command != "" {
+		if !s.track(conn) {
+			return nil
+		}
+		defer s.untrack(conn)
+		fmt.Fprintf(os.Stderr, "Connection from %s\n", conn.RemoteAddr())
+		return s.run(conn)
+	}
+
+	// Соединение не регистрируется в clients: при остановке его закрывает сам interact,
+	// получив прерывание, и сеанс завершается без сообщения об ошибке чтения
+	defer conn.Close()
+	fmt.Fprintf(os.Stderr, "Connection from %s\n", conn.RemoteAddr())
+	stop := make(chan os.Signal, 1)
+	go func() {
+		<-s.ctx.Done()
+		stop <- os.Interrupt
+	}()
+	interact(conn, conn, s.traffic.output(), 4096, readInput(os.Stdin), stop, nil, &sessionTimeouts{})
+	return nil
+}
+
+// handle обслуживает клиента в режиме -k: выводит его строки в STDOUT,
+// а в режиме --chat пересылает их остальным клиентам.
+func (s *server) handle(conn net.Conn) {
+	defer s.wg.Done()
+	conn = s.traffic.wrap(conn, conn.RemoteAddr().String())
+	if !s.track(conn) {
+		return
+	}
+	defer s.untrack(conn)
+
+	client := conn.RemoteAddr().String()
+	fmt.Fprintf(os.Stderr, "Connection from %s\n", client)
+	defer fmt.Fprintf(os.Stderr, "Connection closed: %s\n", client)
+
+	if s.options.command != "" {
+		if err := s.run(conn); err != nil {
+			fmt.Fprintf(os.Stderr, "Command error (%s): %v\n", client, err)
+		}
+		return
+	}
+
+	if s.options.chat {
+		s.send(conn, fmt.Sprintf("* %s joined\n", client))
+		defer s.send(conn, fmt.Sprintf("* %s left\n", client))
+	}
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			if err != nil {
+				line += "\n"
+			}
+			if s.options.chat {
+				line = fmt.Sprintf("[%s] %s", client, line)
+				s.send(conn, line)
+			}
+			s.outputMu.Lock()
+			io.WriteString(s.traffic.output(), line)
+			s.outputMu.Unlock()
+		}
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				fmt.Fprintf(os.Stderr, "Read error (%s): %v\n", client, err)
+			}
+			return
+		}
+	}
+}
+
+// broadcastInput рассылает строки STDIN всем подключенным клиентам.
+// После конца ввода сервер продолжает работать.
+func (s *server) broadcastInput() {
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			s.send(nil, line)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// send отправляет строку всем клиентам, кроме from. Клиент, запись которому
+// не удалась, отключается.
+func (s *server) send(from net.Conn, line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.clients {
+		if conn == from {
+			continue
+		}
+		conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if _, err := io.WriteString(conn, line); err != nil {
+			conn.Close()
+		}
+	}
+}
+
+// track регистрирует соединение; после начала остановки оно сразу закрывается.
+func (s *server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx.Err() != nil {
+		conn.Close()
+		return false
+	}
+	s.clients[conn] = true
+	return true
+}
+
+func (s *server) untrack(conn net.Conn) {
+	s.mu.Lock()
+	delete(s.clients, conn)
+	s.mu.Unlock()
+	conn.Close()
+}
+
+// shutdown прекращает прием соединений и закрывает открытые.
+func (s *server) shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cancel()
+	s.listener.Close()
+	for conn := range s.clients {
+		conn.Close()
+	}
+}
+
+// run запускает команду -e для соединения.
+func (s *server) run(conn net.Conn) error {
+	err := runCommand(s.ctx, conn, s.options.command)
+	if s.ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// runCommand запускает команду через sh -c, подключив соединение к ее STDIN и STDOUT
+// (STDERR остается нашим). При отмене ctx команда получает SIGINT, а через 2 секунды
+// принудительно завершается.
+func runCommand(ctx context.Context, conn net.Conn, command string) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = 2 * time.Second
+	cmd.Stderr = os.Stderr
+
+	// Сокет TCP или unix передается процессу как есть
+	if filer, ok := conn.(interface{ File() (*os.File, error) }); ok {
+		file, err := filer.File()
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		cmd.Stdin = file
+		cmd.Stdout = file
+		return cmd.Run()
+	}
+
+	// TLS-соединение и соединение с --log/--hexdump - через каналы. STDIN копируется
+	// своей горутиной: с cmd.Stdin = conn exec ждал бы конца копирования, то есть
+	// отключения клиента, и после завершения команды
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stdout = conn
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	copied := make(chan struct{})
+	go func() {
+		defer close(copied)
+		io.Copy(stdin, conn)
+		stdin.Close()
+	}()
+
+	err = cmd.Wait()
+	// Команда завершилась: прерываем чтение соединения, которое могло остаться без ответа
+	conn.SetReadDeadline(time.Now())
+	<-copied
+	return err
+}
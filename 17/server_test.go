@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerOptionsValidate(t *testing.T) {
+	tests := []struct {
+		options serverOptions
+		listen  bool
+		dial    dialOptions
+		message string // пустая строка - ошибки нет
+	}{
+		{serverOptions{}, false, dialOptions{}, ""},
+		{serverOptions{}, true, dialOptions{}, ""},
+		{serverOptions{keep: true, command: "cat"}, true, dialOptions{}, ""},
+		{serverOptions{chat: true}, true, dialOptions{tls: true, certFile: "c.pem", keyFile: "k.pem"}, ""},
+		{serverOptions{keep: true}, false, dialOptions{}, "require -l"},
+		{serverOptions{chat: true}, false, dialOptions{}, "require -l"},
+		{serverOptions{}, true, dialOptions{udp: true}, "UDP mode"},
+		{serverOptions{}, true, dialOptions{tls: true, serverName: "example.com"}, "can't be used with -l"},
+		{serverOptions{}, true, dialOptions{tls: true, insecure: true}, "can't be used with -l"},
+		{serverOptions{}, true, dialOptions{tls: true}, "requires --cert"},
+		{serverOptions{chat: true, command: "cat"}, true, dialOptions{}, "cannot be combined"},
+	}
+
+	for _, test := range tests {
+		err := test.options.validate(test.listen, &test.dial)
+		switch {
+		case test.message == "" && err != nil:
+			t.Errorf("validate(%+v, %v): %v", test.options, test.listen, err)
+		case test.message != "" && (err == nil || !strings.Contains(err.Error(), test.message)):
+			t.Errorf("validate(%+v, %v) = %v, expected error containing %q", test.options, test.listen, err, test.message)
+		}
+	}
+}
+
+// Свободный адрес для serve, который сам открывает слушающий сокет
+func freeAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+// Подключается к серверу, дожидаясь начала прослушивания
+func dialServer(t *testing.T, address string) (net.Conn, *bufio.Reader) {
+	for attempt := 0; ; attempt++ {
+		conn, err := net.Dial("tcp", address)
+		if err == nil {
+			t.Cleanup(func() { conn.Close() })
+			return conn, bufio.NewReader(conn)
+		}
+		if attempt == 50 {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func expectLine(t *testing.T, conn net.Conn, reader *bufio.Reader, expected string) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if line, err := reader.ReadString('\n'); line != expected {
+		t.Fatalf("received %q, %v, expected %q", line, err, expected)
+	}
+}
+
+func TestServeChat(t *testing.T) {
+	// Строки STDIN рассылаются всем клиентам
+	input, inputWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = input
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		inputWriter.Close()
+		input.Close()
+	})
+
+	address := freeAddress(t)
+	sigCh := make(chan os.Signal, 1)
+	served := make(chan error, 1)
+	go func() {
+		dial := &dialOptions{address: address}
+		served <- serve(dial, &serverOptions{chat: true}, &monitor{hexdump: io.Discard}, sigCh)
+	}()
+
+	// Строка из STDIN подтверждает, что первый клиент уже зарегистрирован
+	first, firstReader := dialServer(t, address)
+	io.WriteString(inputWriter, "welcome\n")
+	expectLine(t, first, firstReader, "welcome\n")
+
+	second, secondReader := dialServer(t, address)
+	secondName := second.LocalAddr().String()
+	expectLine(t, first, firstReader, "* "+secondName+" joined\n")
+
+	// Строка клиента уходит остальным с его адресом, но не ему самому
+	io.WriteString(second, "hello\n")
+	expectLine(t, first, firstReader, "["+secondName+"] hello\n")
+
+	io.WriteString(inputWriter, "to everyone\n")
+	expectLine(t, first, firstReader, "to everyone\n")
+	expectLine(t, second, secondReader, "to everyone\n")
+
+	second.Close()
+	expectLine(t, first, firstReader, "* "+secondName+" left\n")
+
+	sigCh <- os.Interrupt
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("serve: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not stop on interrupt")
+	}
+	first.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := firstReader.ReadString('\n'); err != io.EOF {
+		t.Errorf("client after shutdown: %v, expected EOF", err)
+	}
+}
+
+// Соединение без метода File: команда подключается через каналы
+type pipedConn struct {
+	net.Conn
+}
+
+func TestRunCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		piped   bool
+		input   string
+		output  string
+	}{
+		{"file", "tr a-z A-Z", false, "hello\n", "HELLO\n"},
+		{"pipe", "tr a-z A-Z", true, "hello\n", "HELLO\n"},
+		// Команда завершается, не дочитав ввод: клиент остается подключенным,
+		// но runCommand не должен ждать его отключения
+		{"file without input", "echo done", false, "", "done\n"},
+		{"pipe without input", "echo done", true, "", "done\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, server := tcpPair(t)
+			var conn net.Conn = server
+			if test.piped {
+				conn = pipedConn{server}
+			}
+
+			finished := make(chan error, 1)
+			go func() {
+				finished <- runCommand(context.Background(), conn, test.command)
+				server.Close()
+			}()
+
+			io.WriteString(client, test.input)
+			if test.input != "" {
+				client.(*net.TCPConn).CloseWrite()
+			}
+			client.SetReadDeadline(time.Now().Add(5 * time.Second))
+			output, err := io.ReadAll(client)
+			if err != nil || string(output) != test.output {
+				t.Errorf("output = %q, %v, expected %q", output, err, test.output)
+			}
+
+			select {
+			case err := <-finished:
+				if err != nil {
+					t.Errorf("runCommand: %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("runCommand did not return after the command exited")
+			}
+		})
+	}
+}
+
+func TestRunCommandCancel(t *testing.T) {
+	for _, piped := range []bool{false, true} {
+		_, server := tcpPair(t)
+		var conn net.Conn = server
+		if piped {
+			conn = pipedConn{server}
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		finished := make(chan error, 1)
+		go func() { finished <- runCommand(ctx, conn, "exec sleep 30") }()
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+
+		select {
+		case err := <-finished:
+			if err == nil {
+				t.Errorf("piped %v: expected an error for an interrupted command", piped)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("piped %v: command was not interrupted", piped)
+		}
+	}
+}
+
+// Пара соединенных TCP-сокетов: у net.Pipe нет File и CloseWrite
+func tcpPair(t *testing.T) (client, server net.Conn) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	if client, err = net.Dial("tcp", listener.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+	if server, err = listener.Accept(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client, server
+}
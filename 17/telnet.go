@@ -0,0 +1,298 @@
+package main
+
+import (
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Команды протокола telnet (RFC 854)
+const (
+	cmdSE   = 240 // конец подсогласования
+	cmdSB   = 250 // начало подсогласования
+	cmdWILL = 251
+	cmdWONT = 252
+	cmdDO   = 253
+	cmdDONT = 254
+	cmdIAC  = 255
+)
+
+// Опции telnet
+const (
+	optEcho            = 1  // RFC 857
+	optSuppressGoAhead = 3  // RFC 858
+	optTerminalType    = 24 // RFC 1091
+	optNAWS            = 31 // RFC 1073, размер окна
+)
+
+// Подкоманды TERMINAL-TYPE
+const (
+	terminalTypeIs   = 0
+	terminalTypeSend = 1
+)
+
+// Состояние разбора входящего потока
+const (
+	stateData       = iota
+	stateIAC        // получен IAC
+	stateOption     // получена WILL/WONT/DO/DONT, ждем номер опции
+	stateSubneg     // внутри SB ... IAC SE
+	stateSubnegIAC  // IAC внутри подсогласования
+	stateCarriageCR // получен CR: следующий NUL отбрасывается (RFC 854)
+)
+
+// Соединение telnet: вырезает из входящего потока команды и отвечает на согласование опций,
+// экранирует IAC в исходящих данных и передает концы строк как CR LF.
+// Согласование только ответное: клиент сам ничего не предлагает, поэтому
+// к серверам без telnet (SMTP, HTTP) в поток не попадает лишних байтов.
+type telnetConn struct {
+	conn net.Conn
+
+	// Разбор входящего потока (только из читающей горутины)
+	state   int
+	command byte
+	subneg  []byte
+	buffer  []byte
+
+	mu     sync.Mutex    // запись в сокет и состояние опций
+	local  map[byte]bool // опции, включенные на нашей стороне (мы ответили WILL)
+	remote map[byte]bool // опции, включенные на стороне сервера (мы ответили DO)
+
+	// Вызывается при включении и выключении эха на стороне сервера
+	onEcho func(remote bool)
+}
+
+func newTelnetConn(conn net.Conn, onEcho func(remote bool)) *telnetConn {
+	return &telnetConn{
+		conn:   conn,
+		local:  make(map[byte]bool),
+		remote: make(map[byte]bool),
+		onEcho: onEcho,
+	}
+}
+
+// Read возвращает данные сервера без команд telnet.
+func (t *telnetConn) Read(p []byte) (int, error) {
+	if len(t.buffer) < len(p) {
+		t.buffer = make([]byte, len(p))
+	}
+	for {
+		n, err := t.conn.Read(t.buffer[:len(p)])
+		data := t.filter(t.buffer[:n], p[:0])
+		// Пакет мог состоять только из команд: читаем дальше, а не возвращаем 0 байтов
+		if len(data) > 0 || err != nil {
+			return len(data), err
+		}
+	}
+}
+
+// filter разбирает входящие байты, дописывает данные в out и обрабатывает команды.
+func (t *telnetConn) filter(input, out []byte) []byte {
+	for _, b := range input {
+		switch t.state {
+		case stateData, stateCarriageCR:
+			if t.state == stateCarriageCR {
+				t.state = stateData
+				if b == 0 {
+					continue
+				}
+			}
+			switch b {
+			case cmdIAC:
+				t.state = stateIAC
+			case '\r':
+				t.state = stateCarriageCR
+				out = append(out, b)
+			default:
+				out = append(out, b)
+			}
+
+		case stateIAC:
+			switch b {
+			case cmdIAC:
+				// Экранированный байт 255
+				out = append(out, b)
+				t.state = stateData
+			case cmdWILL, cmdWONT, cmdDO, cmdDONT:
+				t.command = b
+				t.state = stateOption
+			case cmdSB:
+				t.subneg = t.subneg[:0]
+				t.state = stateSubneg
+			default:
+				// NOP, GA, DM и прочие команды без параметров игнорируются
+				t.state = stateData
+			}
+
+		case stateOption:
+			t.negotiate(t.command, b)
+			t.state = stateData
+
+		case stateSubneg:
+			if b == cmdIAC {
+				t.state = stateSubnegIAC
+			} else {
+				t.subneg = append(t.subneg, b)
+			}
+
+		case stateSubnegIAC:
+			switch b {
+			case cmdSE:
+				t.subnegotiate(t.subneg)
+				t.state = stateData
+			case cmdIAC:
+				t.subneg = append(t.subneg, b)
+				t.state = stateSubneg
+			default:
+				// Оборванное подсогласование: отбрасываем его
+				t.state = stateData
+			}
+		}
+	}
+	return out
+}
+
+// supportedLocal сообщает, готов ли клиент включить опцию на своей стороне.
+func supportedLocal(option byte) bool {
+	switch option {
+	case optSuppressGoAhead, optTerminalType:
+		return true
+	case optNAWS:
+		_, _, ok := windowSize(int(os.Stdin.Fd()))
+		return ok
+	}
+	return false
+}
+
+// supportedRemote сообщает, согласен ли клиент на включение опции сервером.
+func supportedRemote(option byte) bool {
+	return option == optEcho || option == optSuppressGoAhead
+}
+
+// negotiate отвечает на запрос сервера (RFC 855). На запрос, совпадающий с текущим
+// состоянием опции, ответа нет: так согласование не зацикливается.
+func (t *telnetConn) negotiate(command, option byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch command {
+	case cmdDO:
+		if t.local[option] {
+			return
+		}
+		if !supportedLocal(option) {
+			t.send(cmdIAC, cmdWONT, option)
+			return
+		}
+		t.local[option] = true
+		t.send(cmdIAC, cmdWILL, option)
+		if option == optNAWS {
+			t.sendWindowSize()
+		}
+
+	case cmdDONT:
+		if t.local[option] {
+			t.local[option] = false
+			t.send(cmdIAC, cmdWONT, option)
+		}
+
+	case cmdWILL:
+		if t.remote[option] {
+			return
+		}
+		if !supportedRemote(option) {
+			t.send(cmdIAC, cmdDONT, option)
+			return
+		}
+		t.remote[option] = true
+		t.send(cmdIAC, cmdDO, option)
+		if option == optEcho && t.onEcho != nil {
+			t.onEcho(true)
+		}
+
+	case cmdWONT:
+		if t.remote[option] {
+			t.remote[option] = false
+			t.send(cmdIAC, cmdDONT, option)
+			if option == optEcho && t.onEcho != nil {
+				t.onEcho(false)
+			}
+		}
+	}
+}
+
+// subnegotiate обрабатывает подсогласование: сервер запрашивает тип терминала.
+func (t *telnetConn) subnegotiate(data []byte) {
+	if len(data) < 2 || data[0] != optTerminalType || data[1] != terminalTypeSend {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.local[optTerminalType] {
+		return
+	}
+
+	// Типы терминалов по RFC 1091 записываются заглавными буквами
+	terminal := strings.ToUpper(os.Getenv("TERM"))
+	if terminal == "" {
+		terminal = "UNKNOWN"
+	}
+	reply := []byte{cmdIAC, cmdSB, optTerminalType, terminalTypeIs}
+	reply = append(reply, terminal...)
+	t.send(append(reply, cmdIAC, cmdSE)...)
+}
+
+// WindowChanged сообщает серверу новый размер окна, если NAWS включена.
+func (t *telnetConn) WindowChanged() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.local[optNAWS] {
+		t.sendWindowSize()
+	}
+}
+
+// sendWindowSize отправляет IAC SB NAWS ширина высота IAC SE (по 16 бит, старший байт первым).
+func (t *telnetConn) sendWindowSize() {
+	columns, rows, ok := windowSize(int(os.Stdin.Fd()))
+	if !ok {
+		return
+	}
+
+	reply := []byte{cmdIAC, cmdSB, optNAWS}
+	for _, b := range []byte{byte(columns >> 8), byte(columns), byte(rows >> 8), byte(rows)} {
+		reply = append(reply, b)
+		if b == cmdIAC {
+			reply = append(reply, cmdIAC)
+		}
+	}
+	t.send(append(reply, cmdIAC, cmdSE)...)
+}
+
+// send пишет команду в сокет; вызывается под t.mu. Ошибка записи проявится при чтении.
+func (t *telnetConn) send(command ...byte) {
+	t.conn.Write(command)
+}
+
+// Write отправляет данные пользователя: удваивает IAC и заменяет LF на CR LF.
+func (t *telnetConn) Write(p []byte) (int, error) {
+	data := make([]byte, 0, len(p)+8)
+	for i, b := range p {
+		switch {
+		case b == cmdIAC:
+			data = append(data, cmdIAC, cmdIAC)
+		case b == '\n' && (i == 0 || p[i-1] != '\r'):
+			data = append(data, '\r', '\n')
+		default:
+			data = append(data, b)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, err := t.conn.Write(data); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
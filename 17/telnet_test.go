@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+// Соединение, запоминающее ответы клиента серверу
+type recordingConn struct {
+	net.Conn
+	written bytes.Buffer
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	return c.written.Write(p)
+}
+
+func TestTelnetFilter(t *testing.T) {
+	t.Setenv("TERM", "xterm-256color")
+	const nop = 241
+
+	tests := []struct {
+		name   string
+		chunks []string
+		data   string
+		reply  []byte
+		echo   []bool
+	}{
+		{"plain data", []string{"hello\r\n"}, "hello\r\n", nil, nil},
+		{"escaped IAC", []string{"a\xff\xffb"}, "a\xffb", nil, nil},
+		{"CR NUL", []string{"a\r\x00b"}, "a\rb", nil, nil},
+		{"CR NUL split", []string{"a\r", "\x00b"}, "a\rb", nil, nil},
+		{"command without option", []string{"a\xff\xf1b"}, "ab", nil, nil},
+		{"WILL ECHO", []string{"\xff\xfb\x01"}, "", []byte{cmdIAC, cmdDO, optEcho}, []bool{true}},
+		{"WILL ECHO repeated", []string{"\xff\xfb\x01\xff\xfb\x01"}, "", []byte{cmdIAC, cmdDO, optEcho}, []bool{true}},
+		{"WILL then WONT ECHO", []string{"\xff\xfb\x01", "\xff\xfc\x01"}, "", []byte{cmdIAC, cmdDO, optEcho, cmdIAC, cmdDONT, optEcho}, []bool{true, false}},
+		{"WONT when disabled", []string{"\xff\xfc\x01"}, "", nil, nil},
+		{"IAC split across reads", []string{"a\xff", "\xfb", "\x03b"}, "ab", []byte{cmdIAC, cmdDO, optSuppressGoAhead}, nil},
+		{"unsupported DO", []string{"\xff\xfd\x63"}, "", []byte{cmdIAC, cmdWONT, 0x63}, nil},
+		{"unsupported WILL", []string{"\xff\xfb\x63"}, "", []byte{cmdIAC, cmdDONT, 0x63}, nil},
+		{"DONT when disabled", []string{"\xff\xfe\x03"}, "", nil, nil},
+		{
+			"terminal type", []string{"\xff\xfd\x18", "\xff\xfa\x18\x01\xff", "\xf0ok"}, "ok",
+			append([]byte{cmdIAC, cmdWILL, optTerminalType, cmdIAC, cmdSB, optTerminalType, terminalTypeIs}, append([]byte("XTERM-256COLOR"), cmdIAC, cmdSE)...),
+			nil,
+		},
+		{"terminal type not negotiated", []string{"\xff\xfa\x18\x01\xff\xf0"}, "", nil, nil},
+		{"subnegotiation with escaped IAC", []string{"\xff\xfa\x63\xff\xff\x01\xff\xf0x"}, "x", nil, nil},
+		{"NOP", []string{string([]byte{cmdIAC, nop}) + "x"}, "x", nil, nil},
+	}
+
+	for _, test := range tests {
+		conn := &recordingConn{}
+		var echo []bool
+		telnet := newTelnetConn(conn, func(remote bool) { echo = append(echo, remote) })
+
+		var data []byte
+		for _, chunk := range test.chunks {
+			data = telnet.filter([]byte(chunk), data)
+		}
+		if string(data) != test.data {
+			t.Errorf("%s: data = %q, expected %q", test.name, data, test.data)
+		}
+		if !bytes.Equal(conn.written.Bytes(), test.reply) {
+			t.Errorf("%s: reply = %v, expected %v", test.name, conn.written.Bytes(), test.reply)
+		}
+		if len(echo) != len(test.echo) || (len(echo) > 0 && echo[len(echo)-1] != test.echo[len(test.echo)-1]) {
+			t.Errorf("%s: echo changes = %v, expected %v", test.name, echo, test.echo)
+		}
+	}
+}
+
+func TestTelnetWrite(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"line\n", "line\r\n"},
+		{"line\r\n", "line\r\n"},
+		{"\n\n", "\r\n\r\n"},
+		{"a\xffb", "a\xff\xffb"},
+	}
+
+	for _, test := range tests {
+		conn := &recordingConn{}
+		n, err := newTelnetConn(conn, nil).Write([]byte(test.input))
+		if err != nil || n != len(test.input) {
+			t.Errorf("Write(%q) = %d, %v", test.input, n, err)
+		}
+		if got := conn.written.String(); got != test.expected {
+			t.Errorf("Write(%q) sent %q, expected %q", test.input, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// Размер окна терминала (struct winsize)
+type winsize struct {
+	rows    uint16
+	columns uint16
+	xpixel  uint16
+	ypixel  uint16
+}
+
+// Выполняет ioctl над файловым дескриптором
+func ioctl(fd int, request uintptr, argument unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request, uintptr(argument))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Возвращает ширину и высоту терминала; ok = false, если дескриптор не терминал
+func windowSize(fd int) (columns, rows int, ok bool) {
+	var size winsize
+	if err := ioctl(fd, syscall.TIOCGWINSZ, unsafe.Pointer(&size)); err != nil || size.columns == 0 {
+		return 0, 0, false
+	}
+	return int(size.columns), int(size.rows), true
+}
+
+// Включает или выключает локальное эхо терминала (построчный ввод сохраняется)
+func setEcho(fd int, enabled bool) error {
+	var termios syscall.Termios
+	if err := ioctl(fd, syscall.TCGETS, unsafe.Pointer(&termios)); err != nil {
+		return err
+	}
+	if enabled {
+		termios.Lflag |= syscall.ECHO
+	} else {
+		termios.Lflag &^= syscall.ECHO
+	}
+	return ioctl(fd, syscall.TCSETS, unsafe.Pointer(&termios))
+}
+
+// Подписывает канал на изменение размера окна терминала
+func notifyResize(ch chan<- os.Signal) {
+	signal.Notify(ch, syscall.SIGWINCH)
+}
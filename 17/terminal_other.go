@@ -0,0 +1,21 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// Возвращает ширину и высоту терминала (на этой платформе размер не определяется)
+func windowSize(fd int) (columns, rows int, ok bool) {
+	return 0, 0, false
+}
+
+// Включает или выключает локальное эхо терминала
+func setEcho(fd int, enabled bool) error {
+	return errors.New("terminal echo control is not supported on this platform")
+}
+
+// Подписывает канал на изменение размера окна терминала
+func notifyResize(ch chan<- os.Signal) {}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// Параметры соединения: транспорт, версия IP и настройки TLS
+type dialOptions struct {
+	address string // host:port или путь к unix-сокету
+	unix    bool
+	udp     bool
+	ipv4    bool
+	ipv6    bool
+	timeout time.Duration
+
+	// Настройки TCP keepalive (нулевые значения - значения Go по умолчанию)
+	keepAlive net.KeepAliveConfig
+
+	tls        bool
+	serverName string // SNI и имя для проверки сертификата (по умолчанию - хост)
+	insecure   bool
+	caFile     string
+	certFile   string
+	keyFile    string
+}
+
+// network возвращает имя сети для net.Dial: tcp/udp с учетом -4/-6 или unix/unixgram.
+func (o *dialOptions) network() string {
+	network := "tcp"
+	if o.udp {
+		network = "udp"
+	}
+	if o.unix {
+		if o.udp {
+			return "unixgram"
+		}
+		return "unix"
+	}
+	switch {
+	case o.ipv4:
+		network += "4"
+	case o.ipv6:
+		network += "6"
+	}
+	return network
+}
+
+// validate проверяет несовместимые сочетания флагов.
+func (o *dialOptions) validate() error {
+	switch {
+	case o.ipv4 && o.ipv6:
+		return errors.New("-4 and -6 are mutually exclusive")
+	case o.unix && (o.ipv4 || o.ipv6):
+		return errors.New("-4/-6 cannot be used with unix sockets")
+	case o.tls && o.udp:
+		return errors.New("--tls is not supported in UDP mode")
+	case !o.tls && (o.insecure || o.serverName != "" || o.caFile != "" || o.certFile != "" || o.keyFile != ""):
+		return errors.New("--sni, --insecure, --ca, --cert and --key require --tls")
+	case (o.certFile == "") != (o.keyFile == ""):
+		return errors.New("--cert and --key must be used together")
+	}
+	return nil
+}
+
+// dial устанавливает соединение; для TLS дополнительно выполняет рукопожатие
+// в пределах того же таймаута.
+func dial(o *dialOptions) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: o.timeout, KeepAliveConfig: o.keepAlive}
+	if !o.keepAlive.Enable {
+		dialer.KeepAlive = -1
+	}
+	if !o.tls {
+		return dialer.Dial(o.network(), o.address)
+	}
+
+	config, err := o.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	tlsDialer := &tls.Dialer{NetDialer: dialer, Config: config}
+	return tlsDialer.Dial(o.network(), o.address)
+}
+
+// listenConfig возвращает настройки слушающего сокета с keepalive для принятых соединений.
+func (o *dialOptions) listenConfig() *net.ListenConfig {
+	config := &net.ListenConfig{KeepAliveConfig: o.keepAlive}
+	if !o.keepAlive.Enable {
+		config.KeepAlive = -1
+	}
+	return config
+}
+
+// tlsConfig собирает настройки TLS: SNI, корневые и клиентские сертификаты.
+func (o *dialOptions) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         o.serverName,
+		InsecureSkipVerify: o.insecure,
+	}
+	if config.ServerName == "" && !o.unix {
+		host, _, err := net.SplitHostPort(o.address)
+		if err != nil {
+			return nil, err
+		}
+		config.ServerName = host
+	}
+
+	if o.caFile != "" {
+		pool, err := loadCertPool(o.caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+
+	if o.certFile != "" {
+		certificate, err := tls.LoadX509KeyPair(o.certFile, o.keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return config, nil
+}
+
+// loadCertPool читает сертификаты удостоверяющих центров из PEM-файла.
+func loadCertPool(filename string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", filename)
+	}
+	return pool, nil
+}
+
+// describeTLS возвращает сведения о TLS-сессии для вывода в STDERR.
+func describeTLS(conn net.Conn) string {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return ""
+	}
+
+	state := tlsConn.ConnectionState()
+	description := fmt.Sprintf("%s, %s", tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+	if len(state.PeerCertificates) > 0 {
+		description += ", subject " + state.PeerCertificates[0].Subject.String()
+	}
+	return description
+}
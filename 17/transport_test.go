@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDialOptionsNetwork(t *testing.T) {
+	tests := []struct {
+		options  dialOptions
+		expected string
+	}{
+		{dialOptions{}, "tcp"},
+		{dialOptions{ipv4: true}, "tcp4"},
+		{dialOptions{ipv6: true}, "tcp6"},
+		{dialOptions{udp: true}, "udp"},
+		{dialOptions{udp: true, ipv4: true}, "udp4"},
+		{dialOptions{udp: true, ipv6: true}, "udp6"},
+		{dialOptions{unix: true}, "unix"},
+		{dialOptions{unix: true, udp: true}, "unixgram"},
+	}
+
+	for _, test := range tests {
+		if network := test.options.network(); network != test.expected {
+			t.Errorf("network(%+v) = %q, expected %q", test.options, network, test.expected)
+		}
+	}
+}
+
+func TestDialOptionsValidate(t *testing.T) {
+	tests := []struct {
+		options dialOptions
+		message string // пустая строка - ошибки нет
+	}{
+		{dialOptions{}, ""},
+		{dialOptions{ipv4: true, udp: true}, ""},
+		{dialOptions{tls: true, serverName: "example.com", insecure: true}, ""},
+		{dialOptions{tls: true, certFile: "c.pem", keyFile: "k.pem"}, ""},
+		{dialOptions{ipv4: true, ipv6: true}, "mutually exclusive"},
+		{dialOptions{unix: true, ipv6: true}, "unix sockets"},
+		{dialOptions{tls: true, udp: true}, "UDP mode"},
+		{dialOptions{insecure: true}, "require --tls"},
+		{dialOptions{serverName: "example.com"}, "require --tls"},
+		{dialOptions{caFile: "ca.pem"}, "require --tls"},
+		{dialOptions{tls: true, certFile: "c.pem"}, "used together"},
+		{dialOptions{tls: true, keyFile: "k.pem"}, "used together"},
+	}
+
+	for _, test := range tests {
+		err := test.options.validate()
+		switch {
+		case test.message == "" && err != nil:
+			t.Errorf("validate(%+v): %v", test.options, err)
+		case test.message != "" && (err == nil || !strings.Contains(err.Error(), test.message)):
+			t.Errorf("validate(%+v) = %v, expected error containing %q", test.options, err, test.message)
+		}
+	}
+}
+
+func TestTLSConfigServerName(t *testing.T) {
+	tests := []struct {
+		options  dialOptions
+		expected string
+	}{
+		{dialOptions{address: "example.com:443"}, "example.com"},
+		{dialOptions{address: "127.0.0.1:443"}, "127.0.0.1"},
+		{dialOptions{address: "[::1]:443"}, "::1"},
+		{dialOptions{address: "127.0.0.1:443", serverName: "example.com"}, "example.com"},
+		// У unix-сокета нет хоста: без --sni имя не задается
+		{dialOptions{address: "/run/app.sock", unix: true}, ""},
+		{dialOptions{address: "/run/app.sock", unix: true, serverName: "app.local"}, "app.local"},
+	}
+
+	for _, test := range tests {
+		config, err := test.options.tlsConfig()
+		if err != nil {
+			t.Errorf("tlsConfig(%q): %v", test.options.address, err)
+			continue
+		}
+		if config.ServerName != test.expected {
+			t.Errorf("tlsConfig(%q).ServerName = %q, expected %q", test.options.address, config.ServerName, test.expected)
+		}
+	}
+
+	if _, err := (&dialOptions{address: "example.com"}).tlsConfig(); err == nil {
+		t.Error("tlsConfig without port: expected an error")
+	}
+	if _, err := (&dialOptions{address: "example.com:443", caFile: "missing.pem"}).tlsConfig(); err == nil {
+		t.Error("tlsConfig with a missing CA file: expected an error")
+	}
+}
+
+func TestDialTLSVerify(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	// Отклоненные рукопожатия ожидаемы: сервер не пишет о них в журнал
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	// Сертификат тестового сервера выдан на example.com и 127.0.0.1
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(block), 0644); err != nil {
+		t.Fatal(err)
+	}
+	address := strings.TrimPrefix(server.URL, "https://")
+
+	tests := []struct {
+		options dialOptions
+		ok      bool
+	}{
+		{dialOptions{caFile: caFile}, true},
+		{dialOptions{caFile: caFile, serverName: "example.com"}, true},
+		{dialOptions{caFile: caFile, serverName: "wrong.example"}, false},
+		{dialOptions{}, false},
+		{dialOptions{insecure: true, serverName: "wrong.example"}, true},
+	}
+
+	for _, test := range tests {
+		test.options.address = address
+		test.options.tls = true
+		test.options.timeout = 5 * time.Second
+		conn, err := dial(&test.options)
+		if err == nil {
+			if !strings.HasPrefix(describeTLS(conn), "TLS 1.") {
+				t.Errorf("describeTLS = %q", describeTLS(conn))
+			}
+			conn.Close()
+		}
+		if (err == nil) != test.ok {
+			t.Errorf("dial(sni %q, ca %v, insecure %v): error %v, expected success %v",
+				test.options.serverName, test.options.caFile != "", test.options.insecure, err, test.ok)
+		}
+	}
+}